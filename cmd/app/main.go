@@ -14,34 +14,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/debug"
 	"github.com/cragr/alert2snow-agent/internal/logging"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
 	"github.com/cragr/alert2snow-agent/internal/servicenow"
 	"github.com/cragr/alert2snow-agent/internal/webhook"
 )
 
-var (
-	// Prometheus metrics
-	alertsReceived = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "alert2snow_alerts_received_total",
-			Help: "Total number of alerts received from Alertmanager",
-		},
-		[]string{"status"},
-	)
-	serviceNowRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "alert2snow_servicenow_requests_total",
-			Help: "Total number of requests to ServiceNow",
-		},
-		[]string{"operation", "status"},
-	)
-)
-
-func init() {
-	prometheus.MustRegister(alertsReceived)
-	prometheus.MustRegister(serviceNowRequests)
-}
-
 func main() {
 	// Initialize logger
 	logger := logging.NewLogger()
@@ -54,19 +34,95 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Close codes differ between instances and aren't checked at startup,
+	// so log what resolves will send.
+	closeNotes := cfg.ResolveNotesTemplate
+	if closeNotes == "" {
+		closeNotes = models.DefaultResolveNotes
+	}
 	logger.Info("configuration loaded",
 		"http_port", cfg.HTTPPort,
+		"worker_pool_size", cfg.WorkerPoolSize,
+		"dedup_window", cfg.DedupWindow.String(),
+		"resolve_stabilization", cfg.ResolveStabilization.String(),
+		"dry_run", cfg.DryRun,
+		"digest_severities", cfg.DigestSeverities,
+		"group_alerts_by", cfg.GroupAlertsBy,
 		"servicenow_base_url", cfg.ServiceNowBaseURL,
+		"servicenow_failover_base_url", cfg.ServiceNowFailoverBaseURL,
+		"servicenow_batch_enabled", cfg.ServiceNowBatchEnabled,
+		"servicenow_close_code", cfg.ServiceNowCloseCode,
+		"servicenow_resolved_state", cfg.ServiceNowResolvedState,
+		"servicenow_close_notes", closeNotes,
+		"queue_dir", cfg.QueueDir,
+		"dead_letter_file", cfg.DeadLetterFile,
 		"cluster_label_key", cfg.ClusterLabelKey,
 		"environment_label_key", cfg.EnvironmentLabelKey,
+		"webhook_auth_enabled", cfg.WebhookAuthToken != "",
+		"webhook_hmac_enabled", cfg.WebhookHMACSecret != "",
+		"config_endpoint_enabled", cfg.ConfigEndpointToken != "",
 	)
 
+	// Register Prometheus metrics
+	m := metrics.New()
+	m.MustRegister(prometheus.DefaultRegisterer)
+
 	// Create ServiceNow client
-	snowClient := servicenow.NewClient(cfg, logging.WithComponent(logger, "servicenow"))
+	snowClient := servicenow.NewClient(cfg, m, logging.WithComponent(logger, "servicenow"))
+
+	// In dry-run mode writes are logged instead of sent; reads still go to ServiceNow
+	var incidentClient interface {
+		webhook.ServiceNowClient
+		servicenow.SweeperClient
+	} = snowClient
+	var lookups servicenow.Lookups = snowClient
+	var failoverClient *servicenow.Client
+	if failoverCfg := cfg.FailoverConfig(); failoverCfg != nil {
+		failoverClient = servicenow.NewClient(failoverCfg, m, logging.WithComponent(logger, "servicenow-failover"))
+		failover := servicenow.NewFailoverClient(snowClient, failoverClient, logging.WithComponent(logger, "servicenow"))
+		incidentClient, lookups = failover, failover
+	}
+	if cfg.DryRun {
+		incidentClient = servicenow.NewDryRunClient(lookups, logging.WithComponent(logger, "servicenow"))
+	}
+
+	// Background workers run until shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Start the auto-close sweeper if enabled
+	if cfg.AutoCloseEnabled {
+		sweeper := servicenow.NewSweeper(incidentClient, cfg, logging.WithComponent(logger, "sweeper"))
+		go sweeper.Run(backgroundCtx)
+	}
 
 	// Create webhook handler
-	transformer := webhook.NewTransformer(cfg)
-	webhookHandler := webhook.NewHandler(snowClient, transformer, logging.WithComponent(logger, "webhook"))
+	transformer := webhook.NewTransformer(cfg, m, logging.WithComponent(logger, "webhook"))
+	webhookHandler := webhook.NewHandler(cfg, incidentClient, transformer, m, logging.WithComponent(logger, "webhook"))
+
+	// Queue alerts that fail while ServiceNow is down and replay them later
+	if cfg.QueueDir != "" {
+		queue, err := webhook.OpenQueue(cfg.QueueDir, cfg.QueueMaxSize, m)
+		if err != nil {
+			logger.Error("failed to open alert queue", "dir", cfg.QueueDir, "error", err)
+			os.Exit(1)
+		}
+		defer queue.Close()
+		webhookHandler.SetQueue(queue)
+		go webhookHandler.ReplayQueue(backgroundCtx)
+	}
+
+	// Record alerts ServiceNow rejects so they aren't lost
+	if cfg.DeadLetterFile != "" {
+		deadLetter, err := webhook.OpenDeadLetter(cfg.DeadLetterFile, m)
+		if err != nil {
+			logger.Error("failed to open dead-letter file", "path", cfg.DeadLetterFile, "error", err)
+			os.Exit(1)
+		}
+		defer deadLetter.Close()
+		webhookHandler.SetDeadLetter(deadLetter)
+		go deadLetter.RefreshMetrics(backgroundCtx)
+	}
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
@@ -74,20 +130,32 @@ func main() {
 	// Alertmanager webhook endpoint
 	mux.Handle("/alertmanager/webhook", webhookHandler)
 
+	// Grafana unified alerting webhook endpoint
+	mux.Handle("/grafana/webhook", webhookHandler.GrafanaHandler())
+
 	// Health and readiness probes
 	mux.HandleFunc("/healthz", healthzHandler)
-	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/readyz", servicenow.NewReadinessHandler(snowClient, cfg, logging.WithComponent(logger, "readiness")))
 
 	// Prometheus metrics endpoint
 	mux.Handle("/metrics", promhttp.Handler())
 
+	// Configuration introspection endpoint, only served when a token is set
+	store := config.NewStore(cfg)
+	if cfg.ConfigEndpointToken != "" {
+		mux.Handle("/config", config.NewHandler(store))
+	}
+
+	// Profiling endpoints, only served with ENABLE_PPROF
+	debug.RegisterPprof(mux, cfg)
+
 	// Create HTTP server
 	addr := fmt.Sprintf(":%s", cfg.HTTPPort)
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      mux,
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: config.ServerWriteTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 
@@ -100,6 +168,25 @@ func main() {
 		}
 	}()
 
+	// Reload credentials, assignment groups and templates on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			next, err := store.Reload()
+			if err != nil {
+				logger.Error("failed to reload configuration, keeping the current one", "error", err)
+				continue
+			}
+			snowClient.Reload(next)
+			if failoverClient != nil {
+				failoverClient.Reload(next.FailoverConfig())
+			}
+			webhookHandler.SetTransformer(webhook.NewTransformer(next, m, logging.WithComponent(logger, "webhook")))
+			logger.Info("configuration reloaded")
+		}
+	}()
+
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -107,6 +194,8 @@ func main() {
 
 	logger.Info("shutting down server...")
 
+	stopBackground()
+
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -119,14 +208,10 @@ func main() {
 	logger.Info("server stopped")
 }
 
-// healthzHandler handles liveness probe requests.
+// healthzHandler handles liveness probe requests. It deliberately checks
+// nothing beyond the process serving HTTP, so a ServiceNow outage marks pods
+// unready without restarting them.
 func healthzHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
-
-// readyzHandler handles readiness probe requests.
-func readyzHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
-}
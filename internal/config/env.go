@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envParser reads typed environment variables. It records the first parse
+// error so Load can populate the Config literal in one pass and check once.
+type envParser struct {
+	// file holds the variables read from ENV_FILE, which take precedence
+	// over the environment.
+	file map[string]string
+	err  error
+}
+
+// get returns the value of key from ENV_FILE or the environment.
+func (p *envParser) get(key string) string {
+	if value, ok := p.file[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// getOr returns the value of key, or defaultValue if it is not set.
+func (p *envParser) getOr(key, defaultValue string) string {
+	if value := p.get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// bool returns the boolean value of key, or defaultValue if it is not set.
+func (p *envParser) bool(key string, defaultValue bool) bool {
+	value := p.get(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.fail(key, value, err)
+		return defaultValue
+	}
+	return b
+}
+
+// int returns the integer value of key, or defaultValue if it is not set.
+func (p *envParser) int(key string, defaultValue int) int {
+	value := p.get(key)
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		p.fail(key, value, err)
+		return defaultValue
+	}
+	return i
+}
+
+// duration returns the time.ParseDuration value of key, or defaultValue if it
+// is not set.
+func (p *envParser) duration(key string, defaultValue time.Duration) time.Duration {
+	value := p.get(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		p.fail(key, value, err)
+		return defaultValue
+	}
+	return d
+}
+
+// list returns the comma-separated values of key with surrounding whitespace
+// and empty entries removed, or nil if it is not set.
+func (p *envParser) list(key string) []string {
+	var out []string
+	for _, item := range strings.Split(p.get(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// keyValues parses the comma-separated name=value pairs of key, or returns
+// nil if it is not set. Whitespace around names and values is ignored.
+func (p *envParser) keyValues(key string) map[string]string {
+	value := p.get(key)
+	if value == "" {
+		return nil
+	}
+
+	out := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(item, "=")
+		name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+		if !ok || name == "" || val == "" {
+			p.fail(key, value, fmt.Errorf("entry %q is not name=value", item))
+			return nil
+		}
+		out[name] = val
+	}
+	return out
+}
+
+// labelMatchers parses the comma-separated label=value pairs of key, keeping
+// their order and repeated labels, or returns nil if it is not set.
+func (p *envParser) labelMatchers(key string) []LabelMatcher {
+	value := p.get(key)
+	if value == "" {
+		return nil
+	}
+
+	var out []LabelMatcher
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		label, labelValue, ok := strings.Cut(item, "=")
+		m := LabelMatcher{Label: strings.TrimSpace(label), Value: strings.TrimSpace(labelValue)}
+		if !ok || m.Label == "" || m.Value == "" {
+			p.fail(key, value, fmt.Errorf("entry %q is not label=value", item))
+			return nil
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// assignmentGroups parses the comma-separated label=value:group rules of key,
+// keeping their order, or returns nil if it is not set. The group follows the
+// last colon, so label values may contain colons.
+func (p *envParser) assignmentGroups(key string) []AssignmentGroupRule {
+	value := p.get(key)
+	if value == "" {
+		return nil
+	}
+
+	var out []AssignmentGroupRule
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		match, group, _ := cutLast(item, ":")
+		label, labelValue, ok := strings.Cut(match, "=")
+		rule := AssignmentGroupRule{
+			Label: strings.TrimSpace(label),
+			Value: strings.TrimSpace(labelValue),
+			Group: strings.TrimSpace(group),
+		}
+		if !ok || rule.Label == "" || rule.Value == "" || rule.Group == "" {
+			p.fail(key, value, fmt.Errorf("entry %q is not label=value:group", item))
+			return nil
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// json decodes the JSON value of key into target, leaving target untouched if
+// the variable is not set.
+func (p *envParser) json(key string, target interface{}) {
+	value := p.get(key)
+	if value == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		p.fail(key, value, err)
+	}
+}
+
+// textOrFile returns the value of key or, if fileKey is set instead, the
+// contents of the file it names. Setting both is an error.
+func (p *envParser) textOrFile(key, fileKey string) string {
+	value, path := p.get(key), p.get(fileKey)
+	if path == "" {
+		return value
+	}
+	if value != "" {
+		p.fail(fileKey, path, fmt.Errorf("%s is also set", key))
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.fail(fileKey, path, err)
+		return ""
+	}
+	return string(data)
+}
+
+// correlationLabels returns CORRELATION_LABELS or, if its alias
+// CORRELATION_INCLUDE_LABELS is set instead, the alias's labels plus
+// alertname, which the alias always hashes. Setting both is an error.
+func (p *envParser) correlationLabels() []string {
+	labels, include := p.list("CORRELATION_LABELS"), p.list("CORRELATION_INCLUDE_LABELS")
+	if len(include) == 0 {
+		return labels
+	}
+	if len(labels) > 0 {
+		p.fail("CORRELATION_INCLUDE_LABELS", p.get("CORRELATION_INCLUDE_LABELS"), errors.New("it is an alias of CORRELATION_LABELS, which is also set"))
+		return nil
+	}
+	if !slices.Contains(include, "alertname") {
+		include = append(include, "alertname")
+	}
+	return include
+}
+
+// fail records a parse error unless an earlier one was already recorded.
+func (p *envParser) fail(key, value string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+	}
+}
+
+// readEnvFile parses a file of KEY=VALUE lines, as used by ENV_FILE. Blank
+// lines and lines starting with # are skipped, and a value wrapped in single
+// or double quotes is unquoted without further escaping.
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d is not KEY=VALUE", i+1)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		out[key] = value
+	}
+	return out, nil
+}
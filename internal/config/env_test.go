@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvParser_KeyValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "unset", value: "", want: nil},
+		{
+			name:  "pairs with whitespace",
+			value: " cmdb_ci = abc123 , u_source=prometheus,",
+			want:  map[string]string{"cmdb_ci": "abc123", "u_source": "prometheus"},
+		},
+		{name: "value may contain equals", value: "u_query=a=b", want: map[string]string{"u_query": "a=b"}},
+		{name: "missing separator", value: "cmdb_ci", wantErr: true},
+		{name: "empty value", value: "cmdb_ci=", wantErr: true},
+		{name: "empty name", value: "=abc123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_KEY_VALUES", tt.value)
+
+			var env envParser
+			got := env.keyValues("TEST_KEY_VALUES")
+
+			if (env.err != nil) != tt.wantErr {
+				t.Fatalf("keyValues() error = %v, wantErr %v", env.err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvParser_AssignmentGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []AssignmentGroupRule
+		wantErr bool
+	}{
+		{name: "unset", value: "", want: nil},
+		{
+			name:  "rules keep their order",
+			value: "team=platform:GROUP_SYS_A, team = db : GROUP_SYS_B,",
+			want: []AssignmentGroupRule{
+				{Label: "team", Value: "platform", Group: "GROUP_SYS_A"},
+				{Label: "team", Value: "db", Group: "GROUP_SYS_B"},
+			},
+		},
+		{
+			name:  "value may contain colons",
+			value: "instance=db-1:9100:GROUP_SYS_C",
+			want:  []AssignmentGroupRule{{Label: "instance", Value: "db-1:9100", Group: "GROUP_SYS_C"}},
+		},
+		{name: "missing group", value: "team=platform", wantErr: true},
+		{name: "empty group", value: "team=platform:", wantErr: true},
+		{name: "missing value", value: "team:GROUP_SYS_A", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_ASSIGNMENT_GROUPS", tt.value)
+
+			var env envParser
+			got := env.assignmentGroups("TEST_ASSIGNMENT_GROUPS")
+
+			if (env.err != nil) != tt.wantErr {
+				t.Fatalf("assignmentGroups() error = %v, wantErr %v", env.err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignmentGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvParser_LabelMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []LabelMatcher
+		wantErr bool
+	}{
+		{name: "unset", value: "", want: nil},
+		{
+			name:  "pairs keep their order and repeated labels",
+			value: "environment=dev, cluster = sandbox,environment=test,",
+			want: []LabelMatcher{
+				{Label: "environment", Value: "dev"},
+				{Label: "cluster", Value: "sandbox"},
+				{Label: "environment", Value: "test"},
+			},
+		},
+		{name: "missing value", value: "environment=", wantErr: true},
+		{name: "missing separator", value: "environment", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_LABEL_MATCHERS", tt.value)
+
+			var env envParser
+			got := env.labelMatchers("TEST_LABEL_MATCHERS")
+
+			if (env.err != nil) != tt.wantErr {
+				t.Fatalf("labelMatchers() error = %v, wantErr %v", env.err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labelMatchers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "comments, blank lines and quotes",
+			content: "# rotated by vault\n\nSERVICENOW_PASSWORD=\"s3cr=t\"\nexport SERVICENOW_USERNAME = svc\nASSIGNMENT_GROUP_MAP='team=db:DB'\n",
+			want: map[string]string{
+				"SERVICENOW_PASSWORD":  "s3cr=t",
+				"SERVICENOW_USERNAME":  "svc",
+				"ASSIGNMENT_GROUP_MAP": "team=db:DB",
+			},
+		},
+		{name: "empty value", content: "SERVICENOW_ASSIGNMENT_GROUP=", want: map[string]string{"SERVICENOW_ASSIGNMENT_GROUP": ""}},
+		{name: "missing separator", content: "SERVICENOW_PASSWORD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.env")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readEnvFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readEnvFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readEnvFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
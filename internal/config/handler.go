@@ -0,0 +1,81 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces non-empty secrets in the /config output.
+const redactedValue = "REDACTED"
+
+// secretFields lists the Config fields that must never be exposed.
+var secretFields = map[string]bool{
+	"ServiceNowPassword":         true,
+	"ServiceNowFailoverPassword": true,
+	"ServiceNowProxyURL":         true, // may carry proxy credentials
+	"ReportCallbackURL":          true, // may carry a token
+	"WebhookAuthToken":           true,
+	"WebhookHMACSecret":          true,
+	"ConfigEndpointToken":        true,
+	"PprofToken":                 true,
+}
+
+// Redacted returns the configuration as a field name -> value map with
+// secrets replaced and durations rendered as strings (e.g. "30s"). Empty
+// secrets stay empty so operators can tell whether one is set.
+func (c *Config) Redacted() map[string]interface{} {
+	out := make(map[string]interface{})
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		field := v.Field(i)
+		switch {
+		case secretFields[name]:
+			if field.String() != "" {
+				out[name] = redactedValue
+			} else {
+				out[name] = ""
+			}
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			out[name] = time.Duration(field.Int()).String()
+		default:
+			out[name] = field.Interface()
+		}
+	}
+	return out
+}
+
+// NewHandler returns an http.Handler serving the redacted configuration in
+// effect as JSON to requests bearing its ConfigEndpointToken.
+func NewHandler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := store.Get()
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cfg.ConfigEndpointToken == "" || !ok || !tokensEqual(token, cfg.ConfigEndpointToken) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cfg.Redacted())
+	})
+}
+
+// tokensEqual compares two secrets in constant time without leaking the
+// length of the expected token.
+func tokensEqual(got, want string) bool {
+	gotSum := sha256.Sum256([]byte(got))
+	wantSum := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(gotSum[:], wantSum[:]) == 1
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Reload reads the configuration again and returns a copy of current with
+// the reloadable settings replaced: the ServiceNow credentials, the
+// assignment group settings, the templates, and SUPPRESS_IF_LABEL.
+// Everything else, such as HTTP_PORT, keeps the value it had at startup.
+// If the new configuration does not load, the error is returned and
+// current stays in effect.
+//
+// The environment of a running process doesn't change, so new values only
+// take effect when they come from ENV_FILE or DESCRIPTION_TEMPLATE_FILE.
+func Reload(current *Config) (*Config, error) {
+	next, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *current
+	cfg.ServiceNowUsername = next.ServiceNowUsername
+	cfg.ServiceNowPassword = next.ServiceNowPassword
+	cfg.ServiceNowFailoverUsername = next.ServiceNowFailoverUsername
+	cfg.ServiceNowFailoverPassword = next.ServiceNowFailoverPassword
+	cfg.ServiceNowAssignmentGroup = next.ServiceNowAssignmentGroup
+	cfg.AssignmentGroupMap = next.AssignmentGroupMap
+	cfg.ResolveNotesTemplate = next.ResolveNotesTemplate
+	cfg.DescriptionTemplate = next.DescriptionTemplate
+	cfg.ShortDescriptionTemplate = next.ShortDescriptionTemplate
+	cfg.ConsoleLinkTemplate = next.ConsoleLinkTemplate
+	cfg.IncidentURLTemplate = next.IncidentURLTemplate
+	cfg.SuppressIfLabel = next.SuppressIfLabel
+	return &cfg, nil
+}
+
+// Store holds the configuration in effect. Readers get it with Get, which is
+// safe while Reload replaces it.
+type Store struct {
+	mu      sync.Mutex // serializes reloads
+	current atomic.Pointer[Config]
+}
+
+// NewStore returns a Store holding cfg.
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get returns the configuration in effect. Callers must not modify it.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload replaces the configuration in effect as described for Reload and
+// returns the new one. On error the configuration is left unchanged.
+func (s *Store) Reload() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := Reload(s.Get())
+	if err != nil {
+		return nil, err
+	}
+	s.current.Store(next)
+	return next, nil
+}
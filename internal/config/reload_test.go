@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_Reload(t *testing.T) {
+	t.Setenv("SERVICENOW_BASE_URL", "https://example.service-now.com")
+	t.Setenv("SERVICENOW_USERNAME", "user")
+	t.Setenv("SERVICENOW_PASSWORD", "from-env")
+	t.Setenv("HTTP_PORT", "8080")
+
+	envFile := filepath.Join(t.TempDir(), "agent.env")
+	t.Setenv("ENV_FILE", envFile)
+	writeEnv := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(envFile, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeEnv("SERVICENOW_PASSWORD=old\n")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ServiceNowPassword != "old" {
+		t.Fatalf("ServiceNowPassword = %q, want ENV_FILE to win over the environment", cfg.ServiceNowPassword)
+	}
+	store := NewStore(cfg)
+
+	writeEnv("SERVICENOW_PASSWORD=new\nSERVICENOW_ASSIGNMENT_GROUP=platform\nRESOLVE_NOTES_TEMPLATE={{.AlertName}} cleared\nHTTP_PORT=9090\n")
+	next, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if store.Get() != next {
+		t.Error("Get() does not return the reloaded configuration")
+	}
+	if next.ServiceNowPassword != "new" || next.ServiceNowAssignmentGroup != "platform" || next.ResolveNotesTemplate != "{{.AlertName}} cleared" {
+		t.Errorf("reloadable settings not reloaded: password %q, group %q, notes %q",
+			next.ServiceNowPassword, next.ServiceNowAssignmentGroup, next.ResolveNotesTemplate)
+	}
+	if next.HTTPPort != "8080" {
+		t.Errorf("HTTPPort = %q, want the startup value 8080", next.HTTPPort)
+	}
+	if cfg.ServiceNowPassword != "old" {
+		t.Errorf("Reload modified the previous configuration")
+	}
+
+	writeEnv("RESOLVE_NOTES_TEMPLATE={{.Missing}}\n")
+	if _, err := store.Reload(); err == nil {
+		t.Fatal("Reload() succeeded with an invalid template")
+	}
+	if store.Get() != next {
+		t.Error("a failed reload replaced the configuration")
+	}
+}
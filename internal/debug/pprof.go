@@ -0,0 +1,88 @@
+// Package debug serves runtime diagnostics such as pprof profiles.
+package debug
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+)
+
+// pprofPrefix is where the profiling endpoints are mounted.
+const pprofPrefix = "/debug/pprof/"
+
+// RegisterPprof mounts the net/http/pprof handlers under /debug/pprof/ on mux
+// when ENABLE_PPROF is set, guarded by PPROF_TOKEN and PPROF_ALLOWED_CIDRS.
+// It registers nothing otherwise.
+func RegisterPprof(mux *http.ServeMux, cfg *config.Config) {
+	if !cfg.PprofEnabled {
+		return
+	}
+
+	profiles := http.NewServeMux()
+	profiles.HandleFunc(pprofPrefix, pprof.Index)
+	profiles.HandleFunc(pprofPrefix+"cmdline", pprof.Cmdline)
+	profiles.HandleFunc(pprofPrefix+"profile", pprof.Profile)
+	profiles.HandleFunc(pprofPrefix+"symbol", pprof.Symbol)
+	profiles.HandleFunc(pprofPrefix+"trace", pprof.Trace)
+
+	mux.Handle(pprofPrefix, guard(cfg, profiles))
+}
+
+// guard admits requests that pass every configured check: a bearer token
+// equal to PPROF_TOKEN and a client address within PPROF_ALLOWED_CIDRS.
+func guard(cfg *config.Config, next http.Handler) http.Handler {
+	// config.Load has already validated the CIDRs.
+	var allowed []*net.IPNet
+	for _, cidr := range cfg.PprofAllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			allowed = append(allowed, network)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.PprofToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !tokensEqual(token, cfg.PprofToken) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		if len(allowed) > 0 && !addrAllowed(r.RemoteAddr, allowed) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// addrAllowed reports whether the IP in a host:port remote address falls in
+// one of the allowed networks.
+func addrAllowed(remoteAddr string, allowed []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensEqual compares two secrets in constant time without leaking the
+// length of the expected token.
+func tokensEqual(got, want string) bool {
+	gotSum := sha256.Sum256([]byte(got))
+	wantSum := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(gotSum[:], wantSum[:]) == 1
+}
@@ -0,0 +1,80 @@
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		token      string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "disabled", cfg: &config.Config{PprofToken: "secret"}, token: "secret", wantStatus: http.StatusNotFound},
+		{name: "valid token", cfg: &config.Config{PprofEnabled: true, PprofToken: "secret"}, token: "secret", wantStatus: http.StatusOK},
+		{name: "missing token", cfg: &config.Config{PprofEnabled: true, PprofToken: "secret"}, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", cfg: &config.Config{PprofEnabled: true, PprofToken: "secret"}, token: "guess", wantStatus: http.StatusUnauthorized},
+		{
+			name:       "allowed address",
+			cfg:        &config.Config{PprofEnabled: true, PprofAllowedCIDRs: []string{"10.0.0.0/8"}},
+			remoteAddr: "10.1.2.3:40000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "address outside allowlist",
+			cfg:        &config.Config{PprofEnabled: true, PprofAllowedCIDRs: []string{"10.0.0.0/8"}},
+			remoteAddr: "192.0.2.1:40000",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "token and allowlist both required",
+			cfg:        &config.Config{PprofEnabled: true, PprofToken: "secret", PprofAllowedCIDRs: []string{"10.0.0.0/8"}},
+			token:      "secret",
+			remoteAddr: "192.0.2.1:40000",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			RegisterPprof(mux, tt.cfg)
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("GET /debug/pprof/ status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRegisterPprof_Profiles(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterPprof(mux, &config.Config{PprofEnabled: true, PprofToken: "secret"})
+
+	for _, path := range []string{"/debug/pprof/heap", "/debug/pprof/goroutine", "/debug/pprof/cmdline"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d", path, rr.Code, http.StatusOK)
+		}
+	}
+}
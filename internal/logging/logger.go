@@ -2,19 +2,48 @@
 package logging
 
 import (
+	"context"
 	"log/slog"
 	"os"
 )
 
-// NewLogger creates a new structured JSON logger for the application.
+// NewLogger creates a new structured JSON logger for the application. The
+// level is read from LOG_LEVEL (debug, info, warn, error) and defaults to info.
 func NewLogger() *slog.Logger {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: levelFromEnv(),
 	})
 	return slog.New(handler)
 }
 
+// levelFromEnv parses LOG_LEVEL, falling back to info for unset or unknown values.
+func levelFromEnv() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
 // WithComponent returns a logger with a component field for categorizing log messages.
 func WithComponent(logger *slog.Logger, component string) *slog.Logger {
 	return logger.With("component", component)
 }
+
+// loggerKey is the context key for a request-scoped logger.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, so code handling one
+// request can log with that request's attributes.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or fallback if
+// there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
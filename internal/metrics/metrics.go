@@ -0,0 +1,178 @@
+// Package metrics defines the Prometheus collectors exported by the agent.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds the Prometheus collectors shared by the agent's components.
+// Components receive a *Metrics so tests can use an unregistered instance.
+type Metrics struct {
+	AlertsReceived          *prometheus.CounterVec
+	AlertsDropped           *prometheus.CounterVec
+	AlertsSuppressed        *prometheus.CounterVec
+	ServiceNowRequests      *prometheus.CounterVec
+	ServiceNowDuration      *prometheus.HistogramVec
+	ServiceNowAuthFailures  prometheus.Counter
+	AlertProcessingDuration *prometheus.HistogramVec
+	GeneratorURLFailures    *prometheus.CounterVec
+	AlreadyResolved         prometheus.Counter
+	ForeignIncidents        prometheus.Counter
+	ResolveOutcomes         *prometheus.CounterVec
+	ResolveNoMatch          *prometheus.CounterVec
+	QueueDepth              prometheus.Gauge
+	QueueDropped            prometheus.Counter
+	DeadLettered            *prometheus.CounterVec
+	DeadLetterEntries       prometheus.Gauge
+	DeadLetterBytes         prometheus.Gauge
+	ReportCallbacks         *prometheus.CounterVec
+}
+
+// New creates an unregistered set of collectors.
+func New() *Metrics {
+	return &Metrics{
+		AlertsReceived: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_alerts_received_total",
+				Help: "Total number of alerts received from Alertmanager",
+			},
+			[]string{"status"},
+		),
+		AlertsDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_alerts_dropped_total",
+				Help: "Total number of alerts ignored because they could not be processed",
+			},
+			[]string{"reason"},
+		),
+		AlertsSuppressed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_alerts_suppressed_total",
+				Help: "Total number of firing alerts dropped by SUPPRESS_IF_LABEL, by matching label",
+			},
+			[]string{"label"},
+		),
+		ServiceNowRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_servicenow_requests_total",
+				Help: "Total number of requests to ServiceNow",
+			},
+			[]string{"operation", "status"},
+		),
+		ServiceNowDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "alert2snow_servicenow_request_duration_seconds",
+				Help:    "Latency of individual HTTP requests to ServiceNow, including failed attempts",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"operation"},
+		),
+		ServiceNowAuthFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "alert2snow_servicenow_auth_failures_total",
+				Help: "Total number of ServiceNow requests rejected with 401 or 403",
+			},
+		),
+		AlertProcessingDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "alert2snow_alert_processing_duration_seconds",
+				Help:    "End-to-end time to process a single alert, including ServiceNow calls",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"outcome"},
+		),
+		GeneratorURLFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_generator_url_failures_total",
+				Help: "Total number of GeneratorURLs a cluster name could not be extracted from",
+			},
+			[]string{"reason"},
+		),
+		AlreadyResolved: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "alert2snow_incidents_already_resolved_total",
+				Help: "Total number of resolved alerts whose incident was already resolved or closed",
+			},
+		),
+		ForeignIncidents: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "alert2snow_foreign_incidents_skipped_total",
+				Help: "Total number of resolved alerts whose incident lacked the INCIDENT_MARKER_FIELD marker and was left open",
+			},
+		),
+		ResolveOutcomes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_resolve_outcomes_total",
+				Help: "Total number of resolved alerts by outcome of resolving their incident",
+			},
+			[]string{"outcome"},
+		),
+		ResolveNoMatch: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_resolve_no_match_total",
+				Help: "Total number of resolved alerts for which no incident matched the correlation ID",
+			},
+			[]string{"alertname"},
+		),
+		QueueDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "alert2snow_queue_depth",
+				Help: "Number of failed alerts waiting in the on-disk queue for replay",
+			},
+		),
+		QueueDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "alert2snow_queue_dropped_total",
+				Help: "Total number of queued alerts dropped because the queue was full",
+			},
+		),
+		DeadLettered: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_dead_lettered_total",
+				Help: "Total number of alerts or alert groups written to the dead-letter file",
+			},
+			[]string{"operation"},
+		),
+		DeadLetterEntries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "alert2snow_dead_letter_entries",
+				Help: "Number of entries in the dead-letter file",
+			},
+		),
+		DeadLetterBytes: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "alert2snow_dead_letter_size_bytes",
+				Help: "Size of the dead-letter file in bytes",
+			},
+		),
+		ReportCallbacks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "alert2snow_report_callbacks_total",
+				Help: "Total number of processing reports sent to REPORT_CALLBACK_URL by outcome",
+			},
+			[]string{"outcome"},
+		),
+	}
+}
+
+// MustRegister registers all collectors with reg, panicking on failure.
+func (m *Metrics) MustRegister(reg prometheus.Registerer) {
+	reg.MustRegister(
+		m.AlertsReceived,
+		m.AlertsDropped,
+		m.AlertsSuppressed,
+		m.ServiceNowRequests,
+		m.ServiceNowDuration,
+		m.ServiceNowAuthFailures,
+		m.AlertProcessingDuration,
+		m.GeneratorURLFailures,
+		m.AlreadyResolved,
+		m.ForeignIncidents,
+		m.ResolveOutcomes,
+		m.ResolveNoMatch,
+		m.QueueDepth,
+		m.QueueDropped,
+		m.DeadLettered,
+		m.DeadLetterEntries,
+		m.DeadLetterBytes,
+		m.ReportCallbacks,
+	)
+}
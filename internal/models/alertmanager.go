@@ -32,4 +32,7 @@ type Alert struct {
 const (
 	AlertStatusFiring   = "firing"
 	AlertStatusResolved = "resolved"
+	// AlertStatusSuppressed is sent by some tools for silenced or
+	// inhibited alerts; Alertmanager itself does not send it.
+	AlertStatusSuppressed = "suppressed"
 )
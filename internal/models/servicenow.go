@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ServiceNowIncident represents the payload structure for creating/updating
 // incidents in ServiceNow via the Table API.
 type ServiceNowIncident struct {
@@ -7,11 +12,77 @@ type ServiceNowIncident struct {
 	Description      string `json:"description"`
 	Impact           string `json:"impact"`
 	Urgency          string `json:"urgency"`
+	Priority         string `json:"priority,omitempty"`
 	Category         string `json:"category"`
 	Subcategory      string `json:"subcategory"`
 	AssignmentGroup  string `json:"assignment_group,omitempty"`
 	CallerID         string `json:"caller_id,omitempty"`
+	ContactType      string `json:"contact_type,omitempty"`
 	CorrelationID    string `json:"correlation_id"`
+
+	// ExtraFields holds additional columns, such as custom u_ fields, that
+	// are merged into the JSON payload. They never replace the fields above.
+	ExtraFields map[string]string `json:"-"`
+}
+
+// SetField sets a field by its JSON name: a standard field when name is one
+// of them, otherwise an entry in ExtraFields.
+func (i *ServiceNowIncident) SetField(name, value string) {
+	switch name {
+	case "short_description":
+		i.ShortDescription = value
+	case "description":
+		i.Description = value
+	case "impact":
+		i.Impact = value
+	case "urgency":
+		i.Urgency = value
+	case "priority":
+		i.Priority = value
+	case "category":
+		i.Category = value
+	case "subcategory":
+		i.Subcategory = value
+	case "assignment_group":
+		i.AssignmentGroup = value
+	case "caller_id":
+		i.CallerID = value
+	case "contact_type":
+		i.ContactType = value
+	case "correlation_id":
+		i.CorrelationID = value
+	default:
+		if i.ExtraFields == nil {
+			i.ExtraFields = make(map[string]string)
+		}
+		i.ExtraFields[name] = value
+	}
+}
+
+// MarshalJSON encodes the incident with ExtraFields merged in alongside the
+// standard fields.
+func (i ServiceNowIncident) MarshalJSON() ([]byte, error) {
+	type incident ServiceNowIncident
+	raw, err := json.Marshal(incident(i))
+	if err != nil || len(i.ExtraFields) == 0 {
+		return raw, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range i.ExtraFields {
+		if _, exists := fields[name]; exists {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = encoded
+	}
+	return json.Marshal(fields)
 }
 
 // ServiceNowResponse represents the response from ServiceNow Table API.
@@ -31,6 +102,71 @@ type ServiceNowResult struct {
 	State            string `json:"state"`
 	CorrelationID    string `json:"correlation_id"`
 	ShortDescription string `json:"short_description"`
+	ResolvedAt       string `json:"resolved_at,omitempty"`
+
+	// Fields holds every string-valued column of the record by name,
+	// including those above, so callers can read custom u_ fields.
+	Fields map[string]string `json:"-"`
+}
+
+// UnmarshalJSON decodes the standard fields and collects every string-valued
+// column into Fields.
+func (r *ServiceNowResult) UnmarshalJSON(data []byte) error {
+	type result ServiceNowResult
+	if err := json.Unmarshal(data, (*result)(r)); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	r.Fields = make(map[string]string, len(raw))
+	for name, value := range raw {
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			r.Fields[name] = s
+		}
+	}
+	return nil
+}
+
+// TimeLayout is the format of date-time values in Table API responses and
+// encoded queries. Values are in UTC.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// ResolvedTime parses ResolvedAt. It reports false if the incident has no
+// resolved_at value or it cannot be parsed.
+func (r ServiceNowResult) ResolvedTime() (time.Time, bool) {
+	if r.ResolvedAt == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(TimeLayout, r.ResolvedAt, time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ServiceNowImportResponse represents the response from the ServiceNow Import Set API.
+type ServiceNowImportResponse struct {
+	ImportSet    string                   `json:"import_set"`
+	StagingTable string                   `json:"staging_table"`
+	Result       []ServiceNowImportResult `json:"result"`
+}
+
+// ServiceNowImportResult represents the outcome of transforming a single
+// import set row into a target table record.
+type ServiceNowImportResult struct {
+	TransformMap  string `json:"transform_map"`
+	Table         string `json:"table"`
+	DisplayName   string `json:"display_name"`
+	DisplayValue  string `json:"display_value"`
+	RecordLink    string `json:"record_link"`
+	Status        string `json:"status"`
+	StatusMessage string `json:"status_message"`
+	ErrorMessage  string `json:"error_message"`
+	SysID         string `json:"sys_id"`
 }
 
 // ServiceNowUpdatePayload represents the payload for updating an incident state.
@@ -40,10 +176,76 @@ type ServiceNowUpdatePayload struct {
 	CloseNotes   string `json:"close_notes,omitempty"`
 	RootCause    string `json:"u_root_cause,omitempty"`
 	RestoredDate string `json:"u_restored_date,omitempty"`
+	WorkNotes    string `json:"work_notes,omitempty"`
+}
+
+// ServiceNowReopenPayload represents the payload for moving a resolved
+// incident back to in progress.
+type ServiceNowReopenPayload struct {
+	State     string `json:"state"`
+	WorkNotes string `json:"work_notes,omitempty"`
+}
+
+// ServiceNowWorkNotePayload represents the payload for appending a work note
+// to an incident.
+type ServiceNowWorkNotePayload struct {
+	WorkNotes string `json:"work_notes"`
 }
 
 // ServiceNow incident state constants.
 const (
+	// StateInProgress indicates the incident is being worked (state 2 in ServiceNow).
+	StateInProgress = "2"
 	// StateResolved indicates the incident is resolved (state 6 in ServiceNow).
 	StateResolved = "6"
+	// StateClosed indicates the incident is closed (state 7 in ServiceNow).
+	StateClosed = "7"
 )
+
+// DefaultResolveNotes is the close note used when no template is configured.
+const DefaultResolveNotes = "Alert resolved - condition cleared automatically"
+
+// DefaultCloseCode is the close_code of resolved incidents unless
+// SERVICENOW_CLOSE_CODE is set.
+const DefaultCloseCode = "Solved (Permanently)"
+
+// DescriptionData is the data available to DESCRIPTION_TEMPLATE and
+// SHORT_DESCRIPTION_TEMPLATE: the alert itself and the values the agent
+// derives from it.
+type DescriptionData struct {
+	Alert       Alert
+	AlertName   string
+	Cluster     string
+	Environment string
+	Severity    string
+	Namespace   string
+	Pod         string
+	Container   string
+	ConsoleURL  string
+	ExternalURL string
+}
+
+// ConsoleLinkData is the data available to CONSOLE_LINK_TEMPLATE.
+type ConsoleLinkData struct {
+	Cluster   string
+	Namespace string
+	Labels    map[string]string
+}
+
+// IncidentURLData is the data available to SERVICENOW_INCIDENT_URL_TEMPLATE.
+// Table is the name of the table the incident was created in, such as
+// incident or change_request.
+type IncidentURLData struct {
+	BaseURL string
+	Table   string
+	SysID   string
+	Number  string
+}
+
+// ResolveNotesData is the data available to RESOLVE_NOTES_TEMPLATE.
+type ResolveNotesData struct {
+	AlertName      string
+	CorrelationID  string
+	IncidentNumber string
+	ResolvedAt     time.Time
+}
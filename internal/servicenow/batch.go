@@ -0,0 +1,283 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// batchRequest is the body of a ServiceNow REST Batch API call.
+type batchRequest struct {
+	BatchRequestID string             `json:"batch_request_id"`
+	RestRequests   []batchRestRequest `json:"rest_requests"`
+}
+
+// batchRestRequest is one request inside a batch. Body is base64 encoded.
+type batchRestRequest struct {
+	ID                     string        `json:"id"`
+	Method                 string        `json:"method"`
+	URL                    string        `json:"url"`
+	Headers                []batchHeader `json:"headers"`
+	Body                   string        `json:"body"`
+	ExcludeResponseHeaders bool          `json:"exclude_response_headers"`
+}
+
+type batchHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// batchResponse is the Batch API response. Requests ServiceNow did not get
+// to, for example after a time limit, are listed by ID as unserviced.
+type batchResponse struct {
+	BatchRequestID     string                 `json:"batch_request_id"`
+	ServicedRequests   []batchServicedRequest `json:"serviced_requests"`
+	UnservicedRequests []string               `json:"unserviced_requests"`
+}
+
+// batchServicedRequest is the outcome of one request. Body is base64 encoded.
+type batchServicedRequest struct {
+	ID           string `json:"id"`
+	StatusCode   int    `json:"status_code"`
+	StatusText   string `json:"status_text"`
+	Body         string `json:"body"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// buildBatchRequest wraps one Table API create per incident into a batch,
+// using each incident's correlation ID as its request ID. bodyPatch, if
+// set, is merged into each create body.
+func buildBatchRequest(batchID, endpointPath string, incidents []models.ServiceNowIncident, bodyPatch map[string]any) (*batchRequest, error) {
+	batch := &batchRequest{BatchRequestID: batchID}
+	for _, incident := range incidents {
+		body, err := json.Marshal(incident)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal incident: %w", err)
+		}
+		if body, err = patchBody(body, bodyPatch); err != nil {
+			return nil, err
+		}
+		batch.RestRequests = append(batch.RestRequests, batchRestRequest{
+			ID:     incident.CorrelationID,
+			Method: http.MethodPost,
+			URL:    endpointPath,
+			Headers: []batchHeader{
+				{Name: "Content-Type", Value: "application/json"},
+				{Name: "Accept", Value: "application/json"},
+			},
+			Body:                   base64.StdEncoding.EncodeToString(body),
+			ExcludeResponseHeaders: true,
+		})
+	}
+	return batch, nil
+}
+
+// parseBatchResponse returns the created incidents keyed by correlation ID.
+// Requests that were unserviced, failed, or returned an unreadable body are
+// left out so the caller can retry them individually.
+func parseBatchResponse(body []byte) (map[string]*CreateIncidentResult, error) {
+	var batchResp batchResponse
+	if err := json.Unmarshal(body, &batchResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch response: %w", err)
+	}
+
+	results := make(map[string]*CreateIncidentResult, len(batchResp.ServicedRequests))
+	for _, served := range batchResp.ServicedRequests {
+		if served.StatusCode < 200 || served.StatusCode >= 300 {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(served.Body)
+		if err != nil {
+			continue
+		}
+		result, err := parseTableResponse(decoded)
+		if err != nil || result.SysID == "" {
+			continue
+		}
+		results[served.ID] = result
+	}
+	return results, nil
+}
+
+// batchSeq numbers batch requests for correlation in ServiceNow logs.
+var batchSeq atomic.Uint64
+
+// CreateIncidents creates incidents through the REST Batch API in a single
+// HTTP call and returns the created records keyed by correlation ID.
+// Incidents missing from the map were not created by the batch; the error
+// covers only the batch call as a whole.
+func (c *Client) CreateIncidents(ctx context.Context, incidents []models.ServiceNowIncident) (map[string]*CreateIncidentResult, error) {
+	batch, err := buildBatchRequest(strconv.FormatUint(batchSeq.Add(1), 10), c.endpointPath, incidents, c.bodyPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+
+	c.logger.Debug("creating incidents with ServiceNow batch request",
+		"batch_request_id", batch.BatchRequestID,
+		"count", len(incidents),
+	)
+
+	var respBody []byte
+
+	err = WithRetry(ctx, c.retryConfigFor(opBatchCreate, "batch_request_id", batch.BatchRequestID), func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+c.batchPath, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		c.setHeaders(req)
+
+		resp, err := c.do(req, opBatchCreate)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if err := c.checkResponse(resp); err != nil {
+			return err
+		}
+
+		respBody, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBatchResponse(respBody)
+}
+
+// batcher coalesces concurrent CreateIncident calls into Batch API requests.
+// A batch is sent once it holds maxSize incidents or linger has passed since
+// its first incident. Incidents the batch did not create fall back to an
+// individual create by their caller.
+type batcher struct {
+	client  *Client
+	maxSize int
+	linger  time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending []*batchCall
+	timer   *time.Timer
+}
+
+// batchCall is one CreateIncident call waiting on a batch.
+type batchCall struct {
+	incident models.ServiceNowIncident
+	done     chan *CreateIncidentResult
+}
+
+// create queues the incident for the next batch and waits for it. A nil
+// result from the batch means the caller must create the incident itself.
+func (b *batcher) create(ctx context.Context, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
+	call := &batchCall{incident: incident, done: make(chan *CreateIncidentResult, 1)}
+	if !b.enqueue(call) {
+		return b.client.createIncident(ctx, b.client.endpointPath, incident)
+	}
+
+	select {
+	case result := <-call.done:
+		if result != nil {
+			return result, nil
+		}
+		return b.client.createIncident(ctx, b.client.endpointPath, incident)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds call to the pending batch, flushing it when full. It reports
+// false if the batch already holds the same correlation ID, since batch
+// results are matched by it.
+func (b *batcher) enqueue(call *batchCall) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range b.pending {
+		if p.incident.CorrelationID == call.incident.CorrelationID {
+			return false
+		}
+	}
+
+	b.pending = append(b.pending, call)
+	if len(b.pending) >= b.maxSize {
+		b.flushLocked()
+		return true
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.linger, b.flush)
+	}
+	return true
+}
+
+// flush sends the pending batch.
+func (b *batcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+// flushLocked takes the pending calls and sends them in the background.
+// b.mu must be held.
+func (b *batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	calls := b.pending
+	b.pending = nil
+	if len(calls) > 0 {
+		go b.send(calls)
+	}
+}
+
+// send submits calls as one batch and hands each caller its result, or nil
+// to fall back to an individual create. The batch runs on its own context
+// because it serves several requests.
+func (b *batcher) send(calls []*batchCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	incidents := make([]models.ServiceNowIncident, len(calls))
+	for i, call := range calls {
+		incidents[i] = call.incident
+	}
+
+	results, err := b.client.CreateIncidents(ctx, incidents)
+	if err != nil {
+		b.client.logger.Warn("batch create failed, creating incidents individually",
+			"count", len(calls),
+			"error", err,
+		)
+	} else if missing := len(calls) - len(results); missing > 0 {
+		b.client.logger.Warn("batch did not create every incident, creating the rest individually",
+			"count", len(calls),
+			"missing", missing,
+		)
+	}
+
+	for _, call := range calls {
+		call.done <- results[call.incident.CorrelationID]
+	}
+}
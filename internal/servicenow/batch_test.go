@@ -0,0 +1,247 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestBuildBatchRequest(t *testing.T) {
+	incidents := []models.ServiceNowIncident{
+		{ShortDescription: "[east] TargetDown", CorrelationID: "corr-a"},
+		{ShortDescription: "[west] TargetDown", CorrelationID: "corr-b"},
+	}
+
+	batch, err := buildBatchRequest("7", "/api/now/table/incident", incidents, nil)
+	if err != nil {
+		t.Fatalf("buildBatchRequest() error = %v", err)
+	}
+
+	if batch.BatchRequestID != "7" {
+		t.Errorf("BatchRequestID = %q, want %q", batch.BatchRequestID, "7")
+	}
+	if len(batch.RestRequests) != 2 {
+		t.Fatalf("expected 2 rest requests, got %d", len(batch.RestRequests))
+	}
+	for i, req := range batch.RestRequests {
+		if req.ID != incidents[i].CorrelationID {
+			t.Errorf("request %d ID = %q, want %q", i, req.ID, incidents[i].CorrelationID)
+		}
+		if req.Method != http.MethodPost || req.URL != "/api/now/table/incident" {
+			t.Errorf("request %d = %s %s, want POST /api/now/table/incident", i, req.Method, req.URL)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			t.Fatalf("request %d body is not base64: %v", i, err)
+		}
+		var body models.ServiceNowIncident
+		if err := json.Unmarshal(decoded, &body); err != nil {
+			t.Fatalf("request %d body is not an incident: %v", i, err)
+		}
+		if body.ShortDescription != incidents[i].ShortDescription {
+			t.Errorf("request %d short_description = %q, want %q", i, body.ShortDescription, incidents[i].ShortDescription)
+		}
+	}
+}
+
+// servedBody base64-encodes a Table API body for a serviced batch request.
+func servedBody(body string) string {
+	return base64.StdEncoding.EncodeToString([]byte(body))
+}
+
+func TestParseBatchResponse(t *testing.T) {
+	resp := batchResponse{
+		BatchRequestID: "1",
+		ServicedRequests: []batchServicedRequest{
+			{ID: "object", StatusCode: 201, Body: servedBody(`{"result":{"sys_id":"s1","number":"INC0000001"}}`)},
+			{ID: "array", StatusCode: 201, Body: servedBody(`{"result":[{"sys_id":"s2","number":"INC0000002"}]}`)},
+			{ID: "rejected", StatusCode: 403, Body: servedBody(`{"error":{"message":"denied"}}`)},
+			{ID: "garbled", StatusCode: 201, Body: "not base64!"},
+		},
+		UnservicedRequests: []string{"unserviced"},
+	}
+	body, _ := json.Marshal(resp)
+
+	results, err := parseBatchResponse(body)
+	if err != nil {
+		t.Fatalf("parseBatchResponse() error = %v", err)
+	}
+
+	want := map[string]string{"object": "INC0000001", "array": "INC0000002"}
+	if len(results) != len(want) {
+		t.Errorf("expected %d results, got %v", len(want), results)
+	}
+	for id, number := range want {
+		if results[id] == nil || results[id].Number != number {
+			t.Errorf("results[%q] = %+v, want number %q", id, results[id], number)
+		}
+	}
+}
+
+// batchServer serves the Batch API, creating each incident unless its
+// correlation ID is in reject, and counts individual Table API creates.
+type batchServer struct {
+	mu           sync.Mutex
+	batchCalls   int
+	batchStatus  int
+	reject       map[string]bool
+	singleCreate []string
+}
+
+func (s *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/now/v1/batch":
+		s.batchCalls++
+		if s.batchStatus != 0 {
+			w.WriteHeader(s.batchStatus)
+			return
+		}
+		var req batchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		resp := batchResponse{BatchRequestID: req.BatchRequestID}
+		for _, rr := range req.RestRequests {
+			if s.reject[rr.ID] {
+				resp.ServicedRequests = append(resp.ServicedRequests, batchServicedRequest{ID: rr.ID, StatusCode: 400})
+				continue
+			}
+			resp.ServicedRequests = append(resp.ServicedRequests, batchServicedRequest{
+				ID:         rr.ID,
+				StatusCode: 201,
+				Body:       servedBody(fmt.Sprintf(`{"result":{"sys_id":"batch-%s","number":"INC-%s"}}`, rr.ID, rr.ID)),
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/api/now/table/incident":
+		var incident models.ServiceNowIncident
+		json.NewDecoder(r.Body).Decode(&incident)
+		s.singleCreate = append(s.singleCreate, incident.CorrelationID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.ServiceNowResponse{
+			Result: models.ServiceNowResult{SysID: "single-" + incident.CorrelationID, Number: "INC-single"},
+		})
+	default:
+		http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+// createConcurrently issues one CreateIncident per correlation ID at once
+// and returns the results keyed by correlation ID.
+func createConcurrently(t *testing.T, client *Client, ids ...string) map[string]*CreateIncidentResult {
+	t.Helper()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]*CreateIncidentResult)
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: id})
+			if err != nil {
+				t.Errorf("CreateIncident(%s) error = %v", id, err)
+				return
+			}
+			mu.Lock()
+			results[id] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func newBatchingClient(serverURL string) *Client {
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      serverURL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+		ServiceNowAPIMode:      config.APIModeTable,
+		ServiceNowBatchEnabled: true,
+		ServiceNowBatchPath:    "/api/now/v1/batch",
+		ServiceNowBatchMaxSize: 3,
+		ServiceNowBatchLinger:  time.Minute,
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+	return client
+}
+
+func TestClient_CreateIncident_Batched(t *testing.T) {
+	srv := &batchServer{reject: map[string]bool{"b": true}}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	// A full batch is sent without waiting for the linger timer.
+	results := createConcurrently(t, newBatchingClient(server.URL), "a", "b", "c")
+
+	if srv.batchCalls != 1 {
+		t.Errorf("expected 1 batch call, got %d", srv.batchCalls)
+	}
+	for _, id := range []string{"a", "c"} {
+		if results[id] == nil || results[id].SysID != "batch-"+id || results[id].Number != "INC-"+id {
+			t.Errorf("result for %s = %+v, want batch-created incident", id, results[id])
+		}
+	}
+
+	// The request the batch rejected is retried on its own.
+	if len(srv.singleCreate) != 1 || srv.singleCreate[0] != "b" {
+		t.Errorf("expected only b to be created individually, got %v", srv.singleCreate)
+	}
+	if results["b"] == nil || results["b"].SysID != "single-b" {
+		t.Errorf("result for b = %+v, want individually created incident", results["b"])
+	}
+}
+
+func TestClient_CreateIncident_BatchFailureFallsBack(t *testing.T) {
+	srv := &batchServer{batchStatus: http.StatusBadRequest}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	results := createConcurrently(t, newBatchingClient(server.URL), "a", "b", "c")
+
+	if srv.batchCalls != 1 {
+		t.Errorf("expected 1 batch call, got %d", srv.batchCalls)
+	}
+	if len(srv.singleCreate) != 3 {
+		t.Errorf("expected all 3 incidents to be created individually, got %v", srv.singleCreate)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if results[id] == nil || results[id].SysID != "single-"+id {
+			t.Errorf("result for %s = %+v, want individually created incident", id, results[id])
+		}
+	}
+}
+
+func TestClient_CreateIncident_BatchLinger(t *testing.T) {
+	srv := &batchServer{}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	client := newBatchingClient(server.URL)
+	client.batcher.linger = 10 * time.Millisecond
+
+	// A partial batch is sent once the linger time passes.
+	results := createConcurrently(t, client, "a")
+
+	if srv.batchCalls != 1 || len(srv.singleCreate) != 0 {
+		t.Errorf("expected 1 batch call and no individual creates, got %d and %v", srv.batchCalls, srv.singleCreate)
+	}
+	if results["a"] == nil || results["a"].SysID != "batch-a" {
+		t.Errorf("result for a = %+v, want batch-created incident", results["a"])
+	}
+}
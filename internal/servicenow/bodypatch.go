@@ -0,0 +1,41 @@
+package servicenow
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// patchBody applies SERVICENOW_BODY_PATCH to an encoded create body as a JSON
+// merge patch (RFC 7386). A nil patch returns body unchanged.
+func patchBody(body []byte, patch map[string]any) ([]byte, error) {
+	if patch == nil {
+		return body, nil
+	}
+	var target any
+	if err := json.Unmarshal(body, &target); err != nil {
+		return nil, fmt.Errorf("failed to apply body patch: %w", err)
+	}
+	return json.Marshal(mergePatch(target, patch))
+}
+
+// mergePatch merges patch into target: objects are merged key by key, null
+// removes a key, and any other value replaces the target's. Maps in patch
+// are copied rather than shared, so the patch can be reused.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], value)
+	}
+	return t
+}
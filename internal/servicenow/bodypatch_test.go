@@ -0,0 +1,103 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestPatchBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		patch string
+		want  string
+	}{
+		{name: "no patch", body: `{"a":"b"}`, want: `{"a":"b"}`},
+		{name: "replace value", body: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add value", body: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove value", body: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "add nested object", body: `{"a":"b"}`, patch: `{"u_meta":{"source":"alertmanager","tags":["ocp"]}}`, want: `{"a":"b","u_meta":{"source":"alertmanager","tags":["ocp"]}}`},
+		{name: "merge nested object", body: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"b":"x","d":null}}`, want: `{"a":{"b":"x"}}`},
+		{name: "object replaces string", body: `{"a":"b"}`, patch: `{"a":{"c":"d","e":null}}`, want: `{"a":{"c":"d"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]any
+			if tt.patch != "" {
+				if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := patchBody([]byte(tt.body), patch)
+			if err != nil {
+				t.Fatalf("patchBody() error = %v", err)
+			}
+
+			var gotValue, wantValue any
+			json.Unmarshal(got, &gotValue)
+			json.Unmarshal([]byte(tt.want), &wantValue)
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("patchBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CreateIncident_BodyPatch(t *testing.T) {
+	var receivedBodies []map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		receivedBodies = append(receivedBodies, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.ServiceNowResponse{
+			Result: models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"},
+		})
+	}))
+	defer server.Close()
+
+	var patch map[string]any
+	json.Unmarshal([]byte(`{"u_payload":{"source":"alertmanager"},"subcategory":null}`), &patch)
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowBodyPatch:    patch,
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	// Create twice to check the patch is not consumed by the first request.
+	for range 2 {
+		_, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{
+			ShortDescription: "[test-cluster] TestAlert",
+			Subcategory:      "openshift",
+			CorrelationID:    "abc123def456",
+		})
+		if err != nil {
+			t.Fatalf("CreateIncident() error = %v", err)
+		}
+	}
+
+	for i, body := range receivedBodies {
+		if got := body["u_payload"]; !reflect.DeepEqual(got, map[string]any{"source": "alertmanager"}) {
+			t.Errorf("request %d u_payload = %v, want the nested object from the patch", i, got)
+		}
+		if _, ok := body["subcategory"]; ok {
+			t.Errorf("request %d: expected subcategory to be removed by the patch", i)
+		}
+		if body["short_description"] != "[test-cluster] TestAlert" || body["correlation_id"] != "abc123def456" {
+			t.Errorf("request %d: patch changed unrelated fields: %v", i, body)
+		}
+	}
+}
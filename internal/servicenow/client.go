@@ -9,36 +9,150 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 )
 
 // Client handles communication with the ServiceNow Table API.
 type Client struct {
-	baseURL      string
-	endpointPath string
-	username     string
-	password     string
-	rootCause    string
-	httpClient   *http.Client
-	retryConfig  RetryConfig
-	logger       *slog.Logger
-}
-
-// NewClient creates a new ServiceNow API client.
-func NewClient(cfg *config.Config, logger *slog.Logger) *Client {
-	return &Client{
-		baseURL:      cfg.ServiceNowBaseURL,
-		endpointPath: cfg.ServiceNowEndpointPath,
-		username:     cfg.ServiceNowUsername,
-		password:     cfg.ServiceNowPassword,
-		rootCause:    cfg.ServiceNowRootCause,
-		httpClient:   &http.Client{Timeout: 30_000_000_000}, // 30 seconds
-		retryConfig:  DefaultRetryConfig(),
-		logger:       logger,
+	baseURL           string
+	endpointPath      string
+	rootCause         string
+	closeCode         string
+	resolvedState     string
+	apiMode           string
+	importPath        string
+	importFieldPrefix string
+	batchPath         string
+	bodyPatch         map[string]any
+	skipResponseParse bool
+	lookupBatchSize   int
+	caller            *sysIDResolver
+	batcher           *batcher
+	httpClient        *http.Client
+	retryConfig       RetryConfig
+	logSampler        *requestSampler
+	metrics           *metrics.Metrics
+	logger            *slog.Logger
+
+	// authFailures counts responses rejected with 401 or 403 since the
+	// last successful one.
+	authFailures atomic.Int64
+
+	// creds and assignmentGroup are replaced by Reload.
+	creds           atomic.Pointer[credentials]
+	assignmentGroup atomic.Pointer[sysIDResolver]
+}
+
+// credentials are the ServiceNow account the client authenticates as.
+type credentials struct {
+	username string
+	password string
+}
+
+// NewClient creates a new ServiceNow API client that records every HTTP
+// request in m.
+func NewClient(cfg *config.Config, m *metrics.Metrics, logger *slog.Logger) *Client {
+	c := &Client{
+		baseURL:           cfg.ServiceNowBaseURL,
+		endpointPath:      cfg.ServiceNowEndpointPath,
+		rootCause:         cfg.ServiceNowRootCause,
+		closeCode:         cfg.ServiceNowCloseCode,
+		resolvedState:     cfg.ServiceNowResolvedState,
+		apiMode:           cfg.ServiceNowAPIMode,
+		importPath:        cfg.ServiceNowImportPath,
+		importFieldPrefix: cfg.ServiceNowImportFieldPrefix,
+		batchPath:         cfg.ServiceNowBatchPath,
+		bodyPatch:         cfg.ServiceNowBodyPatch,
+		skipResponseParse: cfg.ServiceNowSkipResponseParse,
+		lookupBatchSize:   cfg.ServiceNowLookupBatchSize,
+		httpClient:        &http.Client{Timeout: httpTimeout(cfg), Transport: newTransport(cfg, logger)},
+		retryConfig:       retryConfigFromConfig(cfg),
+		logSampler:        newRequestSampler(cfg.ServiceNowLogSampleRate),
+		metrics:           m,
+		logger:            logger,
+	}
+	if c.closeCode == "" {
+		c.closeCode = models.DefaultCloseCode
+	}
+	if c.resolvedState == "" {
+		c.resolvedState = models.StateResolved
+	}
+	c.Reload(cfg)
+	if cfg.CallerIDByUsername && cfg.ServiceNowCallerID != "" {
+		c.caller = newCallerResolver(cfg.ServiceNowCallerID)
+	}
+	if cfg.ServiceNowBatchEnabled && c.apiMode != config.APIModeImport {
+		c.batcher = &batcher{
+			client:  c,
+			maxSize: max(cfg.ServiceNowBatchMaxSize, 1),
+			linger:  cfg.ServiceNowBatchLinger,
+			timeout: httpTimeout(cfg) * time.Duration(c.retryConfig.MaxAttempts),
+		}
+	}
+	return c
+}
+
+// Reload switches the client to the credentials and assignment group in
+// cfg, such as a configuration returned by config.Reload. Requests already
+// sent keep the old credentials. A new assignment group name is looked up
+// again on the next create.
+func (c *Client) Reload(cfg *config.Config) {
+	c.creds.Store(&credentials{username: cfg.ServiceNowUsername, password: cfg.ServiceNowPassword})
+
+	if !cfg.AssignmentGroupByName || cfg.ServiceNowAssignmentGroup == "" {
+		c.assignmentGroup.Store(nil)
+		return
+	}
+	if r := c.assignmentGroup.Load(); r == nil || r.value != cfg.ServiceNowAssignmentGroup {
+		c.assignmentGroup.Store(newGroupResolver(cfg.ServiceNowAssignmentGroup))
+	}
+}
+
+// defaultHTTPTimeout is used when no ServiceNow HTTP timeout is configured.
+const defaultHTTPTimeout = 30 * time.Second
+
+// httpTimeout returns the configured request timeout, or the default if unset.
+func httpTimeout(cfg *config.Config) time.Duration {
+	if cfg.ServiceNowHTTPTimeout > 0 {
+		return cfg.ServiceNowHTTPTimeout
+	}
+	return defaultHTTPTimeout
+}
+
+// retryConfigFromConfig builds a RetryConfig from the configuration, keeping
+// the defaults for any value that is not set.
+func retryConfigFromConfig(cfg *config.Config) RetryConfig {
+	rc := DefaultRetryConfig()
+	rc.Rand = newJitterSource()
+	if cfg.ServiceNowRetryMaxAttempts > 0 {
+		rc.MaxAttempts = cfg.ServiceNowRetryMaxAttempts
+	}
+	if cfg.ServiceNowRetryBaseDelay > 0 {
+		rc.BaseDelay = cfg.ServiceNowRetryBaseDelay
+	}
+	if cfg.ServiceNowRetryMaxDelay > 0 {
+		rc.MaxDelay = cfg.ServiceNowRetryMaxDelay
+	}
+	rc.MaxElapsed = cfg.ServiceNowRetryMaxElapsed
+	if cfg.ServiceNowRetryAfterMax > 0 {
+		rc.MaxRetryAfter = cfg.ServiceNowRetryAfterMax
 	}
+	return rc
+}
+
+// retryConfigFor returns the client's retry configuration with attempt
+// logging tagged with the operation and attrs, such as the correlation ID.
+func (c *Client) retryConfigFor(op string, attrs ...any) RetryConfig {
+	rc := c.retryConfig
+	rc.Logger = c.logger.With(append([]any{"operation", op}, attrs...)...)
+	return rc
 }
 
 // CreateIncidentResult contains the result of creating an incident.
@@ -47,23 +161,68 @@ type CreateIncidentResult struct {
 	Number string
 }
 
-// CreateIncident creates a new incident in ServiceNow and returns the incident number.
-func (c *Client) CreateIncident(ctx context.Context, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
-	endpoint := c.baseURL + c.endpointPath
+// tablePath returns path, or the configured SERVICENOW_ENDPOINT_PATH if path
+// is empty. Callers pass the path of the table an alert is routed to.
+func (c *Client) tablePath(path string) string {
+	if path == "" {
+		return c.endpointPath
+	}
+	return path
+}
+
+// CreateIncident creates a new record in the table at path, or the configured
+// table if path is empty, and returns the incident number.
+// In import mode the incident is posted to the configured staging table and the
+// record created by the transform map is returned. With batching enabled the
+// create is combined with concurrent ones into a Batch API request; only
+// creates in the configured table are batched. With
+// SERVICENOW_ASSIGNMENT_GROUP_IS_NAME and SERVICENOW_CALLER_ID_IS_USERNAME
+// the configured group name and caller username are replaced by sys_ids.
+// With SERVICENOW_SKIP_RESPONSE_PARSE unbatched creates return an empty
+// result.
+func (c *Client) CreateIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
+	c.resolveReferences(ctx, &incident)
+	path = c.tablePath(path)
+	if c.batcher != nil && path == c.endpointPath {
+		return c.batcher.create(ctx, incident)
+	}
+	return c.createIncident(ctx, path, incident)
+}
+
+// createIncident creates a single incident with its own HTTP request.
+func (c *Client) createIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
+	endpoint := c.baseURL + path
+	var payload interface{} = incident
+	parse := parseTableResponse
+
+	if c.apiMode == config.APIModeImport {
+		row, err := stagingPayload(incident, c.importFieldPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build import set row: %w", err)
+		}
+		endpoint = c.baseURL + c.importPath
+		payload = row
+		parse = parseImportResponse
+	}
 
-	body, err := json.Marshal(incident)
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal incident: %w", err)
 	}
+	if body, err = patchBody(body, c.bodyPatch); err != nil {
+		return nil, err
+	}
 
 	c.logger.Debug("creating incident in ServiceNow",
 		"correlation_id", incident.CorrelationID,
 		"short_description", incident.ShortDescription,
+		"api_mode", c.apiMode,
+		"path", path,
 	)
 
-	var result *CreateIncidentResult
+	var respBody []byte
 
-	err = WithRetry(ctx, c.retryConfig, func() error {
+	err = WithRetry(ctx, c.retryConfigFor(opCreate, "correlation_id", incident.CorrelationID), func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
@@ -71,7 +230,7 @@ func (c *Client) CreateIncident(ctx context.Context, incident models.ServiceNowI
 
 		c.setHeaders(req)
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := c.do(req, opCreate)
 		if err != nil {
 			return fmt.Errorf("failed to send request: %w", err)
 		}
@@ -81,20 +240,19 @@ func (c *Client) CreateIncident(ctx context.Context, incident models.ServiceNowI
 			return err
 		}
 
-		// Parse response to extract incident number
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+		if c.skipResponseParse {
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, resp.Body)
+			c.logger.Debug("created incident without reading the response",
+				"correlation_id", incident.CorrelationID,
+				"status_code", resp.StatusCode,
+			)
+			return nil
 		}
 
-		var snowResp models.ServiceNowResponse
-		if err := json.Unmarshal(respBody, &snowResp); err != nil {
-			return fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-
-		result = &CreateIncidentResult{
-			SysID:  snowResp.Result.SysID,
-			Number: snowResp.Result.Number,
+		respBody, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
 		}
 
 		return nil
@@ -103,23 +261,97 @@ func (c *Client) CreateIncident(ctx context.Context, incident models.ServiceNowI
 	if err != nil {
 		return nil, err
 	}
+	if c.skipResponseParse {
+		return &CreateIncidentResult{}, nil
+	}
 
-	return result, nil
+	// Parse outside the retry loop: the record was created, so a parse failure
+	// must not trigger a duplicate POST.
+	return parse(respBody)
 }
 
-// FindIncidentByCorrelationID searches for an existing incident by correlation ID.
-func (c *Client) FindIncidentByCorrelationID(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
-	// Build query URL with correlation_id filter
-	endpoint := fmt.Sprintf("%s%s?sysparm_query=correlation_id=%s&sysparm_limit=1",
-		c.baseURL, c.endpointPath, url.QueryEscape(correlationID))
+// parseTableResponse extracts the created incident from a Table API response.
+// Some scripted endpoints wrap a single created record in an array, so an
+// array result is accepted and its first element used.
+func parseTableResponse(body []byte) (*CreateIncidentResult, error) {
+	var raw struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var result models.ServiceNowResult
+	if trimmed := bytes.TrimSpace(raw.Result); len(trimmed) > 0 && trimmed[0] == '[' {
+		var results []models.ServiceNowResult
+		if err := json.Unmarshal(trimmed, &results); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(results) == 0 {
+			return nil, fmt.Errorf("response result array is empty")
+		}
+		result = results[0]
+	} else if len(trimmed) > 0 {
+		if err := json.Unmarshal(trimmed, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+
+	return &CreateIncidentResult{
+		SysID:  result.SysID,
+		Number: result.Number,
+	}, nil
+}
 
+// FindIncidentByCorrelationID searches the table at path, or the configured
+// table if path is empty, for an existing incident by correlation ID. An
+// alert that keeps re-firing leaves several incidents with the same ID; the
+// newest open one is returned, or the newest if none is open.
+func (c *Client) FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error) {
 	c.logger.Debug("searching for incident by correlation_id",
 		"correlation_id", correlationID,
 	)
+	return c.findIncident(ctx, path, "correlation_id="+correlationID, correlationID)
+}
+
+// FindIncidentByCorrelationIDOrField searches like FindIncidentByCorrelationID
+// but also matches incidents whose field holds value, in a single query. An
+// empty field or value, or a value that could change the meaning of the
+// encoded query, searches by correlation ID alone.
+func (c *Client) FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error) {
+	if field == "" || value == "" || strings.ContainsAny(value, queryMetaChars) {
+		return c.FindIncidentByCorrelationID(ctx, path, correlationID)
+	}
+
+	c.logger.Debug("searching for incident by correlation_id or field",
+		"correlation_id", correlationID,
+		field, value,
+	)
+	return c.findIncident(ctx, path, fmt.Sprintf("correlation_id=%s^OR%s=%s", correlationID, field, value), correlationID)
+}
+
+// queryMetaChars are the characters with a meaning in encoded queries; values
+// containing them are not matched on.
+const queryMetaChars = "^,=\r\n"
+
+// newestFirst orders correlation ID lookups by creation time, newest first,
+// so the incident the agent is working with wins over older ones.
+const newestFirst = "^ORDERBYDESCsys_created_on"
+
+// correlationLookupLimit bounds how many incidents a correlation ID lookup
+// fetches to find an open one among them.
+const correlationLookupLimit = 10
+
+// findIncident returns the newest open incident in the table at path matching
+// the encoded query, the newest incident if none of them is open, or nil if
+// none matches.
+func (c *Client) findIncident(ctx context.Context, path, query, correlationID string) (*models.ServiceNowResult, error) {
+	endpoint := fmt.Sprintf("%s%s?sysparm_query=%s&sysparm_limit=%d",
+		c.baseURL, c.tablePath(path), url.QueryEscape(query+newestFirst), correlationLookupLimit)
 
 	var result *models.ServiceNowResult
 
-	err := WithRetry(ctx, c.retryConfig, func() error {
+	err := WithRetry(ctx, c.retryConfigFor(opFind, "correlation_id", correlationID), func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
@@ -127,7 +359,7 @@ func (c *Client) FindIncidentByCorrelationID(ctx context.Context, correlationID
 
 		c.setHeaders(req)
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := c.do(req, opFind)
 		if err != nil {
 			return fmt.Errorf("failed to send request: %w", err)
 		}
@@ -147,8 +379,10 @@ func (c *Client) FindIncidentByCorrelationID(ctx context.Context, correlationID
 			return fmt.Errorf("failed to unmarshal response: %w", err)
 		}
 
-		if len(listResp.Result) > 0 {
-			result = &listResp.Result[0]
+		for i := range listResp.Result {
+			if result == nil || !c.isOpen(result) && c.isOpen(&listResp.Result[i]) {
+				result = &listResp.Result[i]
+			}
 		}
 
 		return nil
@@ -161,16 +395,25 @@ func (c *Client) FindIncidentByCorrelationID(ctx context.Context, correlationID
 	return result, nil
 }
 
-// ResolveIncident updates an incident's state to resolved.
-func (c *Client) ResolveIncident(ctx context.Context, sysID string) error {
-	endpoint := fmt.Sprintf("%s%s/%s", c.baseURL, c.endpointPath, sysID)
+// ResolveIncident moves an incident to SERVICENOW_RESOLVED_STATE, resolved
+// by default, with the given close notes, falling back to
+// models.DefaultResolveNotes when empty, and adds workNotes as a work note
+// unless it is empty. path is the incident's table, or empty for the
+// configured one.
+func (c *Client) ResolveIncident(ctx context.Context, path, sysID, closeNotes, workNotes string) error {
+	if closeNotes == "" {
+		closeNotes = models.DefaultResolveNotes
+	}
+
+	endpoint := fmt.Sprintf("%s%s/%s", c.baseURL, c.tablePath(path), sysID)
 
 	payload := models.ServiceNowUpdatePayload{
-		State:        models.StateResolved,
-		CloseCode:    "Solved (Permanently)",
-		CloseNotes:   "Alert resolved - condition cleared automatically",
+		State:        c.resolvedState,
+		CloseCode:    c.closeCode,
+		CloseNotes:   closeNotes,
 		RootCause:    c.rootCause,
 		RestoredDate: time.Now().UTC().Format("01/02/2006 03:04:05 PM"),
+		WorkNotes:    workNotes,
 	}
 
 	body, err := json.Marshal(payload)
@@ -182,7 +425,7 @@ func (c *Client) ResolveIncident(ctx context.Context, sysID string) error {
 		"sys_id", sysID,
 	)
 
-	return WithRetry(ctx, c.retryConfig, func() error {
+	return WithRetry(ctx, c.retryConfigFor(opResolve, "sys_id", sysID), func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
@@ -190,7 +433,141 @@ func (c *Client) ResolveIncident(ctx context.Context, sysID string) error {
 
 		c.setHeaders(req)
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := c.do(req, opResolve)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if err := c.checkResponse(resp); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// FindResolvedIncidentsBefore returns incidents created by this agent's service
+// account that are still in the state resolves move incidents to
+// (SERVICENOW_RESOLVED_STATE) and were resolved before cutoff. At most limit
+// records are returned per call, and none when that state is closed.
+func (c *Client) FindResolvedIncidentsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.ServiceNowResult, error) {
+	if c.resolvedState == models.StateClosed {
+		return nil, nil
+	}
+	query := fmt.Sprintf("state=%s^sys_created_by=%s^correlation_idISNOTEMPTY^resolved_at<%s",
+		c.resolvedState, c.creds.Load().username, cutoff.UTC().Format(models.TimeLayout))
+
+	c.logger.Debug("searching for resolved incidents to close",
+		"cutoff", cutoff.UTC().Format(time.RFC3339),
+	)
+
+	return c.listIncidents(ctx, "", query, limit, 0)
+}
+
+// FindOpenIncidentsByShortDescriptionPrefix returns open (neither resolved
+// nor closed) incidents created by this agent's service account whose
+// short_description starts with prefix, newest first. At most limit records
+// are returned.
+func (c *Client) FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error) {
+	query := fmt.Sprintf("short_descriptionSTARTSWITH%s^stateNOT IN%s^sys_created_by=%s^ORDERBYDESCsys_created_on",
+		prefix, c.notOpenStates(), c.creds.Load().username)
+
+	c.logger.Debug("searching for open incidents by short_description",
+		"prefix", prefix,
+	)
+
+	return c.listIncidents(ctx, "", query, limit, 0)
+}
+
+// defaultLookupBatchSize is the number of correlation IDs per query when
+// SERVICENOW_LOOKUP_BATCH_SIZE is not set.
+const defaultLookupBatchSize = 50
+
+// FindIncidentsByCorrelationIDs looks up the incidents with the given
+// correlation IDs in the table at path, querying up to
+// SERVICENOW_LOOKUP_BATCH_SIZE IDs at a time with correlation_idIN and
+// following pagination. The result maps each correlation ID to its incident;
+// IDs without one are absent. As with FindIncidentByCorrelationID, the newest
+// open incident for an ID wins, or the newest if none is open.
+func (c *Client) FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error) {
+	size := c.lookupBatchSize
+	if size < 1 {
+		size = defaultLookupBatchSize
+	}
+
+	found := make(map[string]*models.ServiceNowResult, len(ids))
+	for start := 0; start < len(ids); start += size {
+		batch := ids[start:min(start+size, len(ids))]
+		query := "correlation_idIN" + strings.Join(batch, ",") + newestFirst
+
+		c.logger.Debug("searching for incidents by correlation_id",
+			"correlation_ids", len(batch),
+		)
+
+		for offset := 0; ; offset += size {
+			page, err := c.listIncidents(ctx, path, query, size, offset)
+			if err != nil {
+				return nil, err
+			}
+			for i := range page {
+				if prev, ok := found[page[i].CorrelationID]; !ok || !c.isOpen(prev) && c.isOpen(&page[i]) {
+					found[page[i].CorrelationID] = &page[i]
+				}
+			}
+			if len(page) < size {
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// writeClient returns c.
+func (c *Client) writeClient() *Client {
+	return c
+}
+
+// isOpen reports whether an incident is neither resolved nor closed, nor in
+// the state resolves move incidents to.
+func (c *Client) isOpen(incident *models.ServiceNowResult) bool {
+	switch incident.State {
+	case models.StateResolved, models.StateClosed, c.resolvedState:
+		return false
+	}
+	return true
+}
+
+// notOpenStates returns the states isOpen rejects, comma-separated for an
+// IN query.
+func (c *Client) notOpenStates() string {
+	states := []string{models.StateResolved, models.StateClosed}
+	if !slices.Contains(states, c.resolvedState) {
+		states = append(states, c.resolvedState)
+	}
+	return strings.Join(states, ",")
+}
+
+// listIncidents runs an encoded query against the table at path and returns
+// at most limit records, skipping the first offset.
+func (c *Client) listIncidents(ctx context.Context, path, query string, limit, offset int) ([]models.ServiceNowResult, error) {
+	endpoint := fmt.Sprintf("%s%s?sysparm_query=%s&sysparm_limit=%d",
+		c.baseURL, c.tablePath(path), url.QueryEscape(query), limit)
+	if offset > 0 {
+		endpoint += fmt.Sprintf("&sysparm_offset=%d", offset)
+	}
+
+	var results []models.ServiceNowResult
+
+	err := WithRetry(ctx, c.retryConfigFor(opList), func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		c.setHeaders(req)
+
+		resp, err := c.do(req, opList)
 		if err != nil {
 			return fmt.Errorf("failed to send request: %w", err)
 		}
@@ -200,13 +577,119 @@ func (c *Client) ResolveIncident(ctx context.Context, sysID string) error {
 			return err
 		}
 
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		var listResp models.ServiceNowListResponse
+		if err := json.Unmarshal(respBody, &listResp); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		results = listResp.Result
 		return nil
 	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CloseIncident updates a resolved incident's state to closed.
+func (c *Client) CloseIncident(ctx context.Context, sysID string) error {
+	c.logger.Debug("closing incident in ServiceNow",
+		"sys_id", sysID,
+	)
+
+	return c.patchIncident(ctx, opClose, c.endpointPath, sysID, models.ServiceNowUpdatePayload{State: models.StateClosed})
+}
+
+// ReopenIncident moves a resolved incident back to in progress, adding note
+// as a work note. path is the incident's table, or empty for the configured one.
+func (c *Client) ReopenIncident(ctx context.Context, path, sysID, note string) error {
+	c.logger.Debug("reopening incident in ServiceNow",
+		"sys_id", sysID,
+	)
+
+	return c.patchIncident(ctx, opReopen, c.tablePath(path), sysID, models.ServiceNowReopenPayload{
+		State:     models.StateInProgress,
+		WorkNotes: note,
+	})
+}
+
+// AddWorkNote appends a work note to an existing incident. path is the
+// incident's table, or empty for the configured one.
+func (c *Client) AddWorkNote(ctx context.Context, path, sysID, note string) error {
+	c.logger.Debug("adding work note in ServiceNow",
+		"sys_id", sysID,
+	)
+
+	return c.patchIncident(ctx, opWorkNote, c.tablePath(path), sysID, models.ServiceNowWorkNotePayload{WorkNotes: note})
+}
+
+// patchIncident sends a PATCH with the given payload to a record in the
+// table at path, recorded in metrics as op.
+func (c *Client) patchIncident(ctx context.Context, op, path, sysID string, payload interface{}) error {
+	endpoint := fmt.Sprintf("%s%s/%s", c.baseURL, path, sysID)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update payload: %w", err)
+	}
+
+	return WithRetry(ctx, c.retryConfigFor(op, "sys_id", sysID), func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		c.setHeaders(req)
+
+		resp, err := c.do(req, op)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		return c.checkResponse(resp)
+	})
+}
+
+// Ping performs a single lightweight authenticated read of the incident table
+// to verify that ServiceNow is reachable and the credentials are accepted.
+// It does not retry; callers bound it with ctx.
+func (c *Client) Ping(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s%s?sysparm_limit=1&sysparm_fields=sys_id", c.baseURL, c.endpointPath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.do(req, opPing)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return c.checkResponse(resp)
+}
+
+// ConsecutiveAuthFailures returns how many responses in a row ServiceNow
+// rejected with 401 or 403; any successful response resets it.
+func (c *Client) ConsecutiveAuthFailures() int {
+	return int(c.authFailures.Load())
 }
 
 // setHeaders sets common headers for ServiceNow API requests.
 func (c *Client) setHeaders(req *http.Request) {
-	req.SetBasicAuth(c.username, c.password)
+	creds := c.creds.Load()
+	req.SetBasicAuth(creds.username, creds.password)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 }
@@ -214,18 +697,33 @@ func (c *Client) setHeaders(req *http.Request) {
 // checkResponse validates the HTTP response from ServiceNow.
 func (c *Client) checkResponse(resp *http.Response) error {
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		c.authFailures.Store(0)
 		return nil
 	}
 
 	body, _ := io.ReadAll(resp.Body)
 
-	c.logger.Error("ServiceNow API error",
-		"status_code", resp.StatusCode,
-		"response", string(body),
-	)
+	if isAuthStatus(resp.StatusCode) {
+		failures := c.authFailures.Add(1)
+		if c.metrics != nil {
+			c.metrics.ServiceNowAuthFailures.Inc()
+		}
+		c.logger.Error("ServiceNow rejected the credentials",
+			"status_code", resp.StatusCode,
+			"consecutive_failures", failures,
+			"username", c.creds.Load().username,
+			"hint", "check that the service account password has not expired and the account has the required roles",
+		)
+	} else {
+		c.logger.Error("ServiceNow API error",
+			"status_code", resp.StatusCode,
+			"response", string(body),
+		)
+	}
 
 	return &RetryableError{
 		Err:        fmt.Errorf("ServiceNow API returned status %d: %s", resp.StatusCode, string(body)),
 		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
 	}
 }
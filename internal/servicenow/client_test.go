@@ -4,12 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 )
 
@@ -53,7 +61,7 @@ func TestClient_CreateIncident(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	// Disable retries for testing
 	client.retryConfig.MaxAttempts = 1
 
@@ -67,7 +75,7 @@ func TestClient_CreateIncident(t *testing.T) {
 		CorrelationID:    "abc123def456",
 	}
 
-	result, err := client.CreateIncident(context.Background(), incident)
+	result, err := client.CreateIncident(context.Background(), "", incident)
 	if err != nil {
 		t.Errorf("CreateIncident() error = %v", err)
 	}
@@ -92,6 +100,157 @@ func TestClient_CreateIncident(t *testing.T) {
 	}
 }
 
+func TestClient_CreateIncident_ResultShapes(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantSysID  string
+		wantNumber string
+		wantErr    bool
+	}{
+		{
+			name:       "object result",
+			body:       `{"result":{"sys_id":"abc123","number":"INC0001234"}}`,
+			wantSysID:  "abc123",
+			wantNumber: "INC0001234",
+		},
+		{
+			name:       "array result uses first element",
+			body:       `{"result":[{"sys_id":"abc123","number":"INC0001234"},{"sys_id":"def456","number":"INC0001235"}]}`,
+			wantSysID:  "abc123",
+			wantNumber: "INC0001234",
+		},
+		{
+			name:    "empty array result",
+			body:    `{"result":[]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				ServiceNowBaseURL:      server.URL,
+				ServiceNowEndpointPath: "/api/now/table/incident",
+				ServiceNowUsername:     "testuser",
+				ServiceNowPassword:     "testpass",
+			}
+
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+
+			result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "abc123def456"})
+			if requests != 1 {
+				t.Errorf("expected 1 request, got %d", requests)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("CreateIncident() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateIncident() error = %v", err)
+			}
+			if result.SysID != tt.wantSysID || result.Number != tt.wantNumber {
+				t.Errorf("CreateIncident() = %+v, want sys_id %q number %q", result, tt.wantSysID, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func TestClient_CreateIncident_SkipResponseParse(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		// Not JSON, and large enough that an unread body isn't drained by
+		// the transport: parsing would fail and the connection be dropped.
+		w.Write([]byte(strings.Repeat("not json ", 64*1024)))
+	}))
+	var connections atomic.Int32
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:           server.URL,
+		ServiceNowEndpointPath:      "/api/now/table/incident",
+		ServiceNowUsername:          "testuser",
+		ServiceNowPassword:          "testpass",
+		ServiceNowSkipResponseParse: true,
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	for i := 0; i < 2; i++ {
+		result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "abc123"})
+		if err != nil {
+			t.Fatalf("CreateIncident() error = %v", err)
+		}
+		if *result != (CreateIncidentResult{}) {
+			t.Errorf("expected empty result, got %+v", result)
+		}
+	}
+
+	if got := connections.Load(); got != 1 {
+		t.Errorf("expected the connection to be reused, got %d connections", got)
+	}
+}
+
+func TestClient_CreateIncident_ExtraFields(t *testing.T) {
+	var receivedBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&receivedBody); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.ServiceNowResponse{
+			Result: models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	_, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{
+		ShortDescription: "[test-cluster] TestAlert",
+		CorrelationID:    "abc123def456",
+		ExtraFields: map[string]string{
+			"u_cluster":      "test-cluster",
+			"correlation_id": "overridden",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+
+	if receivedBody["u_cluster"] != "test-cluster" {
+		t.Errorf("expected u_cluster 'test-cluster', got %q", receivedBody["u_cluster"])
+	}
+	if receivedBody["short_description"] != "[test-cluster] TestAlert" {
+		t.Errorf("expected short_description to be sent, got %q", receivedBody["short_description"])
+	}
+	if receivedBody["correlation_id"] != "abc123def456" {
+		t.Errorf("extra field replaced correlation_id: got %q", receivedBody["correlation_id"])
+	}
+}
+
 func TestClient_FindIncidentByCorrelationID(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -99,8 +258,8 @@ func TestClient_FindIncidentByCorrelationID(t *testing.T) {
 		}
 
 		query := r.URL.Query().Get("sysparm_query")
-		if query != "correlation_id=test-correlation-id" {
-			t.Errorf("expected query 'correlation_id=test-correlation-id', got %q", query)
+		if query != "correlation_id=test-correlation-id^ORDERBYDESCsys_created_on" {
+			t.Errorf("expected query 'correlation_id=test-correlation-id^ORDERBYDESCsys_created_on', got %q", query)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -123,10 +282,10 @@ func TestClient_FindIncidentByCorrelationID(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	client.retryConfig.MaxAttempts = 1
 
-	result, err := client.FindIncidentByCorrelationID(context.Background(), "test-correlation-id")
+	result, err := client.FindIncidentByCorrelationID(context.Background(), "", "test-correlation-id")
 	if err != nil {
 		t.Errorf("FindIncidentByCorrelationID() error = %v", err)
 	}
@@ -156,10 +315,10 @@ func TestClient_FindIncidentByCorrelationID_NotFound(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	client.retryConfig.MaxAttempts = 1
 
-	result, err := client.FindIncidentByCorrelationID(context.Background(), "nonexistent")
+	result, err := client.FindIncidentByCorrelationID(context.Background(), "", "nonexistent")
 	if err != nil {
 		t.Errorf("FindIncidentByCorrelationID() error = %v", err)
 	}
@@ -203,10 +362,10 @@ func TestClient_ResolveIncident(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	client.retryConfig.MaxAttempts = 1
 
-	err := client.ResolveIncident(context.Background(), "sys123")
+	err := client.ResolveIncident(context.Background(), "", "sys123", "", "")
 	if err != nil {
 		t.Errorf("ResolveIncident() error = %v", err)
 	}
@@ -214,6 +373,531 @@ func TestClient_ResolveIncident(t *testing.T) {
 	if receivedBody.State != "6" {
 		t.Errorf("expected state '6', got %q", receivedBody.State)
 	}
+	if receivedBody.CloseNotes != models.DefaultResolveNotes {
+		t.Errorf("expected default close notes, got %q", receivedBody.CloseNotes)
+	}
+	if receivedBody.CloseCode != models.DefaultCloseCode {
+		t.Errorf("expected default close code, got %q", receivedBody.CloseCode)
+	}
+	if receivedBody.WorkNotes != "" {
+		t.Errorf("expected no work notes, got %q", receivedBody.WorkNotes)
+	}
+
+	if err := client.ResolveIncident(context.Background(), "", "sys123", "custom notes", ""); err != nil {
+		t.Errorf("ResolveIncident() error = %v", err)
+	}
+	if receivedBody.CloseNotes != "custom notes" {
+		t.Errorf("expected close notes 'custom notes', got %q", receivedBody.CloseNotes)
+	}
+
+	if err := client.ResolveIncident(context.Background(), "", "sys123", "", "Summary: disk full"); err != nil {
+		t.Errorf("ResolveIncident() error = %v", err)
+	}
+	if receivedBody.WorkNotes != "Summary: disk full" {
+		t.Errorf("expected work notes 'Summary: disk full', got %q", receivedBody.WorkNotes)
+	}
+}
+
+func TestClient_ResolveIncident_CloseCode(t *testing.T) {
+	var receivedBody models.ServiceNowUpdatePayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+		ServiceNowCloseCode:    "Resolved by Monitoring",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if err := client.ResolveIncident(context.Background(), "", "sys123", "", ""); err != nil {
+		t.Fatalf("ResolveIncident() error = %v", err)
+	}
+	if receivedBody.CloseCode != "Resolved by Monitoring" {
+		t.Errorf("close_code = %q, want %q", receivedBody.CloseCode, "Resolved by Monitoring")
+	}
+}
+
+func TestClient_ResolveIncident_ResolvedState(t *testing.T) {
+	var receivedBody models.ServiceNowUpdatePayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:       server.URL,
+		ServiceNowEndpointPath:  "/api/now/table/incident",
+		ServiceNowUsername:      "testuser",
+		ServiceNowPassword:      "testpass",
+		ServiceNowResolvedState: models.StateClosed,
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if err := client.ResolveIncident(context.Background(), "", "sys123", "", ""); err != nil {
+		t.Fatalf("ResolveIncident() error = %v", err)
+	}
+	if receivedBody.State != models.StateClosed {
+		t.Errorf("state = %q, want %q", receivedBody.State, models.StateClosed)
+	}
+}
+
+func TestClient_Reload(t *testing.T) {
+	var users, passwords []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		users = append(users, user)
+		passwords = append(passwords, pass)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "svc",
+		ServiceNowPassword:     "old",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if err := client.AddWorkNote(context.Background(), "", "sys123", "note"); err != nil {
+		t.Fatalf("AddWorkNote() error = %v", err)
+	}
+	reloaded := *cfg
+	reloaded.ServiceNowUsername = "svc2"
+	reloaded.ServiceNowPassword = "new"
+	client.Reload(&reloaded)
+	if err := client.AddWorkNote(context.Background(), "", "sys123", "note"); err != nil {
+		t.Fatalf("AddWorkNote() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(users, []string{"svc", "svc2"}) || !reflect.DeepEqual(passwords, []string{"old", "new"}) {
+		t.Errorf("credentials sent = %v / %v, want the reloaded ones on the second request", users, passwords)
+	}
+}
+
+func TestClient_AddWorkNote(t *testing.T) {
+	var receivedBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		if r.URL.Path != "/api/now/table/incident/sys123" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&receivedBody); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":{"sys_id":"sys123"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if err := client.AddWorkNote(context.Background(), "", "sys123", "Alert: DiskFilling"); err != nil {
+		t.Errorf("AddWorkNote() error = %v", err)
+	}
+
+	if len(receivedBody) != 1 || receivedBody["work_notes"] != "Alert: DiskFilling" {
+		t.Errorf("expected only work_notes in payload, got %v", receivedBody)
+	}
+}
+
+func TestClient_ReopenIncident(t *testing.T) {
+	var receivedBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		if r.URL.Path != "/api/now/table/incident/sys123" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&receivedBody); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":{"sys_id":"sys123"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if err := client.ReopenIncident(context.Background(), "", "sys123", "fired again"); err != nil {
+		t.Errorf("ReopenIncident() error = %v", err)
+	}
+
+	if receivedBody["state"] != models.StateInProgress || receivedBody["work_notes"] != "fired again" {
+		t.Errorf("unexpected reopen payload %v", receivedBody)
+	}
+}
+
+func TestClient_TablePath(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"result": []models.ServiceNowResult{{SysID: "chg123", Number: "CHG0000001"}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	ctx := context.Background()
+	const table = "/api/now/table/change_request"
+	if _, err := client.CreateIncident(ctx, table, models.ServiceNowIncident{CorrelationID: "abc"}); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if _, err := client.FindIncidentByCorrelationID(ctx, table, "abc"); err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if err := client.ResolveIncident(ctx, table, "chg123", "", ""); err != nil {
+		t.Fatalf("ResolveIncident() error = %v", err)
+	}
+	if err := client.AddWorkNote(ctx, "", "inc123", "note"); err != nil {
+		t.Fatalf("AddWorkNote() error = %v", err)
+	}
+
+	want := []string{
+		"POST /api/now/table/change_request",
+		"GET /api/now/table/change_request",
+		"PATCH /api/now/table/change_request/chg123",
+		"PATCH /api/now/table/incident/inc123",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+}
+
+func TestClient_FindIncidentByCorrelationID_Newest(t *testing.T) {
+	// ServiceNow returns records in no particular order unless asked; the
+	// server only puts the newer, open incident first when the query does.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		records := []models.ServiceNowResult{
+			{SysID: "sys-old", Number: "INC0001000", CorrelationID: "abc123", State: models.StateResolved},
+			{SysID: "sys-new", Number: "INC0002000", CorrelationID: "abc123", State: "2"},
+		}
+		if strings.HasSuffix(r.URL.Query().Get("sysparm_query"), "^ORDERBYDESCsys_created_on") {
+			records[0], records[1] = records[1], records[0]
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("sysparm_limit"))
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{Result: records[:min(limit, len(records))]})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	result, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc123")
+	if err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if result == nil || result.SysID != "sys-new" {
+		t.Errorf("FindIncidentByCorrelationID() = %+v, want the newer incident sys-new", result)
+	}
+
+	found, err := client.FindIncidentsByCorrelationIDs(context.Background(), "", []string{"abc123"})
+	if err != nil {
+		t.Fatalf("FindIncidentsByCorrelationIDs() error = %v", err)
+	}
+	if found["abc123"] == nil || found["abc123"].SysID != "sys-new" {
+		t.Errorf("FindIncidentsByCorrelationIDs() = %+v, want the newer incident sys-new", found["abc123"])
+	}
+}
+
+func TestClient_FindIncidentByCorrelationID_PrefersOpen(t *testing.T) {
+	// The newest incident was resolved by hand while an older one with the
+	// same correlation ID is still open.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{Result: []models.ServiceNowResult{
+			{SysID: "sys-new", Number: "INC0002000", CorrelationID: "abc123", State: models.StateResolved},
+			{SysID: "sys-old", Number: "INC0001000", CorrelationID: "abc123", State: "2"},
+		}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	result, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc123")
+	if err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if result == nil || result.SysID != "sys-old" {
+		t.Errorf("FindIncidentByCorrelationID() = %+v, want the open incident sys-old", result)
+	}
+
+	found, err := client.FindIncidentsByCorrelationIDs(context.Background(), "", []string{"abc123"})
+	if err != nil {
+		t.Fatalf("FindIncidentsByCorrelationIDs() error = %v", err)
+	}
+	if found["abc123"] == nil || found["abc123"].SysID != "sys-old" {
+		t.Errorf("FindIncidentsByCorrelationIDs() = %+v, want the open incident sys-old", found["abc123"])
+	}
+}
+
+func TestClient_FindIncidentsByCorrelationIDs(t *testing.T) {
+	pages := map[string][]models.ServiceNowResult{
+		"correlation_idINa,b^ORDERBYDESCsys_created_on/0": {
+			{SysID: "sys-a1", CorrelationID: "a"},
+			{SysID: "sys-a2", CorrelationID: "a"},
+		},
+		"correlation_idINa,b^ORDERBYDESCsys_created_on/2": {{SysID: "sys-b", CorrelationID: "b"}},
+		"correlation_idINc^ORDERBYDESCsys_created_on/0":   {},
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("sysparm_limit") != "2" {
+			t.Errorf("sysparm_limit = %q, want 2", q.Get("sysparm_limit"))
+		}
+		offset := q.Get("sysparm_offset")
+		if offset == "" {
+			offset = "0"
+		}
+		key := q.Get("sysparm_query") + "/" + offset
+		requests = append(requests, r.URL.Path+" "+key)
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{Result: pages[key]})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:         server.URL,
+		ServiceNowEndpointPath:    "/api/now/table/incident",
+		ServiceNowUsername:        "testuser",
+		ServiceNowPassword:        "testpass",
+		ServiceNowLookupBatchSize: 2,
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	found, err := client.FindIncidentsByCorrelationIDs(context.Background(), "/api/now/table/problem", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("FindIncidentsByCorrelationIDs() error = %v", err)
+	}
+
+	wantRequests := []string{
+		"/api/now/table/problem correlation_idINa,b^ORDERBYDESCsys_created_on/0",
+		"/api/now/table/problem correlation_idINa,b^ORDERBYDESCsys_created_on/2",
+		"/api/now/table/problem correlation_idINc^ORDERBYDESCsys_created_on/0",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %q, want %q", requests, wantRequests)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d incidents, want 2: %v", len(found), found)
+	}
+	if found["a"].SysID != "sys-a1" {
+		t.Errorf("incident for a = %q, want the newest match sys-a1", found["a"].SysID)
+	}
+	if found["b"].SysID != "sys-b" {
+		t.Errorf("incident for b = %q, want sys-b", found["b"].SysID)
+	}
+}
+
+func TestClient_FindIncidentByCorrelationIDOrField(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		value     string
+		wantQuery string
+	}{
+		{
+			name:      "correlation ID or fingerprint",
+			field:     "u_alert_fingerprint",
+			value:     "4f9a7c2e1b3d5a60",
+			wantQuery: "correlation_id=abc123^ORu_alert_fingerprint=4f9a7c2e1b3d5a60^ORDERBYDESCsys_created_on",
+		},
+		{name: "no fingerprint", field: "u_alert_fingerprint", wantQuery: "correlation_id=abc123^ORDERBYDESCsys_created_on"},
+		{name: "no field", value: "4f9a7c2e1b3d5a60", wantQuery: "correlation_id=abc123^ORDERBYDESCsys_created_on"},
+		{
+			name:      "value with query operators",
+			field:     "u_alert_fingerprint",
+			value:     "x^NQactive=true",
+			wantQuery: "correlation_id=abc123^ORDERBYDESCsys_created_on",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("sysparm_query")
+				json.NewEncoder(w).Encode(models.ServiceNowListResponse{
+					Result: []models.ServiceNowResult{{SysID: "sys123", Number: "INC0012345"}},
+				})
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				ServiceNowBaseURL:      server.URL,
+				ServiceNowEndpointPath: "/api/now/table/incident",
+				ServiceNowUsername:     "testuser",
+				ServiceNowPassword:     "testpass",
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			result, err := client.FindIncidentByCorrelationIDOrField(context.Background(), "", "abc123", tt.field, tt.value)
+			if err != nil {
+				t.Fatalf("FindIncidentByCorrelationIDOrField() error = %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("sysparm_query = %q, want %q", query, tt.wantQuery)
+			}
+			if result == nil || result.SysID != "sys123" {
+				t.Errorf("result = %+v, want sys123", result)
+			}
+		})
+	}
+}
+
+func TestClient_FindOpenIncidentsByShortDescriptionPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wantQuery := "short_descriptionSTARTSWITH[prod] KubeAPIDown^stateNOT IN6,7^sys_created_by=testuser^ORDERBYDESCsys_created_on"
+		if got := r.URL.Query().Get("sysparm_query"); got != wantQuery {
+			t.Errorf("expected query %q, got %q", wantQuery, got)
+		}
+		if got := r.URL.Query().Get("sysparm_limit"); got != "10" {
+			t.Errorf("expected limit 10, got %q", got)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{
+			Result: []models.ServiceNowResult{
+				{SysID: "sys123", ShortDescription: "[prod] KubeAPIDown"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	results, err := client.FindOpenIncidentsByShortDescriptionPrefix(context.Background(), "[prod] KubeAPIDown", 10)
+	if err != nil {
+		t.Fatalf("FindOpenIncidentsByShortDescriptionPrefix() error = %v", err)
+	}
+	if len(results) != 1 || results[0].SysID != "sys123" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestClient_FindResolvedIncidentsBefore(t *testing.T) {
+	cutoff := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		resolvedState string
+		wantQuery     string
+	}{
+		{
+			name:      "default resolved state",
+			wantQuery: "state=6^sys_created_by=testuser^correlation_idISNOTEMPTY^resolved_at<2024-01-15 10:00:00",
+		},
+		{
+			name:          "configured resolved state",
+			resolvedState: "8",
+			wantQuery:     "state=8^sys_created_by=testuser^correlation_idISNOTEMPTY^resolved_at<2024-01-15 10:00:00",
+		},
+		{
+			name:          "resolves close incidents",
+			resolvedState: models.StateClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries = append(queries, r.URL.Query().Get("sysparm_query"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"result":[]}`))
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				ServiceNowBaseURL:       server.URL,
+				ServiceNowEndpointPath:  "/api/now/table/incident",
+				ServiceNowUsername:      "testuser",
+				ServiceNowPassword:      "testpass",
+				ServiceNowResolvedState: tt.resolvedState,
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			if _, err := client.FindResolvedIncidentsBefore(context.Background(), cutoff, 100); err != nil {
+				t.Fatalf("FindResolvedIncidentsBefore() error = %v", err)
+			}
+			if tt.wantQuery == "" {
+				if len(queries) != 0 {
+					t.Errorf("expected no query, got %q", queries)
+				}
+				return
+			}
+			if len(queries) != 1 || queries[0] != tt.wantQuery {
+				t.Errorf("queries = %q, want [%q]", queries, tt.wantQuery)
+			}
+		})
+	}
 }
 
 func TestClient_CreateIncident_ServerError(t *testing.T) {
@@ -232,17 +916,18 @@ func TestClient_CreateIncident_ServerError(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	// Set max attempts to 2 for faster test
 	client.retryConfig.MaxAttempts = 2
 	client.retryConfig.BaseDelay = 1_000_000 // 1ms
+	client.retryConfig.Jitter = false
 
 	incident := models.ServiceNowIncident{
 		ShortDescription: "Test",
 		CorrelationID:    "test123",
 	}
 
-	_, err := client.CreateIncident(context.Background(), incident)
+	_, err := client.CreateIncident(context.Background(), "", incident)
 	if err == nil {
 		t.Error("expected error for server error response")
 	}
@@ -268,7 +953,7 @@ func TestClient_CreateIncident_ClientError_NoRetry(t *testing.T) {
 		ServiceNowPassword:     "testpass",
 	}
 
-	client := NewClient(cfg, newTestLogger())
+	client := NewClient(cfg, metrics.New(), newTestLogger())
 	client.retryConfig.MaxAttempts = 3
 
 	incident := models.ServiceNowIncident{
@@ -276,7 +961,7 @@ func TestClient_CreateIncident_ClientError_NoRetry(t *testing.T) {
 		CorrelationID:    "test123",
 	}
 
-	_, err := client.CreateIncident(context.Background(), incident)
+	_, err := client.CreateIncident(context.Background(), "", incident)
 	if err == nil {
 		t.Error("expected error for client error response")
 	}
@@ -286,3 +971,52 @@ func TestClient_CreateIncident_ClientError_NoRetry(t *testing.T) {
 		t.Errorf("expected 1 attempt (no retry on 4xx), got %d", attempts)
 	}
 }
+
+func TestNewClient_RetryAndTimeoutConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		wantTimeout time.Duration
+		wantRetry   RetryConfig
+	}{
+		{
+			name:        "defaults when unset",
+			cfg:         &config.Config{},
+			wantTimeout: 30 * time.Second,
+			wantRetry:   DefaultRetryConfig(),
+		},
+		{
+			name: "configured values",
+			cfg: &config.Config{
+				ServiceNowHTTPTimeout:      5 * time.Second,
+				ServiceNowRetryMaxAttempts: 5,
+				ServiceNowRetryBaseDelay:   200 * time.Millisecond,
+				ServiceNowRetryMaxDelay:    2 * time.Second,
+			},
+			wantTimeout: 5 * time.Second,
+			wantRetry: RetryConfig{
+				MaxAttempts: 5,
+				BaseDelay:   200 * time.Millisecond,
+				MaxDelay:    2 * time.Second,
+				Jitter:      true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(tt.cfg, metrics.New(), newTestLogger())
+			if client.httpClient.Timeout != tt.wantTimeout {
+				t.Errorf("http timeout = %v, want %v", client.httpClient.Timeout, tt.wantTimeout)
+			}
+			got := client.retryConfig
+			if got.Rand == nil {
+				t.Error("expected a jitter source")
+			}
+			if got.MaxAttempts != tt.wantRetry.MaxAttempts || got.BaseDelay != tt.wantRetry.BaseDelay ||
+				got.MaxDelay != tt.wantRetry.MaxDelay || got.Jitter != tt.wantRetry.Jitter {
+				t.Errorf("retry config = %+v, want %+v", got, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package servicenow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// DryRunClient wraps a client so that every write is logged instead of sent.
+// Lookups still go through the wrapped client, failover included, so
+// resolves and de-duplication behave as they would for real, and names are
+// resolved to sys_ids in logged incidents as for a real create; creates,
+// resolves, work notes, and closes return a synthetic success without making
+// an HTTP request. Writes are counted in the request metrics with status
+// "dry_run".
+type DryRunClient struct {
+	lookups Lookups
+	// client is the instance writes would go to; its settings shape the
+	// logged payloads.
+	client *Client
+	logger *slog.Logger
+}
+
+// Lookups is the read side of a ServiceNow client, which DryRunClient
+// passes through. Client and FailoverClient implement it.
+type Lookups interface {
+	FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error)
+	FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error)
+	FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error)
+	FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error)
+	FindResolvedIncidentsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.ServiceNowResult, error)
+
+	// writeClient returns the client writes are sent to first.
+	writeClient() *Client
+}
+
+// NewDryRunClient creates a dry-run wrapper around lookups.
+func NewDryRunClient(lookups Lookups, logger *slog.Logger) *DryRunClient {
+	return &DryRunClient{
+		lookups: lookups,
+		client:  lookups.writeClient(),
+		logger:  logger,
+	}
+}
+
+// CreateIncident logs the incident payload, with assignment group and caller
+// names looked up as for a real create, and returns a synthetic result
+// derived from its correlation ID.
+func (d *DryRunClient) CreateIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
+	d.client.resolveReferences(ctx, &incident)
+	d.client.observeDryRun(opCreate)
+	d.logger.Info("dry run: would create incident",
+		"path", d.client.tablePath(path),
+		"incident", incident,
+	)
+
+	return &CreateIncidentResult{
+		SysID:  "dry-run-" + incident.CorrelationID,
+		Number: "DRYRUN",
+	}, nil
+}
+
+// ResolveIncident logs the resolve payload without sending it.
+func (d *DryRunClient) ResolveIncident(ctx context.Context, path, sysID, closeNotes, workNotes string) error {
+	if closeNotes == "" {
+		closeNotes = models.DefaultResolveNotes
+	}
+
+	d.client.observeDryRun(opResolve)
+	d.logger.Info("dry run: would resolve incident",
+		"path", d.client.tablePath(path),
+		"sys_id", sysID,
+		"payload", models.ServiceNowUpdatePayload{
+			State:      d.client.resolvedState,
+			CloseCode:  d.client.closeCode,
+			CloseNotes: closeNotes,
+			RootCause:  d.client.rootCause,
+			WorkNotes:  workNotes,
+		},
+	)
+	return nil
+}
+
+// ReopenIncident logs the reopen without sending it.
+func (d *DryRunClient) ReopenIncident(ctx context.Context, path, sysID, note string) error {
+	d.client.observeDryRun(opReopen)
+	d.logger.Info("dry run: would reopen incident",
+		"path", d.client.tablePath(path),
+		"sys_id", sysID,
+		"payload", models.ServiceNowReopenPayload{
+			State:     models.StateInProgress,
+			WorkNotes: note,
+		},
+	)
+	return nil
+}
+
+// AddWorkNote logs the work note without sending it.
+func (d *DryRunClient) AddWorkNote(ctx context.Context, path, sysID, note string) error {
+	d.client.observeDryRun(opWorkNote)
+	d.logger.Info("dry run: would add work note",
+		"path", d.client.tablePath(path),
+		"sys_id", sysID,
+		"work_notes", note,
+	)
+	return nil
+}
+
+// CloseIncident logs the close without sending it.
+func (d *DryRunClient) CloseIncident(ctx context.Context, sysID string) error {
+	d.client.observeDryRun(opClose)
+	d.logger.Info("dry run: would close incident",
+		"sys_id", sysID,
+	)
+	return nil
+}
+
+// FindIncidentByCorrelationID queries ServiceNow through the wrapped client.
+func (d *DryRunClient) FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error) {
+	return d.lookups.FindIncidentByCorrelationID(ctx, path, correlationID)
+}
+
+// FindIncidentByCorrelationIDOrField queries ServiceNow through the wrapped client.
+func (d *DryRunClient) FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error) {
+	return d.lookups.FindIncidentByCorrelationIDOrField(ctx, path, correlationID, field, value)
+}
+
+// FindIncidentsByCorrelationIDs queries ServiceNow through the wrapped client.
+func (d *DryRunClient) FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error) {
+	return d.lookups.FindIncidentsByCorrelationIDs(ctx, path, ids)
+}
+
+// FindOpenIncidentsByShortDescriptionPrefix queries ServiceNow through the wrapped client.
+func (d *DryRunClient) FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error) {
+	return d.lookups.FindOpenIncidentsByShortDescriptionPrefix(ctx, prefix, limit)
+}
+
+// FindResolvedIncidentsBefore queries ServiceNow through the wrapped client.
+func (d *DryRunClient) FindResolvedIncidentsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.ServiceNowResult, error) {
+	return d.lookups.FindResolvedIncidentsBefore(ctx, cutoff, limit)
+}
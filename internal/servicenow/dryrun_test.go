@@ -0,0 +1,143 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestDryRunClient_NoHTTPWrites(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	m := metrics.New()
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}, m, newTestLogger())
+
+	var buf bytes.Buffer
+	dryRun := NewDryRunClient(client, slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx := context.Background()
+
+	result, err := dryRun.CreateIncident(ctx, "", models.ServiceNowIncident{
+		ShortDescription: "[test-cluster] TestAlert",
+		CorrelationID:    "abc123def456",
+	})
+	if err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if result.SysID != "dry-run-abc123def456" {
+		t.Errorf("unexpected synthetic sys_id %q", result.SysID)
+	}
+
+	if err := dryRun.ResolveIncident(ctx, "", result.SysID, "", ""); err != nil {
+		t.Errorf("ResolveIncident() error = %v", err)
+	}
+	if err := dryRun.AddWorkNote(ctx, "", result.SysID, "note"); err != nil {
+		t.Errorf("AddWorkNote() error = %v", err)
+	}
+	if err := dryRun.CloseIncident(ctx, result.SysID); err != nil {
+		t.Errorf("CloseIncident() error = %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("expected no HTTP requests in dry-run mode, got %d", requests)
+	}
+	for _, op := range []string{opCreate, opResolve, opWorkNote, opClose} {
+		var metric dto.Metric
+		if err := m.ServiceNowRequests.WithLabelValues(op, "dry_run").Write(&metric); err != nil {
+			t.Fatalf("failed to read counter: %v", err)
+		}
+		if got := metric.GetCounter().GetValue(); got != 1 {
+			t.Errorf("requests{operation=%q,status=\"dry_run\"} = %v, want 1", op, got)
+		}
+	}
+
+	output := buf.String()
+	for _, want := range []string{"dry run: would create incident", "[test-cluster] TestAlert", "dry run: would resolve incident", models.DefaultResolveNotes} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected log output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestDryRunClient_FailoverLookups(t *testing.T) {
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primaryURL := primary.URL
+	primary.Close()
+
+	writes := 0
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+		}
+		w.Write([]byte(`{"result":[{"sys_id":"secondary","number":"INC_SECONDARY","state":"1"}]}`))
+	}))
+	defer secondary.Close()
+
+	failover := NewFailoverClient(newFailoverTestClient(primaryURL), newFailoverTestClient(secondary.URL), newTestLogger())
+	dryRun := NewDryRunClient(failover, newTestLogger())
+
+	result, err := dryRun.FindIncidentByCorrelationID(context.Background(), "", "abc")
+	if err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if result == nil || result.Number != "INC_SECONDARY" {
+		t.Errorf("expected lookup to fail over to the secondary, got %+v", result)
+	}
+	if err := dryRun.ResolveIncident(context.Background(), "", result.SysID, "", ""); err != nil {
+		t.Errorf("ResolveIncident() error = %v", err)
+	}
+	if writes != 0 {
+		t.Errorf("expected no writes in dry-run mode, got %d", writes)
+	}
+}
+
+func TestDryRunClient_ResolvesAssignmentGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/now/table/sys_user_group" {
+			t.Errorf("unexpected %s request to %s in dry-run mode", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":[{"sys_id":"grp123"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:         server.URL,
+		ServiceNowEndpointPath:    "/api/now/table/incident",
+		ServiceNowUsername:        "testuser",
+		ServiceNowPassword:        "testpass",
+		ServiceNowAssignmentGroup: "Platform Ops",
+		AssignmentGroupByName:     true,
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	var buf bytes.Buffer
+	dryRun := NewDryRunClient(client, slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	incident := models.ServiceNowIncident{CorrelationID: "abc123def456", AssignmentGroup: "Platform Ops"}
+	if _, err := dryRun.CreateIncident(context.Background(), "", incident); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"assignment_group":"grp123"`) {
+		t.Errorf("expected the logged payload to carry the resolved group sys_id, got:\n%s", buf.String())
+	}
+}
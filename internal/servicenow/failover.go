@@ -0,0 +1,239 @@
+package servicenow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// failoverCooldown is how long operations skip the primary after it was
+// found unavailable, before it is tried again.
+const failoverCooldown = 30 * time.Second
+
+// FailoverClient sends operations to a primary instance and repeats them
+// against a secondary instance when the primary is unreachable: a connection
+// error, 5xx, or 429 once the primary's retries are exhausted, or no answer
+// within half the time left before the request's deadline. Client errors
+// (other 4xx) come from the primary and are returned as is. After a
+// failover, operations go straight to the secondary for failoverCooldown.
+//
+// The two instances do not share records, so operations on an existing
+// incident (resolve, reopen, work notes, close) go to the instance it was
+// found or created on and are not failed over. An incident created on the
+// secondary is not found through the primary once it recovers.
+type FailoverClient struct {
+	primary   *Client
+	secondary *Client
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu sync.Mutex
+	// primaryDownUntil is when the primary is next tried after a failover.
+	primaryDownUntil time.Time
+	// onSecondary holds the sys_ids of incidents seen on the secondary.
+	onSecondary map[string]struct{}
+}
+
+// NewFailoverClient creates a client that fails over from primary to secondary.
+func NewFailoverClient(primary, secondary *Client, logger *slog.Logger) *FailoverClient {
+	return &FailoverClient{
+		primary:     primary,
+		secondary:   secondary,
+		logger:      logger,
+		now:         time.Now,
+		onSecondary: make(map[string]struct{}),
+	}
+}
+
+// writeClient returns the primary, which writes are sent to first.
+func (f *FailoverClient) writeClient() *Client {
+	return f.primary
+}
+
+// shouldFailover reports whether err from the primary means it is
+// unreachable or too slow, rather than that the request was rejected or
+// cancelled. ctx is the caller's context, not the primary's budget.
+func (f *FailoverClient) shouldFailover(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	// The caller's context is still live, so this is the primary's budget
+	// running out.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return IsRetryable(err)
+}
+
+// primaryContext bounds a primary call to half the time left before ctx's
+// deadline, so the secondary still has time to answer when the primary
+// doesn't. Without a deadline the primary is only bounded by its retries.
+func (f *FailoverClient) primaryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, deadline.Sub(f.now())/2)
+}
+
+// primaryDown reports whether the primary failed recently enough to skip.
+func (f *FailoverClient) primaryDown() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now().Before(f.primaryDownUntil)
+}
+
+// failover logs that op is being repeated against the secondary instance and
+// skips the primary for failoverCooldown.
+func (f *FailoverClient) failover(op string, err error) {
+	f.mu.Lock()
+	f.primaryDownUntil = f.now().Add(failoverCooldown)
+	f.mu.Unlock()
+
+	f.logger.Warn("primary ServiceNow instance unavailable, failing over to secondary",
+		"operation", op,
+		"primary", f.primary.baseURL,
+		"secondary", f.secondary.baseURL,
+		"cooldown", failoverCooldown.String(),
+		"error", err,
+	)
+}
+
+// call runs op against the primary, or the secondary while the primary is
+// down or when it turns out to be, and returns the client that answered.
+func call[T any](ctx context.Context, f *FailoverClient, op string, fn func(context.Context, *Client) (T, error)) (T, *Client, error) {
+	if f.primaryDown() {
+		result, err := fn(ctx, f.secondary)
+		return result, f.secondary, err
+	}
+
+	primaryCtx, cancel := f.primaryContext(ctx)
+	result, err := fn(primaryCtx, f.primary)
+	cancel()
+	if !f.shouldFailover(ctx, err) {
+		return result, f.primary, err
+	}
+	f.failover(op, err)
+	result, err = fn(ctx, f.secondary)
+	return result, f.secondary, err
+}
+
+// remember records incidents the secondary returned, so later operations on
+// them go there.
+func (f *FailoverClient) remember(from *Client, incidents ...*models.ServiceNowResult) {
+	if from != f.secondary {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, incident := range incidents {
+		if incident != nil && incident.SysID != "" {
+			f.onSecondary[incident.SysID] = struct{}{}
+		}
+	}
+}
+
+// clientFor returns the instance the incident sysID lives on.
+func (f *FailoverClient) clientFor(sysID string) *Client {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.onSecondary[sysID]; ok {
+		return f.secondary
+	}
+	return f.primary
+}
+
+// CreateIncident creates the incident on the primary, or the secondary if the
+// primary is unavailable.
+func (f *FailoverClient) CreateIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*CreateIncidentResult, error) {
+	result, from, err := call(ctx, f, "create_incident", func(ctx context.Context, c *Client) (*CreateIncidentResult, error) {
+		return c.CreateIncident(ctx, path, incident)
+	})
+	if result != nil {
+		f.remember(from, &models.ServiceNowResult{SysID: result.SysID})
+	}
+	return result, err
+}
+
+// FindIncidentByCorrelationID searches the primary, or the secondary if the
+// primary is unavailable.
+func (f *FailoverClient) FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error) {
+	result, from, err := call(ctx, f, "find_incident", func(ctx context.Context, c *Client) (*models.ServiceNowResult, error) {
+		return c.FindIncidentByCorrelationID(ctx, path, correlationID)
+	})
+	f.remember(from, result)
+	return result, err
+}
+
+// FindIncidentByCorrelationIDOrField searches the primary, or the secondary
+// if the primary is unavailable.
+func (f *FailoverClient) FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error) {
+	result, from, err := call(ctx, f, "find_incident", func(ctx context.Context, c *Client) (*models.ServiceNowResult, error) {
+		return c.FindIncidentByCorrelationIDOrField(ctx, path, correlationID, field, value)
+	})
+	f.remember(from, result)
+	return result, err
+}
+
+// FindIncidentsByCorrelationIDs searches the primary, or the secondary if
+// the primary is unavailable.
+func (f *FailoverClient) FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error) {
+	found, from, err := call(ctx, f, "find_incidents", func(ctx context.Context, c *Client) (map[string]*models.ServiceNowResult, error) {
+		return c.FindIncidentsByCorrelationIDs(ctx, path, ids)
+	})
+	for _, incident := range found {
+		f.remember(from, incident)
+	}
+	return found, err
+}
+
+// FindOpenIncidentsByShortDescriptionPrefix searches the primary, or the
+// secondary if the primary is unavailable.
+func (f *FailoverClient) FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error) {
+	results, from, err := call(ctx, f, "find_open_incidents", func(ctx context.Context, c *Client) ([]models.ServiceNowResult, error) {
+		return c.FindOpenIncidentsByShortDescriptionPrefix(ctx, prefix, limit)
+	})
+	for i := range results {
+		f.remember(from, &results[i])
+	}
+	return results, err
+}
+
+// FindResolvedIncidentsBefore searches the primary, or the secondary if the
+// primary is unavailable.
+func (f *FailoverClient) FindResolvedIncidentsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.ServiceNowResult, error) {
+	results, from, err := call(ctx, f, "find_resolved_incidents", func(ctx context.Context, c *Client) ([]models.ServiceNowResult, error) {
+		return c.FindResolvedIncidentsBefore(ctx, cutoff, limit)
+	})
+	for i := range results {
+		f.remember(from, &results[i])
+	}
+	return results, err
+}
+
+// ResolveIncident resolves the incident on the instance it lives on.
+func (f *FailoverClient) ResolveIncident(ctx context.Context, path, sysID, closeNotes, workNotes string) error {
+	return f.clientFor(sysID).ResolveIncident(ctx, path, sysID, closeNotes, workNotes)
+}
+
+// ReopenIncident reopens the incident on the instance it lives on.
+func (f *FailoverClient) ReopenIncident(ctx context.Context, path, sysID, note string) error {
+	return f.clientFor(sysID).ReopenIncident(ctx, path, sysID, note)
+}
+
+// AddWorkNote adds the work note on the instance the incident lives on.
+func (f *FailoverClient) AddWorkNote(ctx context.Context, path, sysID, note string) error {
+	return f.clientFor(sysID).AddWorkNote(ctx, path, sysID, note)
+}
+
+// CloseIncident closes the incident on the instance it lives on.
+func (f *FailoverClient) CloseIncident(ctx context.Context, sysID string) error {
+	return f.clientFor(sysID).CloseIncident(ctx, sysID)
+}
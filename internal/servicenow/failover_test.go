@@ -0,0 +1,209 @@
+package servicenow
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func newFailoverTestClient(url string) *Client {
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      url,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 2
+	client.retryConfig.BaseDelay = 0
+	return client
+}
+
+func TestFailoverClient_CreateIncident(t *testing.T) {
+	tests := []struct {
+		name              string
+		primaryStatus     int
+		wantNumber        string
+		wantErr           bool
+		wantPrimaryCalls  int
+		wantSecondaryCall bool
+	}{
+		{
+			name:             "primary succeeds",
+			primaryStatus:    http.StatusCreated,
+			wantNumber:       "INC_PRIMARY",
+			wantPrimaryCalls: 1,
+		},
+		{
+			name:              "primary server error fails over after retries",
+			primaryStatus:     http.StatusServiceUnavailable,
+			wantNumber:        "INC_SECONDARY",
+			wantPrimaryCalls:  2,
+			wantSecondaryCall: true,
+		},
+		{
+			name:             "primary client error does not fail over",
+			primaryStatus:    http.StatusBadRequest,
+			wantErr:          true,
+			wantPrimaryCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryCalls := 0
+			primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				primaryCalls++
+				w.WriteHeader(tt.primaryStatus)
+				w.Write([]byte(`{"result":{"sys_id":"primary","number":"INC_PRIMARY"}}`))
+			}))
+			defer primary.Close()
+
+			secondaryCalled := false
+			secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				secondaryCalled = true
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"result":{"sys_id":"secondary","number":"INC_SECONDARY"}}`))
+			}))
+			defer secondary.Close()
+
+			client := NewFailoverClient(newFailoverTestClient(primary.URL), newFailoverTestClient(secondary.URL), newTestLogger())
+			result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "abc"})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateIncident() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.Number != tt.wantNumber {
+				t.Errorf("expected incident %s, got %s", tt.wantNumber, result.Number)
+			}
+			if primaryCalls != tt.wantPrimaryCalls {
+				t.Errorf("expected %d primary calls, got %d", tt.wantPrimaryCalls, primaryCalls)
+			}
+			if secondaryCalled != tt.wantSecondaryCall {
+				t.Errorf("secondary called = %v, want %v", secondaryCalled, tt.wantSecondaryCall)
+			}
+		})
+	}
+}
+
+func TestFailoverClient_PrimaryUnreachable(t *testing.T) {
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primaryURL := primary.URL
+	primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":[{"sys_id":"secondary","number":"INC_SECONDARY","state":"1"}]}`))
+	}))
+	defer secondary.Close()
+
+	client := NewFailoverClient(newFailoverTestClient(primaryURL), newFailoverTestClient(secondary.URL), newTestLogger())
+
+	result, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc")
+	if err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if result == nil || result.Number != "INC_SECONDARY" {
+		t.Errorf("expected incident from secondary, got %+v", result)
+	}
+	if err := client.ResolveIncident(context.Background(), "", "secondary", "", ""); err != nil {
+		t.Errorf("ResolveIncident() error = %v", err)
+	}
+}
+
+func TestFailoverClient_SysIDOperationsStayOnOrigin(t *testing.T) {
+	primaryCalls := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	secondaryCalls := 0
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryCalls++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":{}}`))
+	}))
+	defer secondary.Close()
+
+	client := NewFailoverClient(newFailoverTestClient(primary.URL), newFailoverTestClient(secondary.URL), newTestLogger())
+
+	if err := client.ResolveIncident(context.Background(), "", "primary", "", ""); err == nil {
+		t.Error("expected ResolveIncident() to return the primary's error")
+	}
+	if primaryCalls != 2 {
+		t.Errorf("expected 2 primary calls, got %d", primaryCalls)
+	}
+	if secondaryCalls != 0 {
+		t.Errorf("expected an incident from the primary not to be resolved on the secondary, got %d calls", secondaryCalls)
+	}
+}
+
+func TestFailoverClient_SkipsPrimaryAfterFailover(t *testing.T) {
+	primaryCalls := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result":{"sys_id":"secondary","number":"INC_SECONDARY"}}`))
+	}))
+	defer secondary.Close()
+
+	client := NewFailoverClient(newFailoverTestClient(primary.URL), newFailoverTestClient(secondary.URL), newTestLogger())
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "abc"}); err != nil {
+			t.Fatalf("CreateIncident() error = %v", err)
+		}
+	}
+	if primaryCalls != 2 {
+		t.Errorf("expected the second create to skip the primary, got %d primary calls", primaryCalls)
+	}
+
+	now = now.Add(failoverCooldown)
+	if _, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "abc"}); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if primaryCalls != 4 {
+		t.Errorf("expected the primary to be retried after the cooldown, got %d primary calls", primaryCalls)
+	}
+}
+
+func TestFailoverClient_HangingPrimary(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result":{"sys_id":"secondary","number":"INC_SECONDARY"}}`))
+	}))
+	defer secondary.Close()
+
+	client := NewFailoverClient(newFailoverTestClient(primary.URL), newFailoverTestClient(secondary.URL), newTestLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result, err := client.CreateIncident(ctx, "", models.ServiceNowIncident{CorrelationID: "abc"})
+	if err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if result.Number != "INC_SECONDARY" {
+		t.Errorf("expected incident from secondary, got %s", result.Number)
+	}
+}
@@ -0,0 +1,63 @@
+package servicenow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// stagingPayload converts an incident into an import set row. Staging table
+// columns are custom fields, so every incident field is renamed with the
+// configured prefix (e.g. short_description becomes u_short_description) for
+// the transform map to pick up.
+func stagingPayload(incident models.ServiceNowIncident, prefix string) (map[string]string, error) {
+	raw, err := json.Marshal(incident)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		row[prefix+k] = v
+	}
+	return row, nil
+}
+
+// parseImportResponse extracts the created record from an Import Set API
+// response. Rows the transform map rejected or skipped are reported as errors.
+func parseImportResponse(body []byte) (*CreateIncidentResult, error) {
+	var importResp models.ServiceNowImportResponse
+	if err := json.Unmarshal(body, &importResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal import set response: %w", err)
+	}
+
+	if len(importResp.Result) == 0 {
+		return nil, fmt.Errorf("import set %s returned no transform results", importResp.ImportSet)
+	}
+
+	row := importResp.Result[0]
+	switch row.Status {
+	case "inserted", "updated":
+	default:
+		msg := row.ErrorMessage
+		if msg == "" {
+			msg = row.StatusMessage
+		}
+		return nil, fmt.Errorf("import set %s row was not transformed (status %q): %s", importResp.ImportSet, row.Status, msg)
+	}
+
+	result := &CreateIncidentResult{SysID: row.SysID}
+	if row.DisplayName == "number" {
+		result.Number = row.DisplayValue
+	}
+	return result, nil
+}
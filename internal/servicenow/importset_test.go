@@ -0,0 +1,171 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestStagingPayload(t *testing.T) {
+	incident := models.ServiceNowIncident{
+		ShortDescription: "[test-cluster] TestAlert",
+		Description:      "Test description",
+		Impact:           "3",
+		Urgency:          "3",
+		Category:         "software",
+		Subcategory:      "openshift",
+		CorrelationID:    "abc123",
+	}
+
+	row, err := stagingPayload(incident, "u_")
+	if err != nil {
+		t.Fatalf("stagingPayload() error = %v", err)
+	}
+
+	want := map[string]string{
+		"u_short_description": "[test-cluster] TestAlert",
+		"u_description":       "Test description",
+		"u_impact":            "3",
+		"u_urgency":           "3",
+		"u_category":          "software",
+		"u_subcategory":       "openshift",
+		"u_correlation_id":    "abc123",
+	}
+	if len(row) != len(want) {
+		t.Errorf("stagingPayload() returned %d fields, want %d: %v", len(row), len(want), row)
+	}
+	for k, v := range want {
+		if row[k] != v {
+			t.Errorf("row[%q] = %q, want %q", k, row[k], v)
+		}
+	}
+}
+
+func TestParseImportResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErr    bool
+		wantSysID  string
+		wantNumber string
+	}{
+		{
+			name: "inserted row",
+			body: `{"import_set":"ISET0010001","staging_table":"u_alert_staging","result":[
+				{"transform_map":"Alert to Incident","table":"incident","display_name":"number",
+				 "display_value":"INC0010001","status":"inserted","sys_id":"sys123"}]}`,
+			wantSysID:  "sys123",
+			wantNumber: "INC0010001",
+		},
+		{
+			name: "display field is not number",
+			body: `{"import_set":"ISET0010002","result":[
+				{"table":"incident","display_name":"short_description",
+				 "display_value":"[c] Alert","status":"inserted","sys_id":"sys456"}]}`,
+			wantSysID:  "sys456",
+			wantNumber: "",
+		},
+		{
+			name: "transform error",
+			body: `{"import_set":"ISET0010003","result":[
+				{"table":"incident","status":"error","error_message":"No transform map"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty result",
+			body:    `{"import_set":"ISET0010004","result":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseImportResponse([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImportResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.SysID != tt.wantSysID {
+				t.Errorf("SysID = %q, want %q", result.SysID, tt.wantSysID)
+			}
+			if result.Number != tt.wantNumber {
+				t.Errorf("Number = %q, want %q", result.Number, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func TestClient_CreateIncident_ImportMode(t *testing.T) {
+	var receivedPath string
+	var receivedBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&receivedBody); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.ServiceNowImportResponse{
+			ImportSet:    "ISET0010001",
+			StagingTable: "u_alert_staging",
+			Result: []models.ServiceNowImportResult{
+				{
+					Table:        "incident",
+					DisplayName:  "number",
+					DisplayValue: "INC0010001",
+					Status:       "inserted",
+					SysID:        "sys123",
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:           server.URL,
+		ServiceNowEndpointPath:      "/api/now/table/incident",
+		ServiceNowUsername:          "testuser",
+		ServiceNowPassword:          "testpass",
+		ServiceNowAPIMode:           config.APIModeImport,
+		ServiceNowImportPath:        "/api/now/import/u_alert_staging",
+		ServiceNowImportFieldPrefix: "u_",
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{
+		ShortDescription: "Test",
+		CorrelationID:    "test123",
+	})
+	if err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+
+	if receivedPath != "/api/now/import/u_alert_staging" {
+		t.Errorf("expected staging path, got %q", receivedPath)
+	}
+	if receivedBody["u_correlation_id"] != "test123" {
+		t.Errorf("expected u_correlation_id 'test123', got %q", receivedBody["u_correlation_id"])
+	}
+	if result.Number != "INC0010001" {
+		t.Errorf("expected incident number 'INC0010001', got %q", result.Number)
+	}
+	if result.SysID != "sys123" {
+		t.Errorf("expected sys_id 'sys123', got %q", result.SysID)
+	}
+}
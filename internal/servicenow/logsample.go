@@ -0,0 +1,94 @@
+package servicenow
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// requestSampler selects 1 in every N requests for debug logging. It counts
+// requests rather than drawing random numbers, so the sampled fraction is
+// exact and tests are deterministic.
+type requestSampler struct {
+	every uint64
+	count atomic.Uint64
+}
+
+// newRequestSampler returns a sampler logging 1 in every requests, or nil
+// (never sample) if every is not positive.
+func newRequestSampler(every int) *requestSampler {
+	if every <= 0 {
+		return nil
+	}
+	return &requestSampler{every: uint64(every)}
+}
+
+// sample reports whether the next request should be logged. The first
+// request is always sampled.
+func (s *requestSampler) sample() bool {
+	if s == nil {
+		return false
+	}
+	return (s.count.Add(1)-1)%s.every == 0
+}
+
+// sensitiveHeaders are never written to logs.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// do sends req, records it in metrics under op and, if it is sampled and
+// debug logging is enabled, logs the exchange with sensitive headers
+// redacted. Bodies are not logged.
+func (c *Client) do(req *http.Request, op string) (*http.Response, error) {
+	sampled := c.logSampler.sample() && c.logger.Enabled(req.Context(), slog.LevelDebug)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	c.observe(op, time.Since(start), resp, err)
+	if !sampled {
+		return resp, err
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req)),
+		slog.Any("request_headers", redactHeaders(req.Header)),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode),
+			slog.Any("response_headers", redactHeaders(resp.Header)),
+		)
+	}
+	c.logger.LogAttrs(context.Background(), slog.LevelDebug, "servicenow http exchange", attrs...)
+
+	return resp, err
+}
+
+// redactURL returns the request URL without any embedded credentials.
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
+
+// redactHeaders flattens headers for logging, replacing sensitive values.
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name := range h {
+		if sensitiveHeaders[name] {
+			out[name] = "REDACTED"
+			continue
+		}
+		out[name] = h.Get(name)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+)
+
+func TestRequestSampler(t *testing.T) {
+	tests := []struct {
+		every int
+		want  int
+	}{
+		{every: 0, want: 0},
+		{every: 1, want: 100},
+		{every: 4, want: 25},
+		{every: 3, want: 34},
+	}
+
+	for _, tt := range tests {
+		s := newRequestSampler(tt.every)
+		got := 0
+		for i := 0; i < 100; i++ {
+			if s.sample() {
+				got++
+			}
+		}
+		if got != tt.want {
+			t.Errorf("every %d: sampled %d of 100, want %d", tt.every, got, tt.want)
+		}
+	}
+}
+
+func TestClient_RequestLogSampling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:       server.URL,
+		ServiceNowEndpointPath:  "/api/now/table/incident",
+		ServiceNowUsername:      "testuser",
+		ServiceNowPassword:      "testpass",
+		ServiceNowLogSampleRate: 5,
+	}, metrics.New(), logger)
+
+	for i := 0; i < 20; i++ {
+		if err := client.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping() error = %v", err)
+		}
+	}
+
+	output := buf.String()
+	if got := strings.Count(output, "servicenow http exchange"); got != 4 {
+		t.Errorf("expected 4 of 20 exchanges logged, got %d", got)
+	}
+	if !strings.Contains(output, `"Authorization":"REDACTED"`) {
+		t.Errorf("expected redacted Authorization header in log, got:\n%s", output)
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte("testuser:testpass"))
+	if strings.Contains(output, "testpass") || strings.Contains(output, credentials) {
+		t.Errorf("log output leaks credentials:\n%s", output)
+	}
+}
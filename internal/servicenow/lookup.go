@@ -0,0 +1,146 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// sysIDResolver turns a configured display value, such as an assignment
+// group name or a caller's username, into the sys_id ServiceNow expects in
+// reference fields. Only successful lookups are cached, so a failed one is
+// retried on the next create.
+type sysIDResolver struct {
+	value     string // configured name, as set on incidents by the transformer
+	tablePath string // Table API path searched, e.g. /api/now/table/sys_user
+	field     string // column matched against value, e.g. user_name
+	what      string // describes the record in logs, e.g. "caller"
+	op        string // operation label for request metrics
+
+	mu    sync.Mutex
+	sysID string
+}
+
+// newGroupResolver resolves an assignment group by name.
+func newGroupResolver(name string) *sysIDResolver {
+	return &sysIDResolver{
+		value:     name,
+		tablePath: "/api/now/table/sys_user_group",
+		field:     "name",
+		what:      "assignment group",
+		op:        opGroupLookup,
+	}
+}
+
+// newCallerResolver resolves a caller by username.
+func newCallerResolver(username string) *sysIDResolver {
+	return &sysIDResolver{
+		value:     username,
+		tablePath: "/api/now/table/sys_user",
+		field:     "user_name",
+		what:      "caller",
+		op:        opUserLookup,
+	}
+}
+
+// resolveReferences replaces the configured assignment group and caller names
+// in incident with their sys_ids. The lookups only read from ServiceNow.
+func (c *Client) resolveReferences(ctx context.Context, incident *models.ServiceNowIncident) {
+	c.resolve(ctx, c.assignmentGroup.Load(), &incident.AssignmentGroup)
+	c.resolve(ctx, c.caller, &incident.CallerID)
+}
+
+// resolve replaces *field with the sys_id of r's record if it holds the
+// configured value. If the lookup fails or finds nothing, it logs an error
+// and clears the field, so the incident is created without the reference
+// rather than with one ServiceNow can't resolve.
+func (c *Client) resolve(ctx context.Context, r *sysIDResolver, field *string) {
+	if r == nil || *field != r.value {
+		return
+	}
+	*field = c.sysIDFor(ctx, r)
+}
+
+// sysIDFor returns the sys_id of r's record, looking it up on first use, or
+// "" if it can't be found.
+func (c *Client) sysIDFor(ctx context.Context, r *sysIDResolver) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sysID != "" {
+		return r.sysID
+	}
+
+	sysID, err := c.findSysID(ctx, r)
+	if err != nil {
+		c.logger.Error("failed to look up sys_id, creating incident without this reference",
+			"reference", r.what,
+			r.field, r.value,
+			"error", err,
+		)
+		return ""
+	}
+	if sysID == "" {
+		c.logger.Error("no record found for sys_id lookup, creating incident without this reference",
+			"reference", r.what,
+			r.field, r.value,
+			"table", r.tablePath,
+		)
+		return ""
+	}
+
+	c.logger.Info("resolved configured name to sys_id",
+		"reference", r.what,
+		r.field, r.value,
+		"sys_id", sysID,
+	)
+	r.sysID = sysID
+	return sysID
+}
+
+// findSysID returns the sys_id of the first record in r's table whose field
+// equals the configured value, or "" if there is none.
+func (c *Client) findSysID(ctx context.Context, r *sysIDResolver) (string, error) {
+	endpoint := fmt.Sprintf("%s%s?sysparm_query=%s=%s&sysparm_fields=sys_id&sysparm_limit=1",
+		c.baseURL, r.tablePath, r.field, url.QueryEscape(r.value))
+
+	var sysID string
+	err := WithRetry(ctx, c.retryConfigFor(r.op, r.field, r.value), func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		c.setHeaders(req)
+
+		resp, err := c.do(req, r.op)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if err := c.checkResponse(resp); err != nil {
+			return err
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		var listResp models.ServiceNowListResponse
+		if err := json.Unmarshal(respBody, &listResp); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(listResp.Result) > 0 {
+			sysID = listResp.Result[0].SysID
+		}
+		return nil
+	})
+	return sysID, err
+}
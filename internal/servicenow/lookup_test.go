@@ -0,0 +1,238 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestClient_CreateIncident_AssignmentGroupByName(t *testing.T) {
+	tests := []struct {
+		name        string
+		groupStatus int
+		groupBody   string
+		wantGroup   string
+		wantLookups int
+	}{
+		{
+			name:        "resolved and cached",
+			groupStatus: http.StatusOK,
+			groupBody:   `{"result":[{"sys_id":"grp123"}]}`,
+			wantGroup:   "grp123",
+			wantLookups: 1,
+		},
+		{
+			name:        "group not found",
+			groupStatus: http.StatusOK,
+			groupBody:   `{"result":[]}`,
+			wantLookups: 2,
+		},
+		{
+			name:        "lookup fails",
+			groupStatus: http.StatusForbidden,
+			groupBody:   `{"error":{"message":"ACL"}}`,
+			wantLookups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var lookups []string
+			var groups []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch r.URL.Path {
+				case "/api/now/table/sys_user_group":
+					lookups = append(lookups, r.URL.Query().Get("sysparm_query"))
+					w.WriteHeader(tt.groupStatus)
+					w.Write([]byte(tt.groupBody))
+				case "/api/now/table/incident":
+					var body map[string]any
+					json.NewDecoder(r.Body).Decode(&body)
+					group, _ := body["assignment_group"].(string)
+					groups = append(groups, group)
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+				default:
+					t.Errorf("unexpected request to %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				ServiceNowBaseURL:         server.URL,
+				ServiceNowEndpointPath:    "/api/now/table/incident",
+				ServiceNowUsername:        "user",
+				ServiceNowPassword:        "pass",
+				ServiceNowAssignmentGroup: "Platform Ops",
+				AssignmentGroupByName:     true,
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			for i := 0; i < 2; i++ {
+				incident := models.ServiceNowIncident{CorrelationID: "abc", AssignmentGroup: "Platform Ops"}
+				if _, err := client.CreateIncident(context.Background(), "", incident); err != nil {
+					t.Fatalf("CreateIncident() error = %v", err)
+				}
+			}
+
+			if len(lookups) != tt.wantLookups {
+				t.Errorf("group lookups = %d, want %d", len(lookups), tt.wantLookups)
+			}
+			if len(lookups) > 0 && lookups[0] != "name=Platform Ops" {
+				t.Errorf("lookup query = %q, want %q", lookups[0], "name=Platform Ops")
+			}
+			for i, group := range groups {
+				if group != tt.wantGroup {
+					t.Errorf("create %d assignment_group = %q, want %q", i, group, tt.wantGroup)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Reload_AssignmentGroupByName(t *testing.T) {
+	var lookups []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/now/table/sys_user_group":
+			lookups = append(lookups, r.URL.Query().Get("sysparm_query"))
+			w.Write([]byte(`{"result":[{"sys_id":"grp123"}]}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:         server.URL,
+		ServiceNowEndpointPath:    "/api/now/table/incident",
+		ServiceNowUsername:        "user",
+		ServiceNowPassword:        "pass",
+		ServiceNowAssignmentGroup: "Platform Ops",
+		AssignmentGroupByName:     true,
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	create := func(group string) {
+		t.Helper()
+		incident := models.ServiceNowIncident{CorrelationID: "abc", AssignmentGroup: group}
+		if _, err := client.CreateIncident(context.Background(), "", incident); err != nil {
+			t.Fatalf("CreateIncident() error = %v", err)
+		}
+	}
+
+	create("Platform Ops")
+	// Reloading the same group keeps the cached sys_id.
+	client.Reload(cfg)
+	create("Platform Ops")
+	reloaded := *cfg
+	reloaded.ServiceNowAssignmentGroup = "Database Ops"
+	client.Reload(&reloaded)
+	create("Database Ops")
+
+	want := []string{"name=Platform Ops", "name=Database Ops"}
+	if len(lookups) != len(want) || lookups[0] != want[0] || lookups[1] != want[1] {
+		t.Errorf("group lookups = %q, want %q", lookups, want)
+	}
+}
+
+func TestClient_CreateIncident_AssignmentGroupSysID(t *testing.T) {
+	var group string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/now/table/incident" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		group, _ = body["assignment_group"].(string)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:         server.URL,
+		ServiceNowEndpointPath:    "/api/now/table/incident",
+		ServiceNowUsername:        "user",
+		ServiceNowPassword:        "pass",
+		ServiceNowAssignmentGroup: "grp123",
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+
+	incident := models.ServiceNowIncident{CorrelationID: "abc", AssignmentGroup: "grp123"}
+	if _, err := client.CreateIncident(context.Background(), "", incident); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if group != "grp123" {
+		t.Errorf("assignment_group = %q, want the configured sys_id sent as is", group)
+	}
+}
+
+func TestClient_CreateIncident_CallerByUsername(t *testing.T) {
+	tests := []struct {
+		name       string
+		userBody   string
+		wantCaller string
+	}{
+		{name: "resolved", userBody: `{"result":[{"sys_id":"usr456"}]}`, wantCaller: "usr456"},
+		{name: "user not found", userBody: `{"result":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			var caller string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/now/table/sys_user":
+					query = r.URL.Query().Get("sysparm_query")
+					w.Write([]byte(tt.userBody))
+				case "/api/now/table/incident":
+					var body map[string]any
+					json.NewDecoder(r.Body).Decode(&body)
+					caller, _ = body["caller_id"].(string)
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+				default:
+					t.Errorf("unexpected request to %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				ServiceNowBaseURL:      server.URL,
+				ServiceNowEndpointPath: "/api/now/table/incident",
+				ServiceNowUsername:     "user",
+				ServiceNowPassword:     "pass",
+				ServiceNowCallerID:     "alertmanager.bot",
+				CallerIDByUsername:     true,
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			incident := models.ServiceNowIncident{CorrelationID: "abc", CallerID: "alertmanager.bot"}
+			if _, err := client.CreateIncident(context.Background(), "", incident); err != nil {
+				t.Fatalf("CreateIncident() error = %v", err)
+			}
+			if query != "user_name=alertmanager.bot" {
+				t.Errorf("lookup query = %q, want %q", query, "user_name=alertmanager.bot")
+			}
+			if caller != tt.wantCaller {
+				t.Errorf("caller_id = %q, want %q", caller, tt.wantCaller)
+			}
+		})
+	}
+}
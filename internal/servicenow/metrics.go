@@ -0,0 +1,50 @@
+package servicenow
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Operation label values for the ServiceNow request metrics.
+const (
+	opCreate      = "create"
+	opBatchCreate = "batch_create"
+	opFind        = "find"
+	opList        = "list"
+	opResolve     = "resolve"
+	opReopen      = "reopen"
+	opClose       = "close"
+	opWorkNote    = "work_note"
+	opPing        = "ping"
+	opGroupLookup = "group_lookup"
+	opUserLookup  = "user_lookup"
+)
+
+// statusDryRun is the status label of writes DryRunClient logged instead of
+// sending.
+const statusDryRun = "dry_run"
+
+// observe records one HTTP request to ServiceNow. The status label is the
+// HTTP status code, or "error" when no response was received.
+func (c *Client) observe(op string, duration time.Duration, resp *http.Response, err error) {
+	if c.metrics == nil {
+		return
+	}
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	c.metrics.ServiceNowRequests.WithLabelValues(op, status).Inc()
+	c.metrics.ServiceNowDuration.WithLabelValues(op).Observe(duration.Seconds())
+}
+
+// observeDryRun records a write that was logged instead of sent, so request
+// dashboards still show activity in dry-run mode. No latency is recorded.
+func (c *Client) observeDryRun(op string) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.ServiceNowRequests.WithLabelValues(op, statusDryRun).Inc()
+}
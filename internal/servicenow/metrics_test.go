@@ -0,0 +1,137 @@
+package servicenow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestClient_RequestMetrics(t *testing.T) {
+	creates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			creates++
+		}
+		switch {
+		case r.Method == http.MethodPost && creates == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"result":{"sys_id":"abc","number":"INC0010001"}}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"result":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	m := metrics.New()
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}, m, newTestLogger())
+	client.retryConfig.BaseDelay = 0
+
+	ctx := context.Background()
+	if _, err := client.FindIncidentByCorrelationID(ctx, "", "abc"); err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if _, err := client.CreateIncident(ctx, "", models.ServiceNowIncident{CorrelationID: "abc"}); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+
+	requests := func(op, status string) float64 {
+		var metric dto.Metric
+		if err := m.ServiceNowRequests.WithLabelValues(op, status).Write(&metric); err != nil {
+			t.Fatalf("failed to read counter: %v", err)
+		}
+		return metric.GetCounter().GetValue()
+	}
+	durations := func(op string) uint64 {
+		var metric dto.Metric
+		if err := m.ServiceNowDuration.WithLabelValues(op).(prometheus.Metric).Write(&metric); err != nil {
+			t.Fatalf("failed to read histogram: %v", err)
+		}
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	if got := requests(opFind, "200"); got != 1 {
+		t.Errorf("find requests with status 200 = %v, want 1", got)
+	}
+	if got := requests(opCreate, "503"); got != 1 {
+		t.Errorf("create requests with status 503 = %v, want 1", got)
+	}
+	if got := requests(opCreate, "201"); got != 1 {
+		t.Errorf("create requests with status 201 = %v, want 1", got)
+	}
+	if got := durations(opCreate); got != 2 {
+		t.Errorf("create duration observations = %d, want 2 (one per attempt)", got)
+	}
+	if got := durations(opFind); got != 1 {
+		t.Errorf("find duration observations = %d, want 1", got)
+	}
+}
+
+func TestClient_AuthFailures(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer server.Close()
+
+	m := metrics.New()
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "expired",
+	}, m, newTestLogger())
+
+	ctx := context.Background()
+	_, err := client.FindIncidentByCorrelationID(ctx, "", "abc")
+	if !IsAuthFailure(err) {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v, want an auth failure", err)
+	}
+	status = http.StatusForbidden
+	if err := client.Ping(ctx); !IsAuthFailure(err) {
+		t.Fatalf("Ping() error = %v, want an auth failure", err)
+	}
+
+	var metric dto.Metric
+	if err := m.ServiceNowAuthFailures.Write(&metric); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	if got := metric.GetCounter().GetValue(); got != 2 {
+		t.Errorf("auth failures = %v, want 2 (401s are not retried)", got)
+	}
+	if got := client.ConsecutiveAuthFailures(); got != 2 {
+		t.Errorf("ConsecutiveAuthFailures() = %d, want 2", got)
+	}
+
+	status = http.StatusOK
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if got := client.ConsecutiveAuthFailures(); got != 0 {
+		t.Errorf("ConsecutiveAuthFailures() = %d after a success, want 0", got)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := client.Ping(ctx); err == nil || IsAuthFailure(err) {
+		t.Errorf("Ping() error = %v, want a non-auth failure", err)
+	}
+	if got := client.ConsecutiveAuthFailures(); got != 0 {
+		t.Errorf("ConsecutiveAuthFailures() = %d after a 503, want 0", got)
+	}
+}
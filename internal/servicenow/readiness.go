@@ -0,0 +1,120 @@
+package servicenow
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+)
+
+const (
+	// readinessTimeout bounds each connectivity check when
+	// READINESS_TIMEOUT is not set.
+	readinessTimeout = 2 * time.Second
+	// readinessCacheTTL is how long a successful check is reused when
+	// READINESS_CACHE_TTL is not set, so frequent probes from several
+	// kubelets don't hammer the ServiceNow API.
+	readinessCacheTTL = 10 * time.Second
+)
+
+// Pinger checks connectivity to ServiceNow.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// authFailureCounter is implemented by pingers, such as Client, that count
+// consecutive authentication failures across all requests.
+type authFailureCounter interface {
+	ConsecutiveAuthFailures() int
+}
+
+// ReadinessHandler serves the readiness probe. It reports ready only while
+// ServiceNow is reachable, caching the last successful check.
+type ReadinessHandler struct {
+	pinger   Pinger
+	timeout  time.Duration
+	cacheTTL time.Duration
+	now      func() time.Time
+	logger   *slog.Logger
+
+	// authFailureThreshold, if positive, is the number of consecutive
+	// 401/403 responses after which a cached successful check is dropped.
+	authFailureThreshold int
+
+	mu     sync.Mutex
+	lastOK time.Time
+}
+
+// NewReadinessHandler creates a readiness handler backed by pinger, using
+// the check timeout and cache TTL from cfg. A non-positive timeout falls
+// back to the default; a zero TTL checks ServiceNow on every probe.
+func NewReadinessHandler(pinger Pinger, cfg *config.Config, logger *slog.Logger) *ReadinessHandler {
+	timeout := cfg.ReadinessTimeout
+	if timeout <= 0 {
+		timeout = readinessTimeout
+	}
+
+	return &ReadinessHandler{
+		pinger:               pinger,
+		timeout:              timeout,
+		cacheTTL:             cfg.ReadinessCacheTTL,
+		now:                  time.Now,
+		logger:               logger,
+		authFailureThreshold: cfg.ReadinessAuthFailures,
+	}
+}
+
+// ServeHTTP returns 200 when ServiceNow is reachable and 503 otherwise.
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.check(r.Context()); err != nil {
+		h.logger.Warn("readiness check failed", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if IsAuthFailure(err) {
+			w.Write([]byte("servicenow rejected credentials"))
+			return
+		}
+		w.Write([]byte("servicenow unreachable"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// check pings ServiceNow unless a successful check is still cached. Checks
+// are serialized so concurrent probes share one request. Once the pinger has
+// seen authFailureThreshold auth failures in a row the cache is skipped, so
+// the probe fails until a request, such as this ping, succeeds again.
+func (h *ReadinessHandler) check(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.authFailing() {
+		h.lastOK = time.Time{}
+	}
+
+	if !h.lastOK.IsZero() && h.now().Sub(h.lastOK) < h.cacheTTL {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	if err := h.pinger.Ping(ctx); err != nil {
+		h.lastOK = time.Time{}
+		return err
+	}
+
+	h.lastOK = h.now()
+	return nil
+}
+
+// authFailing reports whether the pinger has reached authFailureThreshold
+// consecutive auth failures.
+func (h *ReadinessHandler) authFailing() bool {
+	counter, ok := h.pinger.(authFailureCounter)
+	return ok && h.authFailureThreshold > 0 && counter.ConsecutiveAuthFailures() >= h.authFailureThreshold
+}
@@ -0,0 +1,208 @@
+package servicenow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+)
+
+func TestClient_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "reachable", status: http.StatusOK, wantErr: false},
+		{name: "bad credentials", status: http.StatusUnauthorized, wantErr: true},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if r.Method != http.MethodGet {
+					t.Errorf("expected GET, got %s", r.Method)
+				}
+				if _, _, ok := r.BasicAuth(); !ok {
+					t.Error("expected basic auth")
+				}
+				if got := r.URL.Query().Get("sysparm_limit"); got != "1" {
+					t.Errorf("expected sysparm_limit=1, got %q", got)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"result":[]}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.Config{
+				ServiceNowBaseURL:      server.URL,
+				ServiceNowEndpointPath: "/api/now/table/incident",
+				ServiceNowUsername:     "testuser",
+				ServiceNowPassword:     "testpass",
+			}, metrics.New(), newTestLogger())
+
+			err := client.Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != 1 {
+				t.Errorf("expected a single attempt, got %d", attempts)
+			}
+		})
+	}
+}
+
+type fakePinger struct {
+	err   error
+	calls int
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	p.calls++
+	return p.err
+}
+
+func TestReadinessHandler(t *testing.T) {
+	pinger := &fakePinger{}
+	handler := NewReadinessHandler(pinger, &config.Config{ReadinessCacheTTL: readinessCacheTTL}, newTestLogger())
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+
+	probe := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rr.Code
+	}
+
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("expected 200 when ServiceNow is reachable, got %d", code)
+	}
+
+	// Within the cache window a failure is not noticed and no request is made
+	pinger.err = &RetryableError{StatusCode: http.StatusServiceUnavailable}
+	now = now.Add(readinessCacheTTL / 2)
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("expected cached 200, got %d", code)
+	}
+	if pinger.calls != 1 {
+		t.Errorf("expected cached result to skip the ping, got %d calls", pinger.calls)
+	}
+
+	// Once the cache expires the failure is reported, and failures are not cached
+	now = now.Add(readinessCacheTTL)
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when ServiceNow is unreachable, got %d", code)
+	}
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 again, got %d", code)
+	}
+	if pinger.calls != 3 {
+		t.Errorf("expected failures to be rechecked, got %d calls", pinger.calls)
+	}
+
+	pinger.err = nil
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("expected 200 after recovery, got %d", code)
+	}
+}
+
+// blockingPinger waits for the check's context to end.
+type blockingPinger struct{}
+
+func (blockingPinger) Ping(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadinessHandler_Config(t *testing.T) {
+	t.Run("configured timeout bounds the check", func(t *testing.T) {
+		handler := NewReadinessHandler(blockingPinger{}, &config.Config{ReadinessTimeout: 20 * time.Millisecond}, newTestLogger())
+
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 on timeout, got %d", rr.Code)
+		}
+		if elapsed := time.Since(start); elapsed >= readinessTimeout {
+			t.Errorf("check took %v, expected the configured timeout to apply", elapsed)
+		}
+	})
+
+	t.Run("zero TTL checks on every probe", func(t *testing.T) {
+		pinger := &fakePinger{}
+		handler := NewReadinessHandler(pinger, &config.Config{}, newTestLogger())
+
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rr.Code)
+			}
+		}
+		if pinger.calls != 3 {
+			t.Errorf("expected 3 pings without caching, got %d", pinger.calls)
+		}
+	})
+}
+
+// authPinger is a fakePinger that also reports consecutive auth failures.
+type authPinger struct {
+	fakePinger
+	authFailures int
+}
+
+func (p *authPinger) ConsecutiveAuthFailures() int {
+	return p.authFailures
+}
+
+func TestReadinessHandler_AuthFailureThreshold(t *testing.T) {
+	pinger := &authPinger{}
+	handler := NewReadinessHandler(pinger, &config.Config{
+		ReadinessCacheTTL:     time.Hour,
+		ReadinessAuthFailures: 3,
+	}, newTestLogger())
+
+	probe := func() (int, string) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rr.Code, rr.Body.String()
+	}
+
+	if code, _ := probe(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	// Webhook requests start failing authentication; below the threshold
+	// the cached check still answers.
+	pinger.authFailures = 2
+	pinger.err = &RetryableError{Err: errors.New("unauthorized"), StatusCode: http.StatusUnauthorized}
+	if code, _ := probe(); code != http.StatusOK {
+		t.Errorf("expected cached 200 below the threshold, got %d", code)
+	}
+
+	pinger.authFailures = 3
+	code, body := probe()
+	if code != http.StatusServiceUnavailable || body != "servicenow rejected credentials" {
+		t.Errorf("expected 503 with an auth message at the threshold, got %d %q", code, body)
+	}
+
+	// Once a request succeeds again, the probe recovers.
+	pinger.authFailures = 0
+	pinger.err = nil
+	if code, _ := probe(); code != http.StatusOK {
+		t.Errorf("expected 200 after the credentials were rotated, got %d", code)
+	}
+	if pinger.calls != 3 {
+		t.Errorf("expected 3 pings, got %d", pinger.calls)
+	}
+}
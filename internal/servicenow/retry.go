@@ -4,24 +4,54 @@ package servicenow
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"math"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// timeAfter is the wait primitive used between attempts; tests replace it to
+// observe delays without sleeping.
+var timeAfter = time.After
+
+// timeNow is the clock MaxElapsed is measured against; tests replace it.
+var timeNow = time.Now
+
 // RetryConfig configures the retry behavior.
 type RetryConfig struct {
 	MaxAttempts int
 	BaseDelay   time.Duration
 	MaxDelay    time.Duration
+	// MaxElapsed caps the wall-clock time spent on one operation, attempts
+	// and waits included: no retry is started whose wait would end past it.
+	// Zero means no cap beyond MaxAttempts.
+	MaxElapsed time.Duration
+	// MaxRetryAfter caps a server-suggested Retry-After wait, so a huge value
+	// from a misbehaving proxy can't stall processing. Zero means no cap.
+	MaxRetryAfter time.Duration
+	// Jitter randomizes each backoff delay; disable for deterministic delays.
+	Jitter bool
+	// Rand returns values in [0.0, 1.0) for jitter and must be safe for
+	// concurrent use. The global math/rand source is used if nil.
+	Rand func() float64
+	// Logger, if set, gets a debug record for every failed attempt and for a
+	// success that needed retries.
+	Logger *slog.Logger
 }
 
 // DefaultRetryConfig returns the default retry configuration.
 func DefaultRetryConfig() RetryConfig {
 	return RetryConfig{
-		MaxAttempts: 3,
-		BaseDelay:   1 * time.Second,
-		MaxDelay:    10 * time.Second,
+		MaxAttempts:   3,
+		BaseDelay:     1 * time.Second,
+		MaxDelay:      10 * time.Second,
+		MaxRetryAfter: 60 * time.Second,
+		Jitter:        true,
 	}
 }
 
@@ -29,6 +59,9 @@ func DefaultRetryConfig() RetryConfig {
 type RetryableError struct {
 	Err        error
 	StatusCode int
+	// RetryAfter is the server-suggested wait before the next attempt, taken
+	// from the Retry-After header. Zero means no suggestion.
+	RetryAfter time.Duration
 }
 
 func (e *RetryableError) Error() string {
@@ -39,58 +72,178 @@ func (e *RetryableError) Unwrap() error {
 	return e.Err
 }
 
+// IsAuthFailure reports whether ServiceNow rejected the request's
+// credentials or their permissions with 401 or 403.
+func IsAuthFailure(err error) bool {
+	var retryableErr *RetryableError
+	return errors.As(err, &retryableErr) && isAuthStatus(retryableErr.StatusCode)
+}
+
+// isAuthStatus reports whether status is 401 Unauthorized or 403 Forbidden.
+func isAuthStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
 // IsRetryable determines if an error should be retried.
 func IsRetryable(err error) bool {
 	var retryableErr *RetryableError
 	if errors.As(err, &retryableErr) {
-		// Retry on 5xx server errors
-		return retryableErr.StatusCode >= 500
+		// Retry on 5xx server errors and 429 rate limiting
+		return retryableErr.StatusCode >= 500 || retryableErr.StatusCode == http.StatusTooManyRequests
 	}
 	// Retry on connection errors
 	return true
 }
 
-// WithRetry executes a function with exponential backoff retry logic.
+// IsUnavailable reports whether err means ServiceNow could not be reached or
+// could not serve the request: a transport error, 5xx or 429. Unlike
+// IsRetryable, it is false for errors that say nothing about ServiceNow's
+// availability, such as a response body that fails to decode.
+func IsUnavailable(err error) bool {
+	var retryableErr *RetryableError
+	if errors.As(err, &retryableErr) {
+		return retryableErr.StatusCode >= 500 || retryableErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// WithRetry executes a function with exponential backoff retry logic. It
+// gives up after cfg.MaxAttempts attempts, or earlier once waiting for the
+// next attempt would exceed cfg.MaxElapsed.
 func WithRetry(ctx context.Context, cfg RetryConfig, fn func() error) error {
 	var lastErr error
+	start := timeNow()
 
 	for attempt := 0; attempt < cfg.MaxAttempts; attempt++ {
 		lastErr = fn()
 		if lastErr == nil {
+			if attempt > 0 {
+				logAttempt(ctx, cfg, "request succeeded after retrying", attempt, nil, 0)
+			}
 			return nil
 		}
 
-		// Check if error is retryable
-		var retryableErr *RetryableError
-		if errors.As(lastErr, &retryableErr) {
-			// Don't retry 4xx client errors
-			if retryableErr.StatusCode >= 400 && retryableErr.StatusCode < 500 {
-				return lastErr
-			}
+		// Don't retry 4xx client errors (other than 429)
+		if !IsRetryable(lastErr) {
+			logAttempt(ctx, cfg, "request failed, not retryable", attempt, lastErr, 0)
+			return lastErr
 		}
 
 		// Don't sleep after the last attempt
 		if attempt < cfg.MaxAttempts-1 {
-			delay := calculateBackoff(attempt, cfg.BaseDelay, cfg.MaxDelay)
+			delay := calculateBackoff(attempt, cfg)
+
+			// Prefer the server's Retry-After hint over our own backoff,
+			// within MaxRetryAfter
+			var retryableErr *RetryableError
+			if errors.As(lastErr, &retryableErr) && retryableErr.RetryAfter > 0 {
+				delay = retryableErr.RetryAfter
+				if cfg.MaxRetryAfter > 0 && delay > cfg.MaxRetryAfter {
+					delay = cfg.MaxRetryAfter
+				}
+			}
+
+			if cfg.MaxElapsed > 0 && timeNow().Sub(start)+delay > cfg.MaxElapsed {
+				logAttempt(ctx, cfg, "request failed, retry time budget exhausted", attempt, lastErr, 0)
+				return lastErr
+			}
+
+			logAttempt(ctx, cfg, "request failed, retrying", attempt, lastErr, delay)
 
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(delay):
+			case <-timeAfter(delay):
 			}
 		}
 	}
 
+	logAttempt(ctx, cfg, "request failed, retries exhausted", cfg.MaxAttempts-1, lastErr, 0)
 	return lastErr
 }
 
-// calculateBackoff calculates the delay for a given attempt using exponential backoff.
-func calculateBackoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
-	delay := time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt)))
-	if delay > maxDelay {
-		delay = maxDelay
+// logAttempt logs the outcome of attempt (zero-based) at debug level if
+// cfg has a logger. delay is the wait before the next attempt, if any.
+func logAttempt(ctx context.Context, cfg RetryConfig, msg string, attempt int, err error, delay time.Duration) {
+	if cfg.Logger == nil || !cfg.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Int("attempt", attempt+1),
+		slog.Int("max_attempts", cfg.MaxAttempts),
+	}
+	if delay > 0 {
+		attrs = append(attrs, slog.Duration("delay", delay))
+	}
+	var retryableErr *RetryableError
+	if errors.As(err, &retryableErr) {
+		attrs = append(attrs, slog.Int("status_code", retryableErr.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	cfg.Logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// calculateBackoff calculates the delay for a given attempt using exponential
+// backoff (BaseDelay * 2^attempt).
+//
+// With cfg.Jitter set it uses "full jitter": the delay is a uniformly random
+// value between 0 and the exponential delay, so replicas that were throttled
+// at the same moment spread their retries out instead of retrying in lockstep.
+// MaxDelay is applied after jitter, so it bounds the actual wait.
+func calculateBackoff(attempt int, cfg RetryConfig) time.Duration {
+	delay := float64(cfg.BaseDelay) * math.Pow(2, float64(attempt))
+	if cfg.Jitter {
+		random := cfg.Rand
+		if random == nil {
+			random = rand.Float64
+		}
+		delay *= random()
 	}
-	return delay
+	if delay > float64(cfg.MaxDelay) {
+		return cfg.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// newJitterSource returns a goroutine-safe random source with its own seed,
+// so each client draws jitter independently of the global source.
+func newJitterSource() func() float64 {
+	var mu sync.Mutex
+	r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return r.Float64()
+	}
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number
+// of seconds or an HTTP-date. It returns zero if the value is missing, invalid,
+// or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
 }
 
 // IsClientError checks if the status code indicates a client error (4xx).
@@ -0,0 +1,345 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// recordDelays replaces the retry wait with one that records each requested
+// delay and returns immediately. The original is restored when the test ends.
+func recordDelays(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	orig := timeAfter
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	t.Cleanup(func() { timeAfter = orig })
+	return &delays
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "seconds", value: "2", want: 2 * time.Second},
+		{name: "seconds with whitespace", value: " 5 ", want: 5 * time.Second},
+		{name: "http date", value: "Mon, 15 Jan 2024 10:00:30 GMT", want: 30 * time.Second},
+		{name: "http date in the past", value: "Mon, 15 Jan 2024 09:00:00 GMT", want: 0},
+		{name: "zero seconds", value: "0", want: 0},
+		{name: "negative seconds", value: "-3", want: 0},
+		{name: "empty", value: "", want: 0},
+		{name: "garbage", value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryable_TooManyRequests(t *testing.T) {
+	if !IsRetryable(&RetryableError{StatusCode: http.StatusTooManyRequests}) {
+		t.Error("expected 429 to be retryable")
+	}
+	if IsRetryable(&RetryableError{StatusCode: http.StatusBadRequest}) {
+		t.Error("expected 400 not to be retryable")
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "transport error", err: fmt.Errorf("failed to send request: %w", &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("connection refused")}), want: true},
+		{name: "server error", err: &RetryableError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "rate limited", err: &RetryableError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "client error", err: &RetryableError{StatusCode: http.StatusBadRequest}},
+		{name: "decode error", err: errors.New("failed to decode response: unexpected EOF")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnavailable(tt.err); got != tt.want {
+				t.Errorf("IsUnavailable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CreateIncident_RetryAfter(t *testing.T) {
+	delays := recordDelays(t)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 3
+	client.retryConfig.BaseDelay = time.Millisecond
+
+	result, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "test123"})
+	if err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+	if result.Number != "INC0001234" {
+		t.Errorf("expected incident number 'INC0001234', got %q", result.Number)
+	}
+
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 2*time.Second {
+		t.Errorf("expected a single 2s wait honoring Retry-After, got %v", *delays)
+	}
+}
+
+func TestWithRetry_RetryAfterCap(t *testing.T) {
+	delays := recordDelays(t)
+
+	cfg := RetryConfig{
+		MaxAttempts:   2,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      time.Millisecond,
+		MaxRetryAfter: 5 * time.Second,
+	}
+	err := WithRetry(context.Background(), cfg, func() error {
+		return &RetryableError{
+			Err:        errors.New("rate limited"),
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: time.Hour,
+		}
+	})
+	if err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if len(*delays) != 1 || (*delays)[0] != 5*time.Second {
+		t.Errorf("expected a single wait capped at 5s, got %v", *delays)
+	}
+}
+
+func TestWithRetry_Jitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		jitter bool
+		want   []time.Duration
+	}{
+		{
+			name:   "deterministic without jitter",
+			jitter: false,
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+		{
+			name:   "full jitter scales each delay before the cap",
+			jitter: true,
+			want:   []time.Duration{25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := recordDelays(t)
+
+			cfg := RetryConfig{
+				MaxAttempts: 5,
+				BaseDelay:   100 * time.Millisecond,
+				MaxDelay:    300 * time.Millisecond,
+				Jitter:      tt.jitter,
+				Rand:        func() float64 { return 0.25 },
+			}
+			err := WithRetry(context.Background(), cfg, func() error {
+				return &RetryableError{Err: errors.New("unavailable"), StatusCode: http.StatusServiceUnavailable}
+			})
+			if err == nil {
+				t.Fatal("expected error after exhausting retries")
+			}
+
+			if len(*delays) != len(tt.want) {
+				t.Fatalf("delays = %v, want %v", *delays, tt.want)
+			}
+			for i, d := range *delays {
+				if d != tt.want[i] {
+					t.Errorf("delay[%d] = %v, want %v", i, d, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetry_MaxElapsed(t *testing.T) {
+	// Each attempt takes 10s on a fake clock that also advances by every wait.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	origNow, origAfter := timeNow, timeAfter
+	timeNow = func() time.Time { return now }
+	var delays []time.Duration
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		now = now.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- now
+		return ch
+	}
+	t.Cleanup(func() { timeNow, timeAfter = origNow, origAfter })
+
+	cfg := RetryConfig{
+		MaxAttempts: 10,
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    5 * time.Second,
+		MaxElapsed:  40 * time.Second,
+	}
+	attempts := 0
+	err := WithRetry(context.Background(), cfg, func() error {
+		attempts++
+		now = now.Add(10 * time.Second)
+		return &RetryableError{Err: errors.New("unavailable"), StatusCode: http.StatusServiceUnavailable}
+	})
+	if err == nil {
+		t.Fatal("expected error once the retry budget is spent")
+	}
+
+	// 10s + 5s + 10s + 5s + 10s = 40s; a third wait would end past the cap.
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3 with attempts remaining", attempts)
+	}
+	if len(delays) != 2 {
+		t.Errorf("delays = %v, want 2 waits", delays)
+	}
+}
+
+func TestNewClient_JitterSourcePerClient(t *testing.T) {
+	a := NewClient(&config.Config{}, metrics.New(), newTestLogger())
+	b := NewClient(&config.Config{}, metrics.New(), newTestLogger())
+
+	if a.retryConfig.Rand == nil || b.retryConfig.Rand == nil {
+		t.Fatal("expected each client to have its own jitter source")
+	}
+
+	// Independently seeded sources should not produce the same sequence
+	same := true
+	for i := 0; i < 4; i++ {
+		if a.retryConfig.Rand() != b.retryConfig.Rand() {
+			same = false
+		}
+	}
+	if same {
+		t.Error("expected clients to draw different jitter sequences")
+	}
+}
+
+func TestClient_RetryAttemptLogging(t *testing.T) {
+	recordDelays(t)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result":{"sys_id":"abc123","number":"INC0001234"}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewClient(&config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+	}, metrics.New(), logger)
+	client.retryConfig.MaxAttempts = 3
+
+	if _, err := client.CreateIncident(context.Background(), "", models.ServiceNowIncident{CorrelationID: "test123"}); err != nil {
+		t.Fatalf("CreateIncident() error = %v", err)
+	}
+
+	type attemptLog struct {
+		Msg           string `json:"msg"`
+		Operation     string `json:"operation"`
+		CorrelationID string `json:"correlation_id"`
+		Attempt       int    `json:"attempt"`
+		MaxAttempts   int    `json:"max_attempts"`
+		Delay         int64  `json:"delay"`
+		StatusCode    int    `json:"status_code"`
+		Error         string `json:"error"`
+	}
+	var logs []attemptLog
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry attemptLog
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry.Attempt > 0 {
+			logs = append(logs, entry)
+		}
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 attempt logs, got %d:\n%s", len(logs), buf.String())
+	}
+
+	retried := logs[0]
+	if retried.Msg != "request failed, retrying" || retried.Attempt != 1 || retried.MaxAttempts != 3 {
+		t.Errorf("unexpected first attempt log %+v", retried)
+	}
+	if retried.StatusCode != http.StatusServiceUnavailable || retried.Error == "" {
+		t.Errorf("expected status code and error in first attempt log, got %+v", retried)
+	}
+	if time.Duration(retried.Delay) != 2*time.Second {
+		t.Errorf("expected 2s delay in first attempt log, got %v", time.Duration(retried.Delay))
+	}
+
+	succeeded := logs[1]
+	if succeeded.Msg != "request succeeded after retrying" || succeeded.Attempt != 2 {
+		t.Errorf("unexpected second attempt log %+v", succeeded)
+	}
+
+	for _, entry := range logs {
+		if entry.Operation != "create" || entry.CorrelationID != "test123" {
+			t.Errorf("expected operation and correlation_id on every attempt log, got %+v", entry)
+		}
+	}
+}
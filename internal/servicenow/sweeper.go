@@ -0,0 +1,98 @@
+package servicenow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// sweepBatchSize caps how many incidents a single sweep closes so one run
+// cannot flood ServiceNow after a long outage; the next run picks up the rest.
+const sweepBatchSize = 100
+
+// SweeperClient defines the ServiceNow operations used by the Sweeper.
+type SweeperClient interface {
+	FindResolvedIncidentsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.ServiceNowResult, error)
+	CloseIncident(ctx context.Context, sysID string) error
+}
+
+// Sweeper periodically closes incidents that have stayed resolved for longer
+// than the configured age.
+type Sweeper struct {
+	client   SweeperClient
+	maxAge   time.Duration
+	interval time.Duration
+	now      func() time.Time
+	logger   *slog.Logger
+}
+
+// NewSweeper creates a Sweeper from the auto-close configuration.
+func NewSweeper(client SweeperClient, cfg *config.Config, logger *slog.Logger) *Sweeper {
+	return &Sweeper{
+		client:   client,
+		maxAge:   time.Duration(cfg.AutoCloseAfterDays) * 24 * time.Hour,
+		interval: cfg.AutoCloseInterval,
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// Run sweeps immediately and then on every interval until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	s.logger.Info("auto-close sweeper started",
+		"max_age", s.maxAge.String(),
+		"interval", s.interval.String(),
+	)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("auto-close sweep failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("auto-close sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep closes resolved incidents older than the configured age and returns
+// how many were closed. Failures to close individual incidents are logged and
+// do not stop the sweep.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	cutoff := s.now().Add(-s.maxAge)
+
+	incidents, err := s.client.FindResolvedIncidentsBefore(ctx, cutoff, sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, incident := range incidents {
+		if err := s.client.CloseIncident(ctx, incident.SysID); err != nil {
+			s.logger.Error("failed to close resolved incident",
+				"sys_id", incident.SysID,
+				"incident_number", incident.Number,
+				"error", err,
+			)
+			continue
+		}
+		closed++
+
+		s.logger.Info("closed resolved incident",
+			"sys_id", incident.SysID,
+			"incident_number", incident.Number,
+			"resolved_at", incident.ResolvedAt,
+		)
+	}
+
+	return closed, nil
+}
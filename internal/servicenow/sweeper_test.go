@@ -0,0 +1,156 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	var mu sync.Mutex
+	var query string
+	var closed []string
+	var closeStates []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			query = r.URL.Query().Get("sysparm_query")
+			json.NewEncoder(w).Encode(models.ServiceNowListResponse{
+				Result: []models.ServiceNowResult{
+					{SysID: "sys1", Number: "INC0000001", State: "6"},
+					{SysID: "sys2", Number: "INC0000002", State: "6"},
+				},
+			})
+		case http.MethodPatch:
+			var body models.ServiceNowUpdatePayload
+			json.NewDecoder(r.Body).Decode(&body)
+			closed = append(closed, strings.TrimPrefix(r.URL.Path, "/api/now/table/incident/"))
+			closeStates = append(closeStates, body.State)
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "alert2snow",
+		ServiceNowPassword:     "testpass",
+		AutoCloseAfterDays:     7,
+		AutoCloseInterval:      time.Hour,
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	sweeper := NewSweeper(client, cfg, newTestLogger())
+	sweeper.now = func() time.Time { return time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC) }
+
+	n, err := sweeper.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Sweep() closed %d incidents, want 2", n)
+	}
+
+	wantQuery := "state=6^sys_created_by=alert2snow^correlation_idISNOTEMPTY^resolved_at<2024-01-08 12:00:00"
+	if query != wantQuery {
+		t.Errorf("query = %q, want %q", query, wantQuery)
+	}
+
+	if len(closed) != 2 || closed[0] != "sys1" || closed[1] != "sys2" {
+		t.Errorf("closed = %v, want [sys1 sys2]", closed)
+	}
+	for _, state := range closeStates {
+		if state != models.StateClosed {
+			t.Errorf("close PATCH state = %q, want %q", state, models.StateClosed)
+		}
+	}
+}
+
+func TestSweeper_Sweep_ContinuesAfterCloseFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(models.ServiceNowListResponse{
+				Result: []models.ServiceNowResult{
+					{SysID: "bad", Number: "INC0000001"},
+					{SysID: "good", Number: "INC0000002"},
+				},
+			})
+		case http.MethodPatch:
+			if strings.HasSuffix(r.URL.Path, "/bad") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "alert2snow",
+		ServiceNowPassword:     "testpass",
+		AutoCloseAfterDays:     7,
+		AutoCloseInterval:      time.Hour,
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	n, err := NewSweeper(client, cfg, newTestLogger()).Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Sweep() closed %d incidents, want 1", n)
+	}
+}
+
+func TestSweeper_Run_StopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		AutoCloseAfterDays:     7,
+		AutoCloseInterval:      time.Hour,
+	}
+
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewSweeper(client, cfg, newTestLogger()).Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sweeper did not stop after context cancellation")
+	}
+}
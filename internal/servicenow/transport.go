@@ -0,0 +1,69 @@
+package servicenow
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+)
+
+// newTransport builds the HTTP transport for ServiceNow requests. It starts
+// from http.DefaultTransport, so TLS verification and the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables behave as in the standard
+// library. SERVICENOW_PROXY_URL sends every request through that proxy
+// instead, and SERVICENOW_PROXY_DISABLED connects directly.
+// SERVICENOW_CA_CERT_FILE adds trusted CAs to the system roots,
+// SERVICENOW_CLIENT_CERT_FILE and SERVICENOW_CLIENT_KEY_FILE present a client
+// certificate for mutual TLS, and SERVICENOW_INSECURE_SKIP_VERIFY turns
+// verification off.
+func newTransport(cfg *config.Config, logger *slog.Logger) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch {
+	case cfg.ServiceNowProxyDisabled:
+		transport.Proxy = nil
+	case cfg.ServiceNowProxyURL != "":
+		// config.Load has already validated the URL.
+		if proxyURL, err := config.ParseProxyURL(cfg.ServiceNowProxyURL); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	if cfg.ServiceNowCACertFile == "" && cfg.ServiceNowClientCertFile == "" && !cfg.ServiceNowSkipTLSVerify {
+		return transport
+	}
+	transport.TLSClientConfig = &tls.Config{}
+	if cfg.ServiceNowCACertFile != "" {
+		// config.Load has already loaded the file once; it can only fail
+		// here if it changed since, and the system roots are kept then.
+		pool, err := config.LoadCACertPool(cfg.ServiceNowCACertFile)
+		if err != nil {
+			logger.Error("failed to load CA bundle, using system roots",
+				"file", cfg.ServiceNowCACertFile,
+				"error", err,
+			)
+		} else {
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+	if cfg.ServiceNowClientCertFile != "" {
+		// As with the CA bundle, config.Load has already loaded the pair.
+		cert, err := tls.LoadX509KeyPair(cfg.ServiceNowClientCertFile, cfg.ServiceNowClientKeyFile)
+		if err != nil {
+			logger.Error("failed to load client certificate, connecting without one",
+				"cert_file", cfg.ServiceNowClientCertFile,
+				"key_file", cfg.ServiceNowClientKeyFile,
+				"error", err,
+			)
+		} else {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	if cfg.ServiceNowSkipTLSVerify {
+		logger.Warn("TLS certificate verification is DISABLED for ServiceNow requests; do not use SERVICENOW_INSECURE_SKIP_VERIFY in production",
+			"base_url", cfg.ServiceNowBaseURL,
+		)
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	return transport
+}
@@ -0,0 +1,230 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+)
+
+func TestClient_ProxyURL(t *testing.T) {
+	var gotURL, gotAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotAuth = r.Header.Get("Proxy-Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("proxyuser", "proxypass")
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      "http://servicenow.invalid",
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "user",
+		ServiceNowPassword:     "pass",
+		ServiceNowProxyURL:     proxyURL.String(),
+	}
+	client := NewClient(cfg, metrics.New(), newTestLogger())
+	client.retryConfig.MaxAttempts = 1
+
+	if _, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc123"); err != nil {
+		t.Fatalf("FindIncidentByCorrelationID() error = %v", err)
+	}
+	if !strings.HasPrefix(gotURL, "http://servicenow.invalid/api/now/table/incident") {
+		t.Errorf("proxy got request for %q, want the ServiceNow URL", gotURL)
+	}
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxyuser:proxypass"))
+	if gotAuth != wantAuth {
+		t.Errorf("Proxy-Authorization = %q, want %q", gotAuth, wantAuth)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		wantProxy bool
+	}{
+		{name: "environment proxy by default", cfg: &config.Config{}, wantProxy: true},
+		{name: "explicit proxy", cfg: &config.Config{ServiceNowProxyURL: "http://proxy.example.com:3128"}, wantProxy: true},
+		{name: "disabled", cfg: &config.Config{ServiceNowProxyDisabled: true}, wantProxy: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newTransport(tt.cfg, newTestLogger())
+			if (transport.Proxy != nil) != tt.wantProxy {
+				t.Errorf("Proxy set = %v, want %v", transport.Proxy != nil, tt.wantProxy)
+			}
+			if transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify {
+				t.Error("expected TLS verification to stay enabled")
+			}
+		})
+	}
+}
+
+func TestClient_TLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		caFile     string
+		skipVerify bool
+		wantErr    bool
+	}{
+		{name: "untrusted certificate", wantErr: true},
+		{name: "CA bundle", caFile: caFile},
+		{name: "verification skipped", skipVerify: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ServiceNowBaseURL:       server.URL,
+				ServiceNowEndpointPath:  "/api/now/table/incident",
+				ServiceNowUsername:      "user",
+				ServiceNowPassword:      "pass",
+				ServiceNowCACertFile:    tt.caFile,
+				ServiceNowSkipTLSVerify: tt.skipVerify,
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			_, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc123")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FindIncidentByCorrelationID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTransport_SkipVerifyWarning(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		transport := newTransport(&config.Config{ServiceNowSkipTLSVerify: skip}, logger)
+
+		gotSkip := transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify
+		if gotSkip != skip {
+			t.Errorf("skip=%v: InsecureSkipVerify = %v", skip, gotSkip)
+		}
+		warned := strings.Contains(buf.String(), "level=WARN") && strings.Contains(buf.String(), "TLS certificate verification is DISABLED")
+		if warned != skip {
+			t.Errorf("skip=%v: warning logged = %v, want %v; log:\n%s", skip, warned, skip, buf.String())
+		}
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key as PEM
+// files in dir and returns the certificate and the two paths.
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alert2snow-agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestClient_ClientCertificate(t *testing.T) {
+	clientCert, certFile, keyFile := writeClientCert(t, t.TempDir())
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":[]}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "no client certificate", wantErr: true},
+		{name: "client certificate", certFile: certFile, keyFile: keyFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ServiceNowBaseURL:        server.URL,
+				ServiceNowEndpointPath:   "/api/now/table/incident",
+				ServiceNowUsername:       "user",
+				ServiceNowPassword:       "pass",
+				ServiceNowSkipTLSVerify:  true,
+				ServiceNowClientCertFile: tt.certFile,
+				ServiceNowClientKeyFile:  tt.keyFile,
+			}
+			client := NewClient(cfg, metrics.New(), newTestLogger())
+			client.retryConfig.MaxAttempts = 1
+
+			_, err := client.FindIncidentByCorrelationID(context.Background(), "", "abc123")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FindIncidentByCorrelationID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
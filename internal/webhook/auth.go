@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// authorized reports whether the request carries the configured bearer token.
+// When no token is configured, every request is accepted.
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.cfg.WebhookAuthToken == "" {
+		return true
+	}
+
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return tokensEqual(token, h.cfg.WebhookAuthToken)
+}
+
+// tokensEqual compares two secrets in constant time. Both values are hashed
+// first so the comparison does not leak the length of the expected token.
+func tokensEqual(got, want string) bool {
+	gotSum := sha256.Sum256([]byte(got))
+	wantSum := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(gotSum[:], wantSum[:]) == 1
+}
+
+// validSignature reports whether the request carries a valid HMAC-SHA256
+// signature of the raw body in the configured header. The header value is the
+// hex-encoded digest, optionally prefixed with "sha256=". When no secret is
+// configured, every request is accepted.
+func (h *Handler) validSignature(r *http.Request, body []byte) bool {
+	if h.cfg.WebhookHMACSecret == "" {
+		return true
+	}
+
+	signature := strings.TrimPrefix(r.Header.Get(h.cfg.WebhookHMACHeader), "sha256=")
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.cfg.WebhookHMACSecret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
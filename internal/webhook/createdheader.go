@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// createdHeader lists the numbers of incidents a webhook request created,
+// for integrators that only read response headers.
+const createdHeader = "X-Alert2Snow-Created"
+
+// createdIncidents collects the numbers of incidents created while serving
+// one webhook request. Workers record into it concurrently.
+type createdIncidents struct {
+	mu      sync.Mutex
+	numbers []string
+}
+
+type createdIncidentsKey struct{}
+
+// withCreatedIncidents returns a context whose incident creates are recorded
+// in the returned collector.
+func withCreatedIncidents(ctx context.Context) (context.Context, *createdIncidents) {
+	created := &createdIncidents{}
+	return context.WithValue(ctx, createdIncidentsKey{}, created), created
+}
+
+// recordCreated notes that an incident was created on behalf of ctx. It does
+// nothing outside a webhook request, such as during queue replays.
+func recordCreated(ctx context.Context, correlationID, number string) {
+	recordAction(ctx, correlationID, actionCreated, number)
+	created, ok := ctx.Value(createdIncidentsKey{}).(*createdIncidents)
+	if !ok || number == "" {
+		return
+	}
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	created.numbers = append(created.numbers, number)
+}
+
+// header returns the recorded numbers sorted and comma-joined, or "" if none
+// were created.
+func (c *createdIncidents) header() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	numbers := slices.Clone(c.numbers)
+	slices.Sort(numbers)
+	return strings.Join(numbers, ",")
+}
@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// deadLetterRefreshInterval is how often the dead-letter gauges are
+// recomputed from the file, which operators may truncate or rotate.
+const deadLetterRefreshInterval = 30 * time.Second
+
+// Dead-letter operations, the operation label of alert2snow_dead_lettered_total.
+const (
+	deadLetterCreate  = "create"
+	deadLetterResolve = "resolve"
+)
+
+// deadLetterEntry is alert work that failed and will not be retried.
+type deadLetterEntry struct {
+	Operation      string            `json:"operation"`
+	Alerts         []models.Alert    `json:"alerts"`
+	GroupLabels    map[string]string `json:"group_labels,omitempty"`
+	ExternalURL    string            `json:"external_url,omitempty"`
+	Error          string            `json:"error"`
+	DeadLetteredAt time.Time         `json:"dead_lettered_at"`
+}
+
+// DeadLetter is an append-only JSON-lines file of alert work ServiceNow
+// rejected, kept for operators to inspect and replay by hand.
+type DeadLetter struct {
+	path    string
+	metrics *metrics.Metrics
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenDeadLetter opens the dead-letter file at path for appending, creating
+// it and its directory if needed.
+func OpenDeadLetter(path string, m *metrics.Metrics) (*DeadLetter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+
+	d := &DeadLetter{path: path, metrics: m, file: file}
+	if err := d.refresh(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// write appends an entry and counts it.
+func (d *DeadLetter) write(entry deadLetterEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead-letter entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return errors.New("dead-letter file is closed")
+	}
+	if _, err := d.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write dead-letter entry: %w", err)
+	}
+	d.metrics.DeadLettered.WithLabelValues(entry.Operation).Inc()
+	d.metrics.DeadLetterEntries.Inc()
+	d.metrics.DeadLetterBytes.Add(float64(len(line)))
+	return d.file.Sync()
+}
+
+// refresh sets the dead-letter gauges from the file's current contents.
+func (d *DeadLetter) refresh() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+	d.metrics.DeadLetterEntries.Set(float64(bytes.Count(data, []byte{'\n'})))
+	d.metrics.DeadLetterBytes.Set(float64(len(data)))
+	return nil
+}
+
+// RefreshMetrics keeps the dead-letter gauges in step with the file until
+// ctx is cancelled.
+func (d *DeadLetter) RefreshMetrics(ctx context.Context) {
+	ticker := time.NewTicker(deadLetterRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.refresh()
+		}
+	}
+}
+
+// Close closes the dead-letter file.
+func (d *DeadLetter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
+
+// SetDeadLetter makes the handler write alerts ServiceNow rejects to d. It
+// must be called before the handler serves requests.
+func (h *Handler) SetDeadLetter(d *DeadLetter) {
+	h.deadLetter = d
+}
+
+// deadLetterable reports whether work that failed with err belongs in the
+// dead-letter file: any failure that is not queued, since sending it again
+// unchanged would fail the same way. Rate-limited alerts are retried by
+// Alertmanager.
+func deadLetterable(err error) bool {
+	return err != nil && !errors.Is(err, errRateLimited) && !queueable(err)
+}
+
+// deadLetterOperation names what failed work was trying to do: create (or
+// update) an incident if any of its alerts is firing, otherwise resolve one.
+func deadLetterOperation(alerts []models.Alert) string {
+	for _, alert := range alerts {
+		if alert.Status != models.AlertStatusResolved {
+			return deadLetterCreate
+		}
+	}
+	return deadLetterResolve
+}
+
+// sendToDeadLetter records failed work if a dead-letter file is configured.
+// groupLabels is nil unless the alerts form a group.
+func (h *Handler) sendToDeadLetter(ctx context.Context, alerts []models.Alert, groupLabels map[string]string, externalURL string, cause error) {
+	if h.deadLetter == nil || len(alerts) == 0 {
+		return
+	}
+
+	operation := deadLetterOperation(alerts)
+	err := h.deadLetter.write(deadLetterEntry{
+		Operation:      operation,
+		Alerts:         alerts,
+		GroupLabels:    groupLabels,
+		ExternalURL:    externalURL,
+		Error:          cause.Error(),
+		DeadLetteredAt: h.now().UTC(),
+	})
+	if err != nil {
+		h.log(ctx).Error("failed to write dead-letter entry", "error", err)
+		return
+	}
+	h.log(ctx).Warn("dead-lettered alert",
+		"alertname", alerts[0].Labels["alertname"],
+		"alerts", len(alerts),
+		"operation", operation,
+	)
+}
+
+// deadLetterJob records a failed worker pool job.
+func (h *Handler) deadLetterJob(ctx context.Context, job alertJob, externalURL string, cause error) {
+	if job.group != nil {
+		h.sendToDeadLetter(ctx, job.group.alerts, job.group.labels, externalURL, cause)
+		return
+	}
+	h.sendToDeadLetter(ctx, []models.Alert{job.alert}, nil, externalURL, cause)
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+	"github.com/cragr/alert2snow-agent/internal/servicenow"
+)
+
+func TestHandler_DeadLettersRejectedCreate(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			return nil, &servicenow.RetryableError{Err: errors.New("bad request"), StatusCode: http.StatusBadRequest}
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, m, newTestLogger()), m, newTestLogger())
+
+	path := filepath.Join(t.TempDir(), "dead-letter", "alerts.jsonl")
+	deadLetter, err := OpenDeadLetter(path, m)
+	if err != nil {
+		t.Fatalf("OpenDeadLetter() error = %v", err)
+	}
+	defer deadLetter.Close()
+	handler.SetDeadLetter(deadLetter)
+
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TestAlert"}})
+
+	if got := counterValue(t, m.DeadLettered, deadLetterCreate); got != 1 {
+		t.Errorf("dead_lettered_total{operation=create} = %v, want 1", got)
+	}
+	if got := metricValue(t, m.DeadLetterEntries); got != 1 {
+		t.Errorf("dead_letter_entries = %v, want 1", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metricValue(t, m.DeadLetterBytes); got != float64(len(data)) {
+		t.Errorf("dead_letter_size_bytes = %v, want %d", got, len(data))
+	}
+	var entry deadLetterEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("failed to decode dead-letter entry: %v", err)
+	}
+	if entry.Operation != deadLetterCreate || entry.Alerts[0].Labels["alertname"] != "TestAlert" {
+		t.Errorf("unexpected dead-letter entry %+v", entry)
+	}
+	if !strings.Contains(entry.Error, "bad request") {
+		t.Errorf("entry error = %q, want the ServiceNow error", entry.Error)
+	}
+
+	// Truncating the file is picked up by the next refresh.
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := deadLetter.refresh(); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if got := metricValue(t, m.DeadLetterEntries); got != 0 {
+		t.Errorf("dead_letter_entries after truncation = %v, want 0", got)
+	}
+}
+
+func TestHandler_DeadLetterSkipsRetryableErrors(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			return nil, &servicenow.RetryableError{Err: errors.New("service unavailable"), StatusCode: http.StatusServiceUnavailable}
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, m, newTestLogger()), m, newTestLogger())
+
+	deadLetter, err := OpenDeadLetter(filepath.Join(t.TempDir(), "alerts.jsonl"), m)
+	if err != nil {
+		t.Fatalf("OpenDeadLetter() error = %v", err)
+	}
+	defer deadLetter.Close()
+	handler.SetDeadLetter(deadLetter)
+
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TestAlert"}})
+
+	if got := counterValue(t, m.DeadLettered, deadLetterCreate); got != 0 {
+		t.Errorf("expected unavailable ServiceNow not to dead-letter, got %v", got)
+	}
+}
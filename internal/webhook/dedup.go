@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupCache remembers correlation IDs of recently processed firing alerts so
+// rapid re-sends (e.g. during a group flap) skip ServiceNow entirely. It is
+// per replica and best effort; ServiceNow lookups remain the source of truth.
+type dedupCache struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// newDedupCache creates a cache with the given window; a zero window disables it.
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window: window,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// recent reports whether id was recorded within the window.
+func (c *dedupCache) recent(id string) bool {
+	if c.window <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	at, ok := c.seen[id]
+	return ok && c.now().Sub(at) < c.window
+}
+
+// record marks id as processed now, pruning expired entries at most once per window.
+func (c *dedupCache) record(id string) {
+	if c.window <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.seen[id] = now
+
+	if now.Sub(c.lastPrune) >= c.window {
+		for k, at := range c.seen {
+			if now.Sub(at) >= c.window {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPrune = now
+	}
+}
+
+// forget removes id so the next firing alert for it is processed.
+func (c *dedupCache) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, id)
+}
+
+// len returns the number of entries, including expired ones not yet pruned.
+func (c *dedupCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.seen)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+	"github.com/cragr/alert2snow-agent/internal/servicenow"
+)
+
+func TestDedupCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newDedupCache(5 * time.Minute)
+	cache.now = func() time.Time { return now }
+
+	if cache.recent("a") {
+		t.Fatal("unrecorded id reported as recent")
+	}
+
+	cache.record("a")
+	now = now.Add(4 * time.Minute)
+	if !cache.recent("a") {
+		t.Error("id recorded 4m ago should be recent")
+	}
+
+	now = now.Add(time.Minute)
+	if cache.recent("a") {
+		t.Error("id recorded 5m ago should have expired")
+	}
+
+	// Recording another id after the window prunes the expired entry.
+	cache.record("b")
+	if n := cache.len(); n != 1 {
+		t.Errorf("expected expired entries to be pruned, %d remain", n)
+	}
+
+	cache.forget("b")
+	if cache.recent("b") {
+		t.Error("forgotten id reported as recent")
+	}
+}
+
+func TestDedupCache_Disabled(t *testing.T) {
+	cache := newDedupCache(0)
+	cache.record("a")
+	if cache.recent("a") {
+		t.Error("zero window should disable deduplication")
+	}
+}
+
+func TestHandler_Dedup(t *testing.T) {
+	mockClient := &mockServiceNowClient{}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		DedupWindow:         5 * time.Minute,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	firing := models.Alert{Status: "firing", Labels: labels}
+	resolved := models.Alert{Status: "resolved", Labels: labels}
+
+	// The mock never finds an incident, so every processed firing alert creates one.
+	sendAlerts(t, handler, firing)
+	sendAlerts(t, handler, firing)
+	if len(mockClient.createCalls) != 1 {
+		t.Fatalf("expected duplicate firing alert to be skipped, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+
+	// A resolve clears the entry so the next firing alert is processed.
+	sendAlerts(t, handler, resolved)
+	sendAlerts(t, handler, firing)
+	if len(mockClient.createCalls) != 2 {
+		t.Errorf("expected firing alert after resolve to be processed, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_Dedup_FailureNotRecorded(t *testing.T) {
+	fail := true
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			if fail {
+				return nil, errors.New("servicenow unavailable")
+			}
+			return &servicenow.CreateIncidentResult{SysID: "sys1", Number: "INC0000001"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		DedupWindow:         5 * time.Minute,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	firing := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+	}
+
+	sendAlerts(t, handler, firing)
+	fail = false
+	sendAlerts(t, handler, firing)
+
+	if len(mockClient.createCalls) != 2 {
+		t.Errorf("expected failed alert to be retried, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+}
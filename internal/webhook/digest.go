@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// digestDateLayout formats the day a digest incident covers.
+const digestDateLayout = "2006-01-02"
+
+// IsDigestAlert reports whether the alert's severity is configured to be
+// collected into the daily digest rather than raising its own incident.
+func (t *Transformer) IsDigestAlert(alert models.Alert) bool {
+	severity := t.Severity(alert)
+	for _, s := range t.cfg.DigestSeverities {
+		if strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatesIncident reports whether a firing alert's severity is in
+// CREATE_FOR_SEVERITIES, compared case-insensitively. Every alert qualifies
+// when the list is empty.
+func (t *Transformer) CreatesIncident(alert models.Alert) bool {
+	if len(t.cfg.CreateForSeverities) == 0 {
+		return true
+	}
+	severity := t.Severity(alert)
+	for _, s := range t.cfg.CreateForSeverities {
+		if strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestIncident builds the digest incident for a cluster and UTC day.
+func (t *Transformer) DigestIncident(cluster string, day time.Time) models.ServiceNowIncident {
+	date := day.UTC().Format(digestDateLayout)
+
+	incident := models.ServiceNowIncident{
+		ShortDescription: fmt.Sprintf("[%s] Daily alert digest %s", cluster, date),
+		Description: fmt.Sprintf("Low-severity alerts (%s) for cluster %s on %s are recorded as work notes on this incident.",
+			strings.Join(t.cfg.DigestSeverities, ", "), cluster, date),
+		Impact:          t.cfg.ServiceNowImpact,
+		Urgency:         t.cfg.ServiceNowUrgency,
+		Category:        t.cfg.ServiceNowCategory,
+		Subcategory:     t.cfg.ServiceNowSubcategory,
+		AssignmentGroup: t.cfg.ServiceNowAssignmentGroup,
+		CallerID:        t.cfg.ServiceNowCallerID,
+		ContactType:     t.cfg.ServiceNowContactType,
+		CorrelationID:   t.DigestCorrelationID(cluster, day),
+	}
+	if len(t.cfg.ServiceNowExtraFields) > 0 {
+		incident.ExtraFields = t.staticFields()
+	}
+	t.applyMarker(&incident)
+	return incident
+}
+
+// DigestCorrelationID returns the correlation ID shared by every alert that
+// lands in a cluster's digest for the given UTC day.
+func (t *Transformer) DigestCorrelationID(cluster string, day time.Time) string {
+	return t.correlationID("digest", map[string]string{
+		"cluster": cluster,
+		"date":    day.UTC().Format(digestDateLayout),
+	})
+}
+
+// handleDigestAlert appends a firing alert to its cluster's digest incident
+// for the current day, creating the incident on the first alert of the day.
+// Resolved digest alerts are ignored; the digest is not tied to any one alert.
+func (h *Handler) handleDigestAlert(ctx context.Context, alert models.Alert) error {
+	alertname := alert.Labels["alertname"]
+
+	if alert.Status != models.AlertStatusFiring {
+		h.log(ctx).Debug("ignoring non-firing digest alert",
+			"alertname", alertname,
+			"status", alert.Status,
+		)
+		return nil
+	}
+
+	t := h.transformer(ctx)
+	cluster := t.extractClusterName(alert)
+	if cluster == "" {
+		cluster = "unknown-cluster"
+	}
+	day := h.now()
+	correlationID := t.DigestCorrelationID(cluster, day)
+
+	// Serialize digest updates so concurrent workers don't each create
+	// the day's first digest incident.
+	unlock := h.locks.lock(correlationID)
+	defer unlock()
+
+	existing, err := h.snowClient.FindIncidentByCorrelationID(ctx, "", correlationID)
+	if err != nil {
+		return err
+	}
+
+	sysID, number := "", ""
+	if existing != nil {
+		sysID, number = existing.SysID, existing.Number
+	} else {
+		result, err := h.snowClient.CreateIncident(ctx, "", t.DigestIncident(cluster, day))
+		if err != nil {
+			return err
+		}
+		sysID, number = result.SysID, result.Number
+		recordCreated(ctx, correlationID, number)
+
+		h.log(ctx).Info("created daily digest incident in ServiceNow",
+			"cluster", cluster,
+			"correlation_id", correlationID,
+			"incident_number", number,
+			"sys_id", sysID,
+		)
+	}
+
+	if err := h.snowClient.AddWorkNote(ctx, "", sysID, t.AlertWorkNote(alert)); err != nil {
+		return err
+	}
+
+	recordAction(ctx, correlationID, actionAddedToDigest, number)
+
+	h.log(ctx).Info("added alert to daily digest",
+		"alertname", alertname,
+		"cluster", cluster,
+		"incident_number", number,
+	)
+
+	return nil
+}
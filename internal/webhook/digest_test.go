@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func sendAlerts(t *testing.T, handler *Handler, alerts ...models.Alert) {
+	t.Helper()
+	body, _ := json.Marshal(models.AlertmanagerPayload{Version: "4", Alerts: alerts})
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestHandler_Digest_CreatesThenAppends(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		DigestSeverities:    []string{"info", "warning"},
+		WorkerPoolSize:      1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	handler.now = func() time.Time { return time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC) }
+
+	lowSeverity := func(name string) models.Alert {
+		return models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": name, "cluster": "prod-east", "severity": "Warning"},
+		}
+	}
+
+	// First alert of the day creates the digest
+	sendAlerts(t, handler, lowSeverity("DiskFilling"))
+
+	if len(mockClient.createCalls) != 1 {
+		t.Fatalf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+	digest := mockClient.createCalls[0]
+	if digest.ShortDescription != "[prod-east] Daily alert digest 2024-01-15" {
+		t.Errorf("unexpected digest short description %q", digest.ShortDescription)
+	}
+	if digest.CorrelationID != handler.transformer(context.Background()).DigestCorrelationID("prod-east", handler.now()) {
+		t.Errorf("unexpected digest correlation ID %q", digest.CorrelationID)
+	}
+
+	// Later alerts the same day only append work notes
+	sendAlerts(t, handler, lowSeverity("PodRestarting"), lowSeverity("CertExpiringSoon"))
+
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected digest to be reused, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+	notes := mockClient.workNotes["sys1"]
+	if len(notes) != 3 {
+		t.Fatalf("expected 3 work notes on digest, got %d", len(notes))
+	}
+	for i, name := range []string{"DiskFilling", "PodRestarting", "CertExpiringSoon"} {
+		if !strings.Contains(notes[i], "Alert: "+name) {
+			t.Errorf("work note %d should mention %s, got %q", i, name, notes[i])
+		}
+	}
+
+	// A new day starts a new digest
+	handler.now = func() time.Time { return time.Date(2024, 1, 16, 0, 5, 0, 0, time.UTC) }
+	sendAlerts(t, handler, lowSeverity("DiskFilling"))
+
+	if len(mockClient.createCalls) != 2 {
+		t.Errorf("expected a new digest for the next day, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+	if len(mockClient.workNotes["sys2"]) != 1 {
+		t.Errorf("expected 1 work note on the next day's digest, got %d", len(mockClient.workNotes["sys2"]))
+	}
+}
+
+func TestHandler_Digest_OtherSeveritiesUnaffected(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		DigestSeverities:    []string{"info"},
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler,
+		models.Alert{Status: "firing", Labels: map[string]string{"alertname": "NodeDown", "cluster": "prod-east", "severity": "critical"}},
+		models.Alert{Status: "resolved", Labels: map[string]string{"alertname": "Chatty", "cluster": "prod-east", "severity": "info"}},
+	)
+
+	if len(mockClient.createCalls) != 1 {
+		t.Fatalf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+	if !strings.Contains(mockClient.createCalls[0].ShortDescription, "NodeDown") {
+		t.Errorf("expected a regular incident for the critical alert, got %q", mockClient.createCalls[0].ShortDescription)
+	}
+	if len(mockClient.workNotes) != 0 {
+		t.Errorf("expected no work notes, got %v", mockClient.workNotes)
+	}
+	if len(mockClient.resolveCalls) != 0 {
+		t.Errorf("expected resolved digest alert to be ignored, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+	}
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// Annotations that carry Grafana-specific alert fields through the pipeline.
+const (
+	annotationGrafanaDashboardURL = "grafana_dashboard_url"
+	annotationGrafanaPanelURL     = "grafana_panel_url"
+	annotationGrafanaValueString  = "grafana_value_string"
+)
+
+// payloadParser decodes a webhook request body into the internal payload.
+type payloadParser func(body []byte) (*models.AlertmanagerPayload, error)
+
+// parseAlertmanagerPayload decodes an Alertmanager webhook body.
+func parseAlertmanagerPayload(body []byte) (*models.AlertmanagerPayload, error) {
+	var payload models.AlertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// parseGrafanaPayload decodes a Grafana unified alerting webhook body and maps
+// it onto the Alertmanager payload so the rest of the pipeline is shared.
+// Dashboard and panel links and the evaluated value are kept as annotations.
+func parseGrafanaPayload(body []byte) (*models.AlertmanagerPayload, error) {
+	var grafana models.GrafanaPayload
+	if err := json.Unmarshal(body, &grafana); err != nil {
+		return nil, err
+	}
+
+	payload := &models.AlertmanagerPayload{
+		Version:           grafana.Version,
+		GroupKey:          grafana.GroupKey,
+		TruncatedAlerts:   grafana.TruncatedAlerts,
+		Status:            grafana.Status,
+		Receiver:          grafana.Receiver,
+		GroupLabels:       grafana.GroupLabels,
+		CommonLabels:      grafana.CommonLabels,
+		CommonAnnotations: grafana.CommonAnnotations,
+		ExternalURL:       grafana.ExternalURL,
+		Alerts:            make([]models.Alert, 0, len(grafana.Alerts)),
+	}
+
+	for _, a := range grafana.Alerts {
+		annotations := make(map[string]string, len(a.Annotations)+3)
+		maps.Copy(annotations, a.Annotations)
+		setIfNotEmpty(annotations, annotationGrafanaDashboardURL, a.DashboardURL)
+		setIfNotEmpty(annotations, annotationGrafanaPanelURL, a.PanelURL)
+		setIfNotEmpty(annotations, annotationGrafanaValueString, a.ValueString)
+
+		payload.Alerts = append(payload.Alerts, models.Alert{
+			Status:       a.Status,
+			Labels:       a.Labels,
+			Annotations:  annotations,
+			StartsAt:     a.StartsAt,
+			EndsAt:       a.EndsAt,
+			GeneratorURL: a.GeneratorURL,
+			Fingerprint:  a.Fingerprint,
+		})
+	}
+
+	return payload, nil
+}
+
+func setIfNotEmpty(m map[string]string, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+// GrafanaHandler returns an http.Handler for Grafana unified alerting
+// webhooks. It shares authentication and alert processing with ServeHTTP.
+func (h *Handler) GrafanaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, "grafana", parseGrafanaPayload)
+	})
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func readGrafanaPayload(t *testing.T) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join(findProjectRoot(t), "test-payload-grafana.json"))
+	if err != nil {
+		t.Skipf("skipping test: could not read test-payload-grafana.json: %v", err)
+	}
+	return body
+}
+
+func TestParseGrafanaPayload(t *testing.T) {
+	payload, err := parseGrafanaPayload(readGrafanaPayload(t))
+	if err != nil {
+		t.Fatalf("parseGrafanaPayload() error = %v", err)
+	}
+
+	if payload.Status != "firing" || payload.Receiver != "servicenow-bridge" {
+		t.Errorf("unexpected payload header %+v", payload)
+	}
+	if len(payload.Alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(payload.Alerts))
+	}
+
+	alert := payload.Alerts[0]
+	if alert.Status != "firing" {
+		t.Errorf("expected status firing, got %q", alert.Status)
+	}
+	if alert.Labels["alertname"] != "HighRequestLatency" || alert.Labels["cluster"] != "production-cluster" {
+		t.Errorf("labels not mapped: %v", alert.Labels)
+	}
+	if alert.Annotations["summary"] != "p99 latency above 2s" {
+		t.Errorf("annotations not mapped: %v", alert.Annotations)
+	}
+	if alert.Annotations[annotationGrafanaDashboardURL] != "https://grafana.example.com/d/payments-api" {
+		t.Errorf("dashboard URL not kept, got %q", alert.Annotations[annotationGrafanaDashboardURL])
+	}
+	if alert.Annotations[annotationGrafanaPanelURL] != "https://grafana.example.com/d/payments-api?viewPanel=4" {
+		t.Errorf("panel URL not kept, got %q", alert.Annotations[annotationGrafanaPanelURL])
+	}
+	if !strings.Contains(alert.Annotations[annotationGrafanaValueString], "value=2.41") {
+		t.Errorf("value string not kept, got %q", alert.Annotations[annotationGrafanaValueString])
+	}
+	if alert.StartsAt.IsZero() {
+		t.Error("expected startsAt to be parsed")
+	}
+}
+
+func TestHandler_GrafanaWebhook(t *testing.T) {
+	body := readGrafanaPayload(t)
+
+	tests := []struct {
+		name           string
+		status         string
+		wantCreateLen  int
+		wantResolveLen int
+	}{
+		{name: "firing creates an incident", status: "firing", wantCreateLen: 1},
+		{name: "resolved resolves the incident", status: "resolved", wantResolveLen: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var foundID string
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					foundID = correlationID
+					if tt.status == "resolved" {
+						return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234", State: "2"}, nil
+					}
+					return nil, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			payloadBody := bytes.ReplaceAll(body, []byte(`"status": "firing"`), []byte(`"status": "`+tt.status+`"`))
+			req := httptest.NewRequest(http.MethodPost, "/grafana/webhook", bytes.NewReader(payloadBody))
+			rr := httptest.NewRecorder()
+
+			handler.GrafanaHandler().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			if len(mockClient.createCalls) != tt.wantCreateLen {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreateLen, len(mockClient.createCalls))
+			}
+			if len(mockClient.resolveCalls) != tt.wantResolveLen {
+				t.Errorf("expected %d ResolveIncident calls, got %d", tt.wantResolveLen, len(mockClient.resolveCalls))
+			}
+
+			wantID := GenerateCorrelationID("HighRequestLatency", map[string]string{
+				"alertname":      "HighRequestLatency",
+				"grafana_folder": "Payments",
+				"severity":       "critical",
+				"namespace":      "payments",
+				"cluster":        "production-cluster",
+			})
+			if foundID != wantID {
+				t.Errorf("expected correlation ID %q, got %q", wantID, foundID)
+			}
+
+			if tt.wantCreateLen == 1 {
+				incident := mockClient.createCalls[0]
+				if incident.ShortDescription != "[production-cluster] HighRequestLatency in namespace: payments" {
+					t.Errorf("unexpected short description %q", incident.ShortDescription)
+				}
+				for _, want := range []string{"Grafana:", "Dashboard: https://grafana.example.com/d/payments-api", "Values: [ var='B'"} {
+					if !strings.Contains(incident.Description, want) {
+						t.Errorf("description should contain %q, got:\n%s", want, incident.Description)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_GrafanaWebhook_RequiresAuth(t *testing.T) {
+	cfg := &config.Config{WebhookAuthToken: "secret-token"}
+	handler := NewHandler(cfg, &mockServiceNowClient{}, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/grafana/webhook", bytes.NewReader([]byte(`{"alerts":[]}`)))
+	rr := httptest.NewRecorder()
+
+	handler.GrafanaHandler().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
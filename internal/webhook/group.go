@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// alertGroup is the set of alerts in one webhook that share the values of
+// the GROUP_ALERTS_BY labels. Its alerts are already normalized.
+type alertGroup struct {
+	labels map[string]string
+	alerts []models.Alert
+}
+
+// GroupCorrelationID returns the correlation ID of the incident covering an
+// alert group, derived only from the group's label values so the same group
+// maps to the same incident regardless of its members.
+func (t *Transformer) GroupCorrelationID(groupLabels map[string]string) string {
+	return t.correlationID("group", groupLabels)
+}
+
+// groupAlerts partitions normalized alerts by the values of the configured
+// group labels, keeping groups in the order they first appear.
+func (t *Transformer) groupAlerts(alerts []models.Alert) []*alertGroup {
+	var groups []*alertGroup
+	byKey := make(map[string]*alertGroup)
+
+	for _, alert := range alerts {
+		labels := make(map[string]string, len(t.cfg.GroupAlertsBy))
+		for _, name := range t.cfg.GroupAlertsBy {
+			labels[name] = alert.Labels[name]
+		}
+
+		key := t.GroupCorrelationID(labels)
+		group, ok := byKey[key]
+		if !ok {
+			group = &alertGroup{labels: labels}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.alerts = append(group.alerts, alert)
+	}
+
+	return groups
+}
+
+// payloadJobs turns a whole webhook into one alert group for
+// GROUP_INTO_SINGLE_INCIDENT. The group is identified by Alertmanager's group
+// labels, or its groupKey when there are none, so the incident follows the
+// Alertmanager group as members come and go; common labels are the last
+// resort for senders that set neither. Digest alerts are still appended
+// individually.
+func (h *Handler) payloadJobs(ctx context.Context, payload *models.AlertmanagerPayload) []alertJob {
+	var jobs []alertJob
+	group := &alertGroup{labels: payload.GroupLabels}
+	switch {
+	case len(group.labels) > 0:
+	case payload.GroupKey != "":
+		group.labels = map[string]string{"group_key": payload.GroupKey}
+	default:
+		group.labels = payload.CommonLabels
+	}
+	if group.labels == nil {
+		group.labels = map[string]string{}
+	}
+
+	t := h.transformer(ctx)
+	for _, alert := range payload.Alerts {
+		normalized := t.Normalize(alert)
+		if t.IsDigestAlert(normalized) {
+			jobs = append(jobs, alertJob{alert: alert})
+			continue
+		}
+		group.alerts = append(group.alerts, normalized)
+	}
+	if len(group.alerts) > 0 {
+		jobs = append(jobs, alertJob{group: group})
+	}
+	return jobs
+}
+
+// TransformGroup builds one incident for the firing alerts of a group. Fields
+// not specific to a member, such as category and priority, come from the
+// first alert; the description lists every member. The short description
+// ends with the member count, which GROUP_ALERT_COUNT_FIELD also copies into
+// a field of its own.
+func (t *Transformer) TransformGroup(groupLabels map[string]string, firing []models.Alert, externalURL string) models.ServiceNowIncident {
+	first := firing[0]
+	incident := t.Transform(first, externalURL)
+
+	cluster := t.extractClusterName(first)
+	if cluster == "" {
+		cluster = "unknown-cluster"
+	}
+	alertname := groupLabels["alertname"]
+	if alertname == "" {
+		alertname = first.Labels["alertname"]
+	}
+
+	incident.ShortDescription = fmt.Sprintf("[%s] %s (%d alerts)",
+		t.applyCase("cluster", cluster), t.applyCase("alertname", alertname), len(firing))
+	incident.Description = t.truncateDescription(t.buildGroupDescription(groupLabels, firing, externalURL))
+	incident.CorrelationID = t.GroupCorrelationID(groupLabels)
+	// A group has no single fingerprint; keeping the first member's would
+	// let that alert's own resolve match the group incident.
+	if t.cfg.FingerprintField != "" {
+		delete(incident.ExtraFields, t.cfg.FingerprintField)
+	}
+	if t.cfg.GroupAlertCountField != "" {
+		incident.SetField(t.cfg.GroupAlertCountField, strconv.Itoa(len(firing)))
+	}
+	return incident
+}
+
+// buildGroupDescription lists the group labels followed by one line per
+// member with the labels that distinguish it from the rest of the group.
+func (t *Transformer) buildGroupDescription(groupLabels map[string]string, firing []models.Alert, externalURL string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("Grouped by: %s\n", formatLabels(groupLabels, nil)))
+	b.WriteString(fmt.Sprintf("Firing alerts: %d\n", len(firing)))
+
+	b.WriteString("\n--- Affected Resources ---\n")
+	for _, alert := range firing {
+		b.WriteString(fmt.Sprintf("- %s", formatLabels(alert.Labels, groupLabels)))
+		if summary := alert.Annotations["summary"]; summary != "" {
+			b.WriteString(fmt.Sprintf(": %s", summary))
+		}
+		b.WriteString("\n")
+	}
+
+	if externalURL != "" {
+		b.WriteString(fmt.Sprintf("\nAlertmanager: %s\n", externalURL))
+	}
+
+	return b.String()
+}
+
+// formatLabels renders labels as sorted key=value pairs, skipping those in exclude.
+func formatLabels(labels, exclude map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if _, skip := exclude[k]; !skip {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// processGroup handles an alert group as one unit and records how long it
+// took. Each member still counts toward the received alerts metric.
+func (h *Handler) processGroup(ctx context.Context, group *alertGroup, externalURL string) error {
+	start := time.Now()
+	for _, alert := range group.alerts {
+		h.metrics.AlertsReceived.WithLabelValues(alert.Status).Inc()
+	}
+
+	err := h.dispatchGroup(ctx, group, externalURL)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	h.metrics.AlertProcessingDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// dispatchGroup creates the group's incident while any member is firing and
+// resolves it once every member has resolved. Like dispatchAlert, it uses the
+// transformer in effect when it started.
+func (h *Handler) dispatchGroup(ctx context.Context, group *alertGroup, externalURL string) error {
+	ctx, t := h.withTransformer(ctx)
+	correlationID := t.GroupCorrelationID(group.labels)
+
+	unlock := h.locks.lock(correlationID)
+	defer unlock()
+
+	var firing, resolved []models.Alert
+	for _, alert := range group.alerts {
+		alert, ok := h.applySuppressedAction(ctx, alert)
+		if !ok || h.suppressedByLabel(ctx, alert) || h.filteredBySeverity(ctx, alert) {
+			continue
+		}
+		switch alert.Status {
+		case models.AlertStatusFiring:
+			firing = append(firing, alert)
+		case models.AlertStatusResolved:
+			resolved = append(resolved, alert)
+		default:
+			h.dropAlert(ctx, alert, dropUnknownStatus)
+		}
+	}
+
+	if len(firing) > 0 {
+		h.cancelPendingResolve(correlationID)
+		if h.dedup.recent(correlationID) {
+			h.log(ctx).Debug("skipping duplicate firing alert group",
+				"group", formatLabels(group.labels, nil),
+				"correlation_id", correlationID,
+			)
+			return nil
+		}
+		if err := h.handleFiringGroup(ctx, group.labels, firing, externalURL, correlationID); err != nil {
+			return err
+		}
+		h.dedup.record(correlationID)
+		return nil
+	}
+
+	if len(resolved) == 0 {
+		return nil
+	}
+	h.dedup.forget(correlationID)
+
+	// Resolve notes describe the member that resolved last.
+	last := resolved[0]
+	for _, alert := range resolved[1:] {
+		if alert.EndsAt.After(last.EndsAt) {
+			last = alert
+		}
+	}
+	return h.resolve(ctx, last, correlationID)
+}
+
+// handleFiringGroup creates the group's incident unless one is already open.
+func (h *Handler) handleFiringGroup(ctx context.Context, groupLabels map[string]string, firing []models.Alert, externalURL, correlationID string) error {
+	t := h.transformer(ctx)
+	group := formatLabels(groupLabels, nil)
+	tablePath := t.EndpointPath(firing[0])
+
+	h.log(ctx).Info("processing firing alert group",
+		"group", group,
+		"firing", len(firing),
+		"correlation_id", correlationID,
+	)
+
+	existing, err := h.snowClient.FindIncidentByCorrelationID(ctx, tablePath, correlationID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && h.isOpen(existing) {
+		recordAction(ctx, correlationID, actionAlreadyOpen, existing.Number)
+		h.log(ctx).Info("incident already open for alert group",
+			"group", group,
+			"correlation_id", correlationID,
+			"incident_number", existing.Number,
+		)
+		return nil
+	}
+	if h.reopenable(existing) {
+		note := fmt.Sprintf("Alert group fired again after being resolved (%d alerts firing)", len(firing))
+		return h.reopen(ctx, tablePath, correlationID, existing, note, "group", group)
+	}
+
+	alertname := groupLabels["alertname"]
+	if alertname == "" {
+		alertname = firing[0].Labels["alertname"]
+	}
+	if !h.limiter.allow(alertname) {
+		h.log(ctx).Warn("rate limited incident creation for alert group",
+			"group", group,
+			"correlation_id", correlationID,
+		)
+		return errRateLimited
+	}
+
+	result, err := h.snowClient.CreateIncident(ctx, tablePath, t.TransformGroup(groupLabels, firing, externalURL))
+	if err != nil {
+		return err
+	}
+	h.incidents.put(tablePath, correlationID, result.SysID, result.Number)
+	recordCreated(ctx, correlationID, result.Number)
+
+	h.log(ctx).Info("created incident for alert group in ServiceNow",
+		"group", group,
+		"firing", len(firing),
+		"correlation_id", correlationID,
+		"incident_number", result.Number,
+		"sys_id", result.SysID,
+		"incident_url", t.IncidentURL(tablePath, result.SysID, result.Number),
+	)
+
+	return nil
+}
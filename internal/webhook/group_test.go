@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func newGroupingHandler(mockClient *mockServiceNowClient) *Handler {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      2,
+		GroupAlertsBy:       []string{"alertname", "cluster"},
+		DigestSeverities:    []string{"info"},
+	}
+	return NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+}
+
+func podAlert(status, alertname, pod string) models.Alert {
+	return models.Alert{
+		Status: status,
+		Labels: map[string]string{
+			"alertname": alertname,
+			"cluster":   "prod",
+			"namespace": "apps",
+			"pod":       pod,
+			"severity":  "critical",
+		},
+	}
+}
+
+func TestHandler_GroupAlerts_OneIncidentPerGroup(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler := newGroupingHandler(mockClient)
+
+	sendAlerts(t, handler,
+		podAlert("firing", "KubePodCrashLooping", "web-1"),
+		podAlert("firing", "KubePodCrashLooping", "web-2"),
+		podAlert("firing", "KubePodCrashLooping", "web-3"),
+		podAlert("firing", "KubePodNotReady", "db-1"),
+	)
+
+	if len(mockClient.createCalls) != 2 {
+		t.Fatalf("expected 2 CreateIncident calls, got %d", len(mockClient.createCalls))
+	}
+
+	var crashLooping *models.ServiceNowIncident
+	for i := range mockClient.createCalls {
+		if strings.Contains(mockClient.createCalls[i].ShortDescription, "KubePodCrashLooping") {
+			crashLooping = &mockClient.createCalls[i]
+		}
+	}
+	if crashLooping == nil {
+		t.Fatal("expected an incident for the KubePodCrashLooping group")
+	}
+
+	if want := "[prod] KubePodCrashLooping (3 alerts)"; crashLooping.ShortDescription != want {
+		t.Errorf("ShortDescription = %q, want %q", crashLooping.ShortDescription, want)
+	}
+	for _, pod := range []string{"pod=web-1", "pod=web-2", "pod=web-3"} {
+		if !strings.Contains(crashLooping.Description, pod) {
+			t.Errorf("expected description to list %s, got:\n%s", pod, crashLooping.Description)
+		}
+	}
+	wantID := handler.transformer(context.Background()).GroupCorrelationID(map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod"})
+	if crashLooping.CorrelationID != wantID {
+		t.Errorf("CorrelationID = %q, want %q", crashLooping.CorrelationID, wantID)
+	}
+}
+
+func TestHandler_GroupAlerts_ResolvesWhenWholeGroupResolves(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler := newGroupingHandler(mockClient)
+
+	sendAlerts(t, handler,
+		podAlert("firing", "KubePodCrashLooping", "web-1"),
+		podAlert("firing", "KubePodCrashLooping", "web-2"),
+	)
+
+	// One member still firing: the incident stays open.
+	sendAlerts(t, handler,
+		podAlert("resolved", "KubePodCrashLooping", "web-1"),
+		podAlert("firing", "KubePodCrashLooping", "web-2"),
+	)
+	if len(mockClient.resolveCalls) != 0 {
+		t.Fatalf("expected no ResolveIncident call while a member fires, got %d", len(mockClient.resolveCalls))
+	}
+
+	sendAlerts(t, handler,
+		podAlert("resolved", "KubePodCrashLooping", "web-1"),
+		podAlert("resolved", "KubePodCrashLooping", "web-2"),
+	)
+	if len(mockClient.resolveCalls) != 1 {
+		t.Fatalf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveCalls))
+	}
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_GroupAlerts_DigestAlertsNotGrouped(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler := newGroupingHandler(mockClient)
+
+	info := podAlert("firing", "KubePodCrashLooping", "web-9")
+	info.Labels["severity"] = "info"
+
+	sendAlerts(t, handler,
+		podAlert("firing", "KubePodCrashLooping", "web-1"),
+		info,
+	)
+
+	if len(mockClient.createCalls) != 2 {
+		t.Fatalf("expected a group incident and a digest incident, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+	for _, incident := range mockClient.createCalls {
+		if strings.Contains(incident.ShortDescription, "KubePodCrashLooping") && strings.Contains(incident.Description, "web-9") {
+			t.Error("digest alert was included in the alert group")
+		}
+	}
+}
+
+// sendPayload posts a webhook payload to handler.
+func sendPayload(t *testing.T, handler *Handler, payload models.AlertmanagerPayload) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestHandler_GroupIntoSingleIncident(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:         "cluster",
+		EnvironmentLabelKey:     "environment",
+		WorkerPoolSize:          2,
+		GroupIntoSingleIncident: true,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	payload := func(alerts ...models.Alert) models.AlertmanagerPayload {
+		return models.AlertmanagerPayload{
+			Version:     "4",
+			GroupKey:    `{}:{namespace="apps"}`,
+			GroupLabels: map[string]string{"namespace": "apps"},
+			Alerts:      alerts,
+		}
+	}
+
+	sendPayload(t, handler, payload(
+		podAlert("firing", "KubePodCrashLooping", "web-1"),
+		podAlert("firing", "KubePodCrashLooping", "web-2"),
+		podAlert("firing", "KubePodNotReady", "db-1"),
+	))
+
+	if len(mockClient.createCalls) != 1 {
+		t.Fatalf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+	incident := mockClient.createCalls[0]
+	if want := "[prod] KubePodCrashLooping (3 alerts)"; incident.ShortDescription != want {
+		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, want)
+	}
+	for _, member := range []string{"pod=web-1", "pod=web-2", "alertname=KubePodNotReady"} {
+		if !strings.Contains(incident.Description, member) {
+			t.Errorf("expected description to list %s, got:\n%s", member, incident.Description)
+		}
+	}
+	if want := handler.transformer(context.Background()).GroupCorrelationID(map[string]string{"namespace": "apps"}); incident.CorrelationID != want {
+		t.Errorf("CorrelationID = %q, want %q", incident.CorrelationID, want)
+	}
+
+	// The incident is resolved only once every member has resolved.
+	sendPayload(t, handler, payload(
+		podAlert("resolved", "KubePodCrashLooping", "web-1"),
+		podAlert("resolved", "KubePodCrashLooping", "web-2"),
+		podAlert("firing", "KubePodNotReady", "db-1"),
+	))
+	if len(mockClient.resolveCalls) != 0 {
+		t.Fatalf("expected no ResolveIncident call while a member fires, got %d", len(mockClient.resolveCalls))
+	}
+	sendPayload(t, handler, payload(
+		podAlert("resolved", "KubePodCrashLooping", "web-1"),
+		podAlert("resolved", "KubePodCrashLooping", "web-2"),
+		podAlert("resolved", "KubePodNotReady", "db-1"),
+	))
+	if len(mockClient.resolveCalls) != 1 {
+		t.Errorf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveCalls))
+	}
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected no further CreateIncident calls, got %d", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_PayloadJobs_GroupIdentity(t *testing.T) {
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", GroupIntoSingleIncident: true}
+	handler := NewHandler(cfg, &mockServiceNowClient{}, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	alerts := []models.Alert{podAlert("firing", "KubePodCrashLooping", "web-1")}
+
+	tests := []struct {
+		name    string
+		payload models.AlertmanagerPayload
+		want    map[string]string
+	}{
+		{
+			name:    "group labels",
+			payload: models.AlertmanagerPayload{GroupKey: "key", GroupLabels: map[string]string{"alertname": "KubePodCrashLooping"}, Alerts: alerts},
+			want:    map[string]string{"alertname": "KubePodCrashLooping"},
+		},
+		{
+			name:    "group key without group labels",
+			payload: models.AlertmanagerPayload{GroupKey: "{}:{}", Alerts: alerts},
+			want:    map[string]string{"group_key": "{}:{}"},
+		},
+		{
+			name:    "common labels",
+			payload: models.AlertmanagerPayload{CommonLabels: map[string]string{"cluster": "prod"}, Alerts: alerts},
+			want:    map[string]string{"cluster": "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := handler.payloadJobs(context.Background(), &tt.payload)
+			if len(jobs) != 1 || jobs[0].group == nil {
+				t.Fatalf("expected one group job, got %+v", jobs)
+			}
+			if !reflect.DeepEqual(jobs[0].group.labels, tt.want) {
+				t.Errorf("group labels = %v, want %v", jobs[0].group.labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformer_TransformGroup_AlertCount(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:      "cluster",
+		EnvironmentLabelKey:  "environment",
+		GroupAlertCountField: "u_alert_count",
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	groupLabels := map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod"}
+	firing := []models.Alert{
+		podAlert("firing", "KubePodCrashLooping", "web-1"),
+		podAlert("firing", "KubePodCrashLooping", "web-2"),
+		podAlert("firing", "KubePodCrashLooping", "web-3"),
+	}
+	incident := transformer.TransformGroup(groupLabels, firing, "")
+
+	if !strings.HasSuffix(incident.ShortDescription, "(3 alerts)") {
+		t.Errorf("ShortDescription = %q, want the alert count", incident.ShortDescription)
+	}
+	raw, err := json.Marshal(incident)
+	if err != nil {
+		t.Fatalf("failed to marshal incident: %v", err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	if payload["u_alert_count"] != "3" {
+		t.Errorf("u_alert_count = %q, want %q", payload["u_alert_count"], "3")
+	}
+
+	cfg.GroupAlertCountField = ""
+	if _, ok := transformer.TransformGroup(groupLabels, firing, "").ExtraFields["u_alert_count"]; ok {
+		t.Error("expected no count field without GROUP_ALERT_COUNT_FIELD")
+	}
+}
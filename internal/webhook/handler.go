@@ -3,164 +3,836 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/logging"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 	"github.com/cragr/alert2snow-agent/internal/servicenow"
 )
 
 // ServiceNowClient defines the interface for ServiceNow operations.
+// Methods taking a path act on the table at that Table API path, or the
+// configured SERVICENOW_ENDPOINT_PATH when it is empty.
 type ServiceNowClient interface {
-	CreateIncident(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error)
-	FindIncidentByCorrelationID(ctx context.Context, correlationID string) (*models.ServiceNowResult, error)
-	ResolveIncident(ctx context.Context, sysID string) error
+	CreateIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error)
+	FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error)
+	FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error)
+	FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error)
+	ResolveIncident(ctx context.Context, path, sysID, closeNotes, workNotes string) error
+	ReopenIncident(ctx context.Context, path, sysID, note string) error
+	AddWorkNote(ctx context.Context, path, sysID, note string) error
+	FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error)
 }
 
 // Handler handles Alertmanager webhook requests.
 type Handler struct {
-	snowClient  ServiceNowClient
-	transformer *Transformer
-	logger      *slog.Logger
+	cfg        *config.Config
+	snowClient ServiceNowClient
+	metrics    *metrics.Metrics
+	now        func() time.Time
+	logger     *slog.Logger
+
+	// tr is the transformer in effect, replaced by SetTransformer.
+	tr atomic.Pointer[Transformer]
+
+	// locks serializes processing of alerts that share a correlation ID.
+	locks *correlationLocks
+	// dedup skips firing alerts already processed within cfg.DedupWindow.
+	dedup *dedupCache
+	// incidents caches the sys_id of created incidents for cfg.IncidentCacheTTL.
+	incidents *incidentCache
+	// pending holds resolves deferred by cfg.ResolveStabilization.
+	pending *pendingResolves
+	// limiter caps incident creates per alertname.
+	limiter *alertnameLimiter
+	// queue, if set, holds failed alerts for ReplayQueue.
+	queue *Queue
+	// deadLetter, if set, records alerts ServiceNow rejected.
+	deadLetter *DeadLetter
 }
 
 // NewHandler creates a new webhook handler.
-func NewHandler(snowClient ServiceNowClient, transformer *Transformer, logger *slog.Logger) *Handler {
-	return &Handler{
-		snowClient:  snowClient,
-		transformer: transformer,
-		logger:      logger,
+func NewHandler(cfg *config.Config, snowClient ServiceNowClient, transformer *Transformer, m *metrics.Metrics, logger *slog.Logger) *Handler {
+	h := &Handler{
+		cfg:        cfg,
+		snowClient: snowClient,
+		metrics:    m,
+		now:        time.Now,
+		logger:     logger,
+		locks:      newCorrelationLocks(),
+		dedup:      newDedupCache(cfg.DedupWindow),
+		incidents:  newIncidentCache(cfg.IncidentCacheTTL, cfg.IncidentCacheMaxSize),
+		pending:    newPendingResolves(cfg.ResolveStabilization),
+		limiter:    newAlertnameLimiter(cfg.PerAlertnameRateLimit),
 	}
+	h.tr.Store(transformer)
+	return h
+}
+
+// SetTransformer replaces the transformer, such as with one built from a
+// reloaded configuration. Alerts already being processed finish with the
+// one they started with.
+func (h *Handler) SetTransformer(t *Transformer) {
+	h.tr.Store(t)
+}
+
+type transformerKey struct{}
+
+// withTransformer returns a context pinned to the transformer in effect, so
+// everything done for one alert uses the same one even across a reload.
+func (h *Handler) withTransformer(ctx context.Context) (context.Context, *Transformer) {
+	t := h.transformer(ctx)
+	return context.WithValue(ctx, transformerKey{}, t), t
+}
+
+// transformer returns the transformer pinned to ctx, or the one in effect.
+func (h *Handler) transformer(ctx context.Context) *Transformer {
+	if t, ok := ctx.Value(transformerKey{}).(*Transformer); ok {
+		return t
+	}
+	return h.tr.Load()
 }
 
 // ServeHTTP handles incoming webhook requests from Alertmanager.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "alertmanager", parseAlertmanagerPayload)
+}
+
+// serve authenticates a webhook request, decodes its body with parse, and
+// processes the alerts. source names the sender in logs. Every log line for
+// the request carries its request_id, which is echoed in X-Request-ID.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, source string, parse payloadParser) {
+	id := requestID(r)
+	w.Header().Set(requestIDHeader, id)
+	logger := h.logger.With("request_id", id)
+
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	if !h.authorized(r) {
+		logger.Warn("rejected unauthorized webhook request", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if h.cfg.WebhookMaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(h.cfg.WebhookMaxBodyBytes))
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Error("failed to read request body", "error", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			logger.Warn("rejected oversized webhook request",
+				"remote_addr", r.RemoteAddr,
+				"limit_bytes", tooLarge.Limit,
+			)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		logger.Error("failed to read request body", "error", err)
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
-	var payload models.AlertmanagerPayload
-	if err := json.Unmarshal(body, &payload); err != nil {
-		h.logger.Error("failed to parse alertmanager payload", "error", err)
+	if !h.validSignature(r, body) {
+		logger.Warn("rejected webhook request with invalid signature", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := parse(body)
+	if err != nil {
+		logger.Error("failed to parse webhook payload", "source", source, "error", err)
 		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	h.logger.Info("received alertmanager webhook",
+	logger.Info("received webhook",
+		"source", source,
 		"alert_count", len(payload.Alerts),
 		"status", payload.Status,
 		"receiver", payload.Receiver,
 	)
 
-	ctx := r.Context()
-	var errCount int
+	ctx, created := withCreatedIncidents(logging.NewContext(r.Context(), logger))
+	var actions *reportActions
+	if h.cfg.ReportCallbackURL != "" {
+		ctx, actions = withReportActions(ctx)
+	}
+	received := len(payload.Alerts)
+	payload.Alerts = h.dropStaleAlerts(ctx, payload.Alerts)
+	// Bound processing so a slow ServiceNow can't hold the request open
+	// indefinitely; alerts not finished by the deadline count as failed.
+	if h.cfg.WebhookProcessTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.WebhookProcessTimeout)
+		defer cancel()
+	}
 
-	for _, alert := range payload.Alerts {
-		if err := h.processAlert(ctx, alert, payload.ExternalURL); err != nil {
-			h.logger.Error("failed to process alert",
-				"alertname", alert.Labels["alertname"],
-				"status", alert.Status,
-				"error", err,
-			)
-			errCount++
-		}
+	var results []alertResult
+	if h.cfg.GroupIntoSingleIncident {
+		results = h.processJobs(ctx, h.payloadJobs(ctx, payload), payload.ExternalURL)
+	} else {
+		results = h.processAlerts(ctx, payload.Alerts, payload.ExternalURL)
 	}
 
-	if errCount > 0 {
-		h.logger.Warn("some alerts failed to process",
+	if errCount := failedAlerts(results); errCount > 0 {
+		logger.Warn("some alerts failed to process",
 			"total", len(payload.Alerts),
 			"failed", errCount,
 		)
 	}
 
+	if numbers := created.header(); numbers != "" {
+		w.Header().Set(createdHeader, numbers)
+	}
+
+	if actions != nil {
+		report := newProcessingReport(results, actions.list())
+		report.RequestID = id
+		report.Source = source
+		report.Receiver = payload.Receiver
+		report.Status = payload.Status
+		report.Received = received
+		report.ProcessedAt = h.now().UTC()
+		go h.sendReport(report)
+	}
+
 	// Return 200 OK even if some alerts failed to prevent Alertmanager from retrying
 	// the entire batch. Individual failures are logged for investigation.
+	if !h.cfg.WebhookDetailedResponse {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok"}`))
+		return
+	}
+
+	response := detailedResponse{Status: "ok", Results: results}
+	if h.cfg.AlertTimeout > 0 {
+		response.AlertTimeout = h.cfg.AlertTimeout.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"ok"}`))
+	json.NewEncoder(w).Encode(response)
+}
+
+// detailedResponse is the webhook response body with WEBHOOK_DETAILED_RESPONSE.
+type detailedResponse struct {
+	Status       string        `json:"status"`
+	AlertTimeout string        `json:"alert_timeout,omitempty"`
+	Results      []alertResult `json:"results"`
+}
+
+// alertJob is one unit of work for the worker pool: a single alert, or a
+// group of alerts handled as one incident when GROUP_ALERTS_BY or
+// GROUP_INTO_SINGLE_INCIDENT is set.
+type alertJob struct {
+	alert models.Alert
+	group *alertGroup
+}
+
+// size returns the number of alerts the job covers.
+func (j alertJob) size() int {
+	if j.group != nil {
+		return len(j.group.alerts)
+	}
+	return 1
+}
+
+// buildJobs turns the alerts of a webhook into jobs. Without GROUP_ALERTS_BY
+// every alert is its own job; with it, alerts are grouped by the configured
+// labels, except digest alerts, which are always appended individually.
+func (h *Handler) buildJobs(ctx context.Context, alerts []models.Alert) []alertJob {
+	if len(h.cfg.GroupAlertsBy) == 0 {
+		jobs := make([]alertJob, len(alerts))
+		for i, alert := range alerts {
+			jobs[i] = alertJob{alert: alert}
+		}
+		return jobs
+	}
+
+	var jobs []alertJob
+	var grouped []models.Alert
+	t := h.transformer(ctx)
+	for _, alert := range alerts {
+		normalized := t.Normalize(alert)
+		if t.IsDigestAlert(normalized) {
+			jobs = append(jobs, alertJob{alert: alert})
+			continue
+		}
+		grouped = append(grouped, normalized)
+	}
+	for _, group := range t.groupAlerts(grouped) {
+		jobs = append(jobs, alertJob{group: group})
+	}
+	return jobs
+}
+
+// alertResult is the outcome of one job, reported in the response when
+// WEBHOOK_DETAILED_RESPONSE is set. DeadlineExceeded distinguishes alerts
+// that ran out of time (ALERT_TIMEOUT or WEBHOOK_PROCESS_TIMEOUT) from ones
+// ServiceNow rejected.
+type alertResult struct {
+	Alertname        string `json:"alertname"`
+	Status           string `json:"status"`
+	Alerts           int    `json:"alerts"`
+	Outcome          string `json:"outcome"`
+	Error            string `json:"error,omitempty"`
+	DeadlineExceeded bool   `json:"deadline_exceeded"`
 }
 
-// processAlert handles a single alert based on its status.
+// newAlertResult describes the outcome of job.
+func newAlertResult(job alertJob, err error) alertResult {
+	alert := job.alert
+	if job.group != nil {
+		alert = job.group.alerts[0]
+	}
+	result := alertResult{
+		Alertname: alert.Labels["alertname"],
+		Status:    alert.Status,
+		Alerts:    job.size(),
+		Outcome:   "success",
+	}
+	if err != nil {
+		result.Outcome = "error"
+		result.Error = err.Error()
+		result.DeadlineExceeded = errors.Is(err, context.DeadlineExceeded)
+	}
+	return result
+}
+
+// failedAlerts returns the number of alerts covered by failed results.
+func failedAlerts(results []alertResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Outcome != "success" {
+			failed += result.Alerts
+		}
+	}
+	return failed
+}
+
+// processAlerts fans the alerts out to a bounded pool of workers and returns
+// one result per job.
+func (h *Handler) processAlerts(ctx context.Context, alerts []models.Alert, externalURL string) []alertResult {
+	return h.processJobs(ctx, h.buildJobs(ctx, alerts), externalURL)
+}
+
+// processJobs runs pending on a bounded pool of workers and returns one
+// result per job. Each job gets at most ALERT_TIMEOUT. Jobs not yet
+// dispatched when ctx is cancelled are reported as failed. With
+// SERVICENOW_LOOKUP_BATCH_SIZE the incidents of resolved jobs are looked up
+// in bulk first.
+func (h *Handler) processJobs(ctx context.Context, pending []alertJob, externalURL string) []alertResult {
+	results := make([]alertResult, len(pending))
+	ctx = h.prefetchResolved(ctx, pending)
+
+	workers := min(h.cfg.WorkerPoolSize, len(pending))
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job := pending[i]
+				err := h.processJob(ctx, job, externalURL)
+				results[i] = newAlertResult(job, err)
+				if err == nil {
+					continue
+				}
+				if job.group != nil {
+					h.log(ctx).Error("failed to process alert group",
+						"group", formatLabels(job.group.labels, nil),
+						"alerts", job.size(),
+						"error", err,
+					)
+				} else {
+					h.log(ctx).Error("failed to process alert",
+						"alertname", job.alert.Labels["alertname"],
+						"status", job.alert.Status,
+						"error", err,
+					)
+				}
+				if queueable(err) {
+					h.enqueueJob(ctx, job, externalURL)
+				} else if deadLetterable(err) {
+					h.deadLetterJob(ctx, job, externalURL, err)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range pending {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			skipped := 0
+			for j := i; j < len(pending); j++ {
+				skipped += pending[j].size()
+				results[j] = newAlertResult(pending[j], ctx.Err())
+				h.enqueueJob(ctx, pending[j], externalURL)
+			}
+			h.log(ctx).Error("request cancelled before all alerts were processed",
+				"skipped", skipped,
+				"error", ctx.Err(),
+			)
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// processJob processes one job within ALERT_TIMEOUT, if set.
+func (h *Handler) processJob(ctx context.Context, job alertJob, externalURL string) error {
+	if h.cfg.AlertTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.AlertTimeout)
+		defer cancel()
+	}
+
+	if job.group != nil {
+		return h.processGroup(ctx, job.group, externalURL)
+	}
+	return h.processAlert(ctx, job.alert, externalURL)
+}
+
+// processAlert handles a single alert and records how long it took.
 func (h *Handler) processAlert(ctx context.Context, alert models.Alert, externalURL string) error {
+	start := time.Now()
+	h.metrics.AlertsReceived.WithLabelValues(alert.Status).Inc()
+
+	err := h.dispatchAlert(ctx, alert, externalURL)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	h.metrics.AlertProcessingDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// dispatchAlert handles a single alert, skipping firing alerts already
+// processed within the dedup window. The alert is processed with the
+// transformer in effect when it started.
+func (h *Handler) dispatchAlert(ctx context.Context, alert models.Alert, externalURL string) error {
+	ctx, t := h.withTransformer(ctx)
+	alert = t.Normalize(alert)
 	alertname := alert.Labels["alertname"]
-	correlationID := GenerateCorrelationID(alertname, alert.Labels)
+	if alertname == "" {
+		h.dropAlert(ctx, alert, dropMissingAlertname)
+		return nil
+	}
+	alert, ok := h.applySuppressedAction(ctx, alert)
+	if !ok {
+		return nil
+	}
+	if h.suppressedByLabel(ctx, alert) || h.filteredBySeverity(ctx, alert) {
+		return nil
+	}
+	correlationID := t.CorrelationID(alert)
+
+	// Overlapping webhooks can carry the same alert; hold the correlation
+	// lock across find/create/resolve so they can't both create an incident.
+	unlock := h.locks.lock(correlationID)
+	defer unlock()
+
+	switch alert.Status {
+	case models.AlertStatusFiring:
+		h.cancelPendingResolve(correlationID)
+		if h.dedup.recent(correlationID) {
+			h.log(ctx).Debug("skipping duplicate firing alert",
+				"alertname", alertname,
+				"correlation_id", correlationID,
+			)
+			return nil
+		}
+	case models.AlertStatusResolved:
+		h.dedup.forget(correlationID)
+	}
+
+	if err := h.routeAlert(ctx, alert, externalURL, correlationID); err != nil {
+		return err
+	}
+
+	if alert.Status == models.AlertStatusFiring {
+		h.dedup.record(correlationID)
+	}
+	return nil
+}
+
+// routeAlert sends an alert to the digest or to the create/resolve flow
+// based on its severity and status.
+func (h *Handler) routeAlert(ctx context.Context, alert models.Alert, externalURL, correlationID string) error {
+	if h.transformer(ctx).IsDigestAlert(alert) {
+		return h.handleDigestAlert(ctx, alert)
+	}
 
 	switch alert.Status {
 	case models.AlertStatusFiring:
 		return h.handleFiringAlert(ctx, alert, externalURL, correlationID)
 	case models.AlertStatusResolved:
-		return h.handleResolvedAlert(ctx, correlationID, alertname)
+		return h.resolve(ctx, alert, correlationID)
 	default:
-		h.logger.Warn("unknown alert status",
-			"alertname", alertname,
-			"status", alert.Status,
-		)
+		h.dropAlert(ctx, alert, dropUnknownStatus)
 		return nil
 	}
 }
 
-// handleFiringAlert creates a new incident in ServiceNow.
+// applySuppressedAction applies SUPPRESSED_ALERT_ACTION to a suppressed
+// alert: with "resolve" it is returned as resolved, with "ignore" ok is
+// false. Other alerts are returned unchanged.
+func (h *Handler) applySuppressedAction(ctx context.Context, alert models.Alert) (models.Alert, bool) {
+	if alert.Status != models.AlertStatusSuppressed {
+		return alert, true
+	}
+	if h.cfg.SuppressedAlertAction != config.SuppressedActionResolve {
+		h.log(ctx).Info("ignoring suppressed alert",
+			"alertname", alert.Labels["alertname"],
+		)
+		return alert, false
+	}
+	alert.Status = models.AlertStatusResolved
+	return alert, true
+}
+
+// Reasons an alert is dropped, used as the alerts_dropped_total label.
+const (
+	dropUnknownStatus    = "unknown_status"
+	dropMissingAlertname = "missing_alertname"
+	dropStale            = "stale"
+	dropSeverity         = "severity"
+)
+
+// filteredBySeverity drops a firing alert whose severity is not in
+// CREATE_FOR_SEVERITIES. Unlike other drops it is expected, so it is only
+// logged at debug. Resolves always go through, so incidents created before
+// the filter was set still resolve.
+func (h *Handler) filteredBySeverity(ctx context.Context, alert models.Alert) bool {
+	t := h.transformer(ctx)
+	if alert.Status != models.AlertStatusFiring || t.IsDigestAlert(alert) || t.CreatesIncident(alert) {
+		return false
+	}
+	h.metrics.AlertsDropped.WithLabelValues(dropSeverity).Inc()
+	h.log(ctx).Debug("dropping alert with severity not in CREATE_FOR_SEVERITIES",
+		"alertname", alert.Labels["alertname"],
+		"severity", t.Severity(alert),
+	)
+	return true
+}
+
+// suppressedByLabel drops a firing alert matching a SUPPRESS_IF_LABEL pair,
+// digest alerts included. Resolves go through, like with
+// CREATE_FOR_SEVERITIES.
+func (h *Handler) suppressedByLabel(ctx context.Context, alert models.Alert) bool {
+	if alert.Status != models.AlertStatusFiring {
+		return false
+	}
+	m, ok := h.transformer(ctx).SuppressingLabel(alert)
+	if !ok {
+		return false
+	}
+	h.metrics.AlertsSuppressed.WithLabelValues(m.Label + "=" + m.Value).Inc()
+	h.log(ctx).Debug("dropping alert matching SUPPRESS_IF_LABEL",
+		"alertname", alert.Labels["alertname"],
+		"label", m.Label,
+		"value", m.Value,
+	)
+	return true
+}
+
+// dropStaleAlerts returns the alerts no older than cfg.MaxPayloadAge, dropping
+// the rest. An alert's age is measured from EndsAt if it is resolved, since a
+// resolve is only stale once the condition cleared long ago, and from
+// StartsAt otherwise. Alerts without the timestamp are kept.
+func (h *Handler) dropStaleAlerts(ctx context.Context, alerts []models.Alert) []models.Alert {
+	if h.cfg.MaxPayloadAge <= 0 {
+		return alerts
+	}
+
+	now := h.now()
+	fresh := alerts[:0:0]
+	for _, alert := range alerts {
+		at := alert.StartsAt
+		if alert.Status == models.AlertStatusResolved {
+			at = alert.EndsAt
+		}
+		if !at.IsZero() && now.Sub(at) > h.cfg.MaxPayloadAge {
+			h.dropAlert(ctx, alert, dropStale)
+			continue
+		}
+		fresh = append(fresh, alert)
+	}
+	return fresh
+}
+
+// dropAlert logs and counts an alert that is ignored for reason.
+func (h *Handler) dropAlert(ctx context.Context, alert models.Alert, reason string) {
+	h.metrics.AlertsDropped.WithLabelValues(reason).Inc()
+	h.log(ctx).Warn("dropping alert",
+		"reason", reason,
+		"alertname", alert.Labels["alertname"],
+		"status", alert.Status,
+		"labels", alert.Labels,
+	)
+}
+
+// handleFiringAlert creates a new incident in ServiceNow unless one is
+// already open for the correlation ID or a configured parent alert's open
+// incident covers it.
 func (h *Handler) handleFiringAlert(ctx context.Context, alert models.Alert, externalURL, correlationID string) error {
+	t := h.transformer(ctx)
 	alertname := alert.Labels["alertname"]
+	tablePath := t.EndpointPath(alert)
 
-	h.logger.Info("processing firing alert",
+	h.log(ctx).Info("processing firing alert",
 		"alertname", alertname,
 		"correlation_id", correlationID,
 	)
 
-	incident := h.transformer.Transform(alert, externalURL)
+	existing, err := h.snowClient.FindIncidentByCorrelationID(ctx, tablePath, correlationID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && h.isOpen(existing) {
+		recordAction(ctx, correlationID, actionAlreadyOpen, existing.Number)
+		h.log(ctx).Info("incident already open for alert",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+			"incident_number", existing.Number,
+		)
+		return nil
+	}
+	if h.reopenable(existing) {
+		note := fmt.Sprintf("Alert fired again after being resolved:\n%s", t.AlertWorkNote(alert))
+		return h.reopen(ctx, tablePath, correlationID, existing, note, "alertname", alertname)
+	}
+
+	if suppressed, err := h.suppressUnderParent(ctx, alert, correlationID); err != nil || suppressed {
+		return err
+	}
+
+	if !h.limiter.allow(alertname) {
+		h.log(ctx).Warn("rate limited incident creation for alert",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+		)
+		return errRateLimited
+	}
+
+	incident := t.Transform(alert, externalURL)
 
-	result, err := h.snowClient.CreateIncident(ctx, incident)
+	result, err := h.snowClient.CreateIncident(ctx, tablePath, incident)
 	if err != nil {
 		return err
 	}
+	h.incidents.put(tablePath, correlationID, result.SysID, result.Number)
+	recordCreated(ctx, correlationID, result.Number)
 
-	h.logger.Info("created incident in ServiceNow",
+	h.log(ctx).Info("created incident in ServiceNow",
 		"alertname", alertname,
 		"correlation_id", correlationID,
 		"incident_number", result.Number,
 		"sys_id", result.SysID,
+		"incident_url", t.IncidentURL(tablePath, result.SysID, result.Number),
 	)
 
 	return nil
 }
 
+// cachedIncident takes the cached incident for correlationID in tablePath
+// out of the cache.
+func (h *Handler) cachedIncident(tablePath, correlationID string) (*models.ServiceNowResult, bool) {
+	entry, ok := h.incidents.take(tablePath, correlationID)
+	if !ok {
+		return nil, false
+	}
+	return &models.ServiceNowResult{SysID: entry.sysID, Number: entry.number}, true
+}
+
+// isOpen reports whether an incident is neither resolved nor closed, nor
+// in the state SERVICENOW_RESOLVED_STATE moves resolved incidents to.
+func (h *Handler) isOpen(incident *models.ServiceNowResult) bool {
+	switch incident.State {
+	case models.StateResolved, models.StateClosed:
+		return false
+	}
+	return h.cfg.ServiceNowResolvedState == "" || incident.State != h.cfg.ServiceNowResolvedState
+}
+
+// reopenable reports whether existing was resolved within cfg.ReopenWindow,
+// so a firing alert should reopen it rather than create a new incident.
+// Closed incidents and incidents without a resolved_at are never reopened.
+func (h *Handler) reopenable(existing *models.ServiceNowResult) bool {
+	if h.cfg.ReopenWindow <= 0 || existing == nil || existing.State != models.StateResolved {
+		return false
+	}
+	resolvedAt, ok := existing.ResolvedTime()
+	if !ok {
+		return false
+	}
+	return h.now().Sub(resolvedAt) <= h.cfg.ReopenWindow
+}
+
+// reopen moves a resolved incident in the table at tablePath back to in
+// progress with note as a work note. logAttrs identify the alert or group in
+// the log.
+func (h *Handler) reopen(ctx context.Context, tablePath, correlationID string, existing *models.ServiceNowResult, note string, logAttrs ...any) error {
+	if err := h.snowClient.ReopenIncident(ctx, tablePath, existing.SysID, note); err != nil {
+		return err
+	}
+	recordAction(ctx, correlationID, actionReopened, existing.Number)
+
+	h.log(ctx).Info("reopened recently resolved incident in ServiceNow",
+		append(logAttrs,
+			"correlation_id", correlationID,
+			"incident_number", existing.Number,
+			"sys_id", existing.SysID,
+			"resolved_at", existing.ResolvedAt,
+		)...,
+	)
+	return nil
+}
+
+// Outcomes counted in alert2snow_resolve_outcomes_total. Every resolve ends
+// in not_found, skipped, resolved or error; found also counts each resolve
+// whose incident was found, before it is skipped, resolved or fails.
+const (
+	resolveFound    = "found"
+	resolveNotFound = "not_found"
+	resolveSkipped  = "skipped"
+	resolveResolved = "resolved"
+	resolveError    = "error"
+)
+
+// countResolve records a resolve outcome.
+func (h *Handler) countResolve(outcome string) {
+	h.metrics.ResolveOutcomes.WithLabelValues(outcome).Inc()
+}
+
 // handleResolvedAlert resolves an existing incident in ServiceNow.
-func (h *Handler) handleResolvedAlert(ctx context.Context, correlationID, alertname string) error {
-	h.logger.Info("processing resolved alert",
+func (h *Handler) handleResolvedAlert(ctx context.Context, alert models.Alert, correlationID string) error {
+	t := h.transformer(ctx)
+	alertname := alert.Labels["alertname"]
+	tablePath := t.EndpointPath(alert)
+
+	h.log(ctx).Info("processing resolved alert",
 		"alertname", alertname,
 		"correlation_id", correlationID,
 	)
 
-	// Find existing incident by correlation ID
-	existing, err := h.snowClient.FindIncidentByCorrelationID(ctx, correlationID)
-	if err != nil {
-		return err
+	// An incident this replica created recently needs no lookup. It is
+	// assumed to still be open and ours; the entry is used once, so a
+	// duplicate or replayed resolve queries ServiceNow as usual.
+	existing, cached := h.cachedIncident(tablePath, correlationID)
+	if cached {
+		h.log(ctx).Debug("using cached incident for resolved alert",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+			"sys_id", existing.SysID,
+		)
+	} else if prefetched, ok := h.prefetchedIncident(ctx, tablePath, correlationID); ok {
+		existing = prefetched
+	} else {
+		// With FINGERPRINT_FIELD the stored fingerprint also matches, so
+		// incidents created before a change to how correlation IDs are
+		// derived still resolve.
+		var err error
+		existing, err = h.snowClient.FindIncidentByCorrelationIDOrField(ctx, tablePath, correlationID, h.cfg.FingerprintField, alert.Fingerprint)
+		if err != nil {
+			h.countResolve(resolveError)
+			return err
+		}
 	}
 
 	if existing == nil {
-		h.logger.Warn("no existing incident found for resolved alert",
+		h.countResolve(resolveNotFound)
+		h.metrics.ResolveNoMatch.WithLabelValues(alertname).Inc()
+		recordAction(ctx, correlationID, actionNotFound, "")
+		// A label that goes into the correlation ID and changed value since
+		// the alert fired (a restarted pod, say) points the resolve at an
+		// ID no incident was created with.
+		h.log(ctx).Warn("no existing incident found for resolved alert",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+			"labels", alert.Labels,
+			"correlation_labels", t.correlationLabels(alert),
+		)
+		return nil
+	}
+	h.countResolve(resolveFound)
+
+	// An incident another tool created with the same correlation ID is not
+	// ours to resolve.
+	if !cached && !t.Owns(existing) {
+		h.metrics.ForeignIncidents.Inc()
+		h.countResolve(resolveSkipped)
+		recordAction(ctx, correlationID, actionSkipped, existing.Number)
+		h.log(ctx).Warn("skipping resolve of incident not created by this agent",
 			"alertname", alertname,
 			"correlation_id", correlationID,
+			"incident_number", existing.Number,
+			"marker_field", h.cfg.IncidentMarkerField,
 		)
 		return nil
 	}
 
-	// Resolve the incident
-	if err := h.snowClient.ResolveIncident(ctx, existing.SysID); err != nil {
+	// Duplicate resolves are common; skip the no-op PATCH, which for a
+	// closed incident would also move it back to resolved.
+	if !h.isOpen(existing) {
+		h.metrics.AlreadyResolved.Inc()
+		h.countResolve(resolveSkipped)
+		recordAction(ctx, correlationID, actionSkipped, existing.Number)
+		h.log(ctx).Debug("incident already resolved",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+			"incident_number", existing.Number,
+			"state", existing.State,
+		)
+		return nil
+	}
+
+	notes, err := t.ResolveNotes(alert, correlationID, existing.Number)
+	if err != nil {
+		h.log(ctx).Warn("using default resolve notes",
+			"alertname", alertname,
+			"correlation_id", correlationID,
+			"error", err,
+		)
+	}
+
+	// Resolve the incident, noting the alert's latest annotations for
+	// whoever picks it up.
+	workNote := fmt.Sprintf("Alert resolved:\n%s", t.AlertWorkNote(alert))
+	if err := h.snowClient.ResolveIncident(ctx, tablePath, existing.SysID, notes, workNote); err != nil {
+		h.countResolve(resolveError)
 		return err
 	}
+	h.countResolve(resolveResolved)
+	recordAction(ctx, correlationID, actionResolved, existing.Number)
 
-	h.logger.Info("resolved incident in ServiceNow",
+	h.log(ctx).Info("resolved incident in ServiceNow",
 		"alertname", alertname,
 		"correlation_id", correlationID,
 		"sys_id", existing.SysID,
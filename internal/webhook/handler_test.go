@@ -3,16 +3,29 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 	"github.com/cragr/alert2snow-agent/internal/servicenow"
 )
@@ -21,14 +34,30 @@ import (
 type mockServiceNowClient struct {
 	createIncidentFn            func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error)
 	findIncidentByCorrelationFn func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error)
-	resolveIncidentFn           func(ctx context.Context, sysID string) error
+	findIncidentOrFieldFn       func(ctx context.Context, correlationID, field, value string) (*models.ServiceNowResult, error)
+	findIncidentsFn             func(ctx context.Context, ids []string) (map[string]*models.ServiceNowResult, error)
+	resolveIncidentFn           func(ctx context.Context, sysID, closeNotes string) error
+	findOpenByPrefixFn          func(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error)
 
+	mu           sync.Mutex
 	createCalls  []models.ServiceNowIncident
+	createPaths  []string
+	findPaths    []string
+	batchFinds   [][]string
 	resolveCalls []string
+	resolvePaths []string
+	resolveNotes []string
+	resolveWork  []string
+	reopenCalls  []string
+	reopenNotes  []string
+	workNotes    map[string][]string
 }
 
-func (m *mockServiceNowClient) CreateIncident(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+func (m *mockServiceNowClient) CreateIncident(ctx context.Context, path string, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+	m.mu.Lock()
 	m.createCalls = append(m.createCalls, incident)
+	m.createPaths = append(m.createPaths, path)
+	m.mu.Unlock()
 	if m.createIncidentFn != nil {
 		return m.createIncidentFn(ctx, incident)
 	}
@@ -39,25 +68,136 @@ func (m *mockServiceNowClient) CreateIncident(ctx context.Context, incident mode
 	}, nil
 }
 
-func (m *mockServiceNowClient) FindIncidentByCorrelationID(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+func (m *mockServiceNowClient) FindIncidentByCorrelationID(ctx context.Context, path, correlationID string) (*models.ServiceNowResult, error) {
+	m.mu.Lock()
+	m.findPaths = append(m.findPaths, path)
+	m.mu.Unlock()
 	if m.findIncidentByCorrelationFn != nil {
 		return m.findIncidentByCorrelationFn(ctx, correlationID)
 	}
 	return nil, nil
 }
 
-func (m *mockServiceNowClient) ResolveIncident(ctx context.Context, sysID string) error {
+func (m *mockServiceNowClient) FindIncidentByCorrelationIDOrField(ctx context.Context, path, correlationID, field, value string) (*models.ServiceNowResult, error) {
+	if m.findIncidentOrFieldFn == nil {
+		return m.FindIncidentByCorrelationID(ctx, path, correlationID)
+	}
+	m.mu.Lock()
+	m.findPaths = append(m.findPaths, path)
+	m.mu.Unlock()
+	return m.findIncidentOrFieldFn(ctx, correlationID, field, value)
+}
+
+func (m *mockServiceNowClient) FindIncidentsByCorrelationIDs(ctx context.Context, path string, ids []string) (map[string]*models.ServiceNowResult, error) {
+	m.mu.Lock()
+	m.batchFinds = append(m.batchFinds, ids)
+	m.mu.Unlock()
+	if m.findIncidentsFn != nil {
+		return m.findIncidentsFn(ctx, ids)
+	}
+	found := make(map[string]*models.ServiceNowResult)
+	for _, id := range ids {
+		if m.findIncidentByCorrelationFn == nil {
+			break
+		}
+		result, err := m.findIncidentByCorrelationFn(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			found[id] = result
+		}
+	}
+	return found, nil
+}
+
+func (m *mockServiceNowClient) ResolveIncident(ctx context.Context, path, sysID, closeNotes, workNotes string) error {
+	m.mu.Lock()
 	m.resolveCalls = append(m.resolveCalls, sysID)
+	m.resolvePaths = append(m.resolvePaths, path)
+	m.resolveNotes = append(m.resolveNotes, closeNotes)
+	m.resolveWork = append(m.resolveWork, workNotes)
+	m.mu.Unlock()
 	if m.resolveIncidentFn != nil {
-		return m.resolveIncidentFn(ctx, sysID)
+		return m.resolveIncidentFn(ctx, sysID, closeNotes)
+	}
+	return nil
+}
+
+func (m *mockServiceNowClient) ReopenIncident(ctx context.Context, path, sysID, note string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reopenCalls = append(m.reopenCalls, sysID)
+	m.reopenNotes = append(m.reopenNotes, note)
+	return nil
+}
+
+func (m *mockServiceNowClient) AddWorkNote(ctx context.Context, path, sysID, note string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.workNotes == nil {
+		m.workNotes = make(map[string][]string)
 	}
+	m.workNotes[sysID] = append(m.workNotes[sysID], note)
 	return nil
 }
 
+// newStatefulMock returns a mock that remembers created incidents so later
+// lookups by correlation ID find them, as ServiceNow would.
+func newStatefulMock() *mockServiceNowClient {
+	created := make(map[string]*models.ServiceNowResult)
+	m := &mockServiceNowClient{}
+	m.findIncidentByCorrelationFn = func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return created[correlationID], nil
+	}
+	m.createIncidentFn = func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		n := len(created) + 1
+		created[incident.CorrelationID] = &models.ServiceNowResult{
+			SysID:  fmt.Sprintf("sys%d", n),
+			Number: fmt.Sprintf("INC%07d", n),
+			State:  "1",
+		}
+		return &servicenow.CreateIncidentResult{SysID: fmt.Sprintf("sys%d", n), Number: fmt.Sprintf("INC%07d", n)}, nil
+	}
+	return m
+}
+
+func (m *mockServiceNowClient) FindOpenIncidentsByShortDescriptionPrefix(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error) {
+	if m.findOpenByPrefixFn != nil {
+		return m.findOpenByPrefixFn(ctx, prefix, limit)
+	}
+	return nil, nil
+}
+
 func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 }
 
+// histogramSampleCount returns the number of observations recorded for the
+// histogram with the given label values.
+func histogramSampleCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := h.WithLabelValues(labels...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// counterValue reads the current value of one series of a counter vector.
+func counterValue(t *testing.T, c *prometheus.CounterVec, labels ...string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.WithLabelValues(labels...).Write(&m); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
 func TestHandler_ServeHTTP_FiringAlert(t *testing.T) {
 	mockClient := &mockServiceNowClient{}
 	cfg := &config.Config{
@@ -66,8 +206,8 @@ func TestHandler_ServeHTTP_FiringAlert(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	payload := models.AlertmanagerPayload{
 		Version:  "4",
@@ -124,8 +264,8 @@ func TestHandler_ServeHTTP_ResolvedAlert(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	payload := models.AlertmanagerPayload{
 		Version:  "4",
@@ -165,6 +305,37 @@ func TestHandler_ServeHTTP_ResolvedAlert(t *testing.T) {
 	}
 }
 
+func TestHandler_ServeHTTP_ResolvedAlert_WorkNotes(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+			return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status: "resolved",
+		Labels: map[string]string{"alertname": "DiskFull", "cluster": "prod"},
+		Annotations: map[string]string{
+			"summary":     "Disk /var is 95% full",
+			"description": "Free space dropped below 5% on node-1",
+		},
+	})
+
+	if len(mockClient.resolveWork) != 1 {
+		t.Fatalf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveWork))
+	}
+	for _, want := range []string{"Alert resolved", "Summary: Disk /var is 95% full", "Description: Free space dropped below 5% on node-1"} {
+		if !strings.Contains(mockClient.resolveWork[0], want) {
+			t.Errorf("work notes %q missing %q", mockClient.resolveWork[0], want)
+		}
+	}
+}
+
 func TestHandler_ServeHTTP_ResolvedAlert_NoExistingIncident(t *testing.T) {
 	mockClient := &mockServiceNowClient{
 		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
@@ -177,8 +348,8 @@ func TestHandler_ServeHTTP_ResolvedAlert_NoExistingIncident(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	payload := models.AlertmanagerPayload{
 		Version: "4",
@@ -209,6 +380,641 @@ func TestHandler_ServeHTTP_ResolvedAlert_NoExistingIncident(t *testing.T) {
 	}
 }
 
+func TestHandler_ResolvedAlert_AlreadyResolved(t *testing.T) {
+	for _, state := range []string{models.StateResolved, models.StateClosed} {
+		t.Run("state "+state, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: state}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: "resolved",
+				Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+			})
+
+			if len(mockClient.resolveCalls) != 0 {
+				t.Errorf("expected no ResolveIncident calls, got %d", len(mockClient.resolveCalls))
+			}
+			var value dto.Metric
+			if err := m.AlreadyResolved.Write(&value); err != nil {
+				t.Fatal(err)
+			}
+			if got := value.GetCounter().GetValue(); got != 1 {
+				t.Errorf("incidents_already_resolved_total = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestHandler_ResolvedAlert_IncidentMarker(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		wantResolve bool
+	}{
+		{name: "marker present", fields: map[string]string{"u_source": "alert2snow-agent"}, wantResolve: true},
+		{name: "marker missing", fields: map[string]string{"u_source": ""}},
+		{name: "other tool's marker", fields: map[string]string{"u_source": "legacy-bridge"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1", Fields: tt.fields}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+				IncidentMarkerField: "u_source",
+				IncidentMarkerValue: "alert2snow-agent",
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: "resolved",
+				Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+			})
+
+			wantCalls, wantSkipped := 0, 1.0
+			if tt.wantResolve {
+				wantCalls, wantSkipped = 1, 0
+			}
+			if len(mockClient.resolveCalls) != wantCalls {
+				t.Errorf("expected %d ResolveIncident calls, got %d", wantCalls, len(mockClient.resolveCalls))
+			}
+			var value dto.Metric
+			if err := m.ForeignIncidents.Write(&value); err != nil {
+				t.Fatal(err)
+			}
+			if got := value.GetCounter().GetValue(); got != wantSkipped {
+				t.Errorf("foreign_incidents_skipped_total = %v, want %v", got, wantSkipped)
+			}
+		})
+	}
+}
+
+func TestHandler_ResolvedAlert_Outcomes(t *testing.T) {
+	open := &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1"}
+	tests := []struct {
+		name       string
+		find       func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error)
+		resolveErr error
+		want       map[string]float64
+	}{
+		{
+			name: "resolved",
+			find: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) { return open, nil },
+			want: map[string]float64{resolveFound: 1, resolveResolved: 1},
+		},
+		{
+			name: "not found",
+			find: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) { return nil, nil },
+			want: map[string]float64{resolveNotFound: 1},
+		},
+		{
+			name: "already resolved",
+			find: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+				return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: models.StateResolved}, nil
+			},
+			want: map[string]float64{resolveFound: 1, resolveSkipped: 1},
+		},
+		{
+			name: "lookup error",
+			find: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+				return nil, errors.New("lookup failed")
+			},
+			want: map[string]float64{resolveError: 1},
+		},
+		{
+			name:       "resolve error",
+			find:       func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) { return open, nil },
+			resolveErr: errors.New("resolve failed"),
+			want:       map[string]float64{resolveFound: 1, resolveError: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: tt.find,
+				resolveIncidentFn: func(ctx context.Context, sysID, closeNotes string) error {
+					return tt.resolveErr
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: "resolved",
+				Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+			})
+
+			for _, outcome := range []string{resolveFound, resolveNotFound, resolveSkipped, resolveResolved, resolveError} {
+				if got := counterValue(t, m.ResolveOutcomes, outcome); got != tt.want[outcome] {
+					t.Errorf("resolve_outcomes_total{outcome=%q} = %v, want %v", outcome, got, tt.want[outcome])
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ResolvedAlert_NoMatch(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+	resolved := func(alertname string) models.Alert {
+		return models.Alert{Status: "resolved", Labels: map[string]string{"alertname": alertname, "cluster": "test-cluster"}}
+	}
+	sendAlerts(t, handler, resolved("TestAlert"), resolved("OtherAlert"))
+	sendAlerts(t, handler, resolved("TestAlert"))
+
+	if got := counterValue(t, m.ResolveNoMatch, "TestAlert"); got != 2 {
+		t.Errorf("resolve_no_match_total{alertname=TestAlert} = %v, want 2", got)
+	}
+	if got := counterValue(t, m.ResolveNoMatch, "OtherAlert"); got != 1 {
+		t.Errorf("resolve_no_match_total{alertname=OtherAlert} = %v, want 1", got)
+	}
+	if len(mockClient.resolveCalls) != 0 {
+		t.Errorf("expected no ResolveIncident calls, got %d", len(mockClient.resolveCalls))
+	}
+}
+
+func TestHandler_CorrelationSourceFingerprint(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		CorrelationSource:   config.CorrelationSourceFingerprint,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighLatency", "value": "0.91"},
+		Fingerprint: "4f9a7c2e1b3d5a60",
+	})
+	sendAlerts(t, handler, models.Alert{
+		Status:      "resolved",
+		Labels:      map[string]string{"alertname": "HighLatency", "value": "0.12"},
+		Fingerprint: "4f9a7c2e1b3d5a60",
+	})
+
+	if len(mockClient.createCalls) != 1 || mockClient.createCalls[0].CorrelationID != "4f9a7c2e1b3d5a60" {
+		t.Fatalf("expected one incident keyed by the fingerprint, got %+v", mockClient.createCalls)
+	}
+	if len(mockClient.resolveCalls) != 1 {
+		t.Errorf("expected the resolve to match by fingerprint despite changed labels, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+	}
+}
+
+func TestHandler_ResolveByCorrelationIDOrFingerprint(t *testing.T) {
+	// The incident was created under an earlier correlation scheme, so
+	// only its stored fingerprint still matches.
+	stored := map[string]string{"u_alert_fingerprint": "4f9a7c2e1b3d5a60"}
+
+	tests := []struct {
+		name         string
+		fingerprint  string
+		wantResolved bool
+	}{
+		{name: "fingerprint matches", fingerprint: "4f9a7c2e1b3d5a60", wantResolved: true},
+		{name: "neither matches", fingerprint: "0000000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotField, gotValue string
+			mockClient := &mockServiceNowClient{
+				findIncidentOrFieldFn: func(ctx context.Context, correlationID, field, value string) (*models.ServiceNowResult, error) {
+					gotField, gotValue = field, value
+					if correlationID == "old-scheme-id" || stored[field] == value {
+						return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1"}, nil
+					}
+					return nil, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+				FingerprintField:    "u_alert_fingerprint",
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status:      "resolved",
+				Labels:      map[string]string{"alertname": "HighLatency"},
+				Fingerprint: tt.fingerprint,
+			})
+
+			if gotField != "u_alert_fingerprint" || gotValue != tt.fingerprint {
+				t.Errorf("find by %s=%q, want u_alert_fingerprint=%q", gotField, gotValue, tt.fingerprint)
+			}
+			if resolved := len(mockClient.resolveCalls) == 1; resolved != tt.wantResolved {
+				t.Errorf("resolved = %v, want %v", resolved, tt.wantResolved)
+			}
+		})
+	}
+
+	t.Run("correlation ID matches", func(t *testing.T) {
+		cfg := &config.Config{
+			ClusterLabelKey:     "cluster",
+			EnvironmentLabelKey: "environment",
+			WorkerPoolSize:      1,
+			FingerprintField:    "u_alert_fingerprint",
+		}
+		transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+		alert := models.Alert{
+			Status:      "resolved",
+			Labels:      map[string]string{"alertname": "HighLatency"},
+			Fingerprint: "0000000000000000",
+		}
+		currentID := transformer.CorrelationID(alert)
+		mockClient := &mockServiceNowClient{
+			findIncidentOrFieldFn: func(ctx context.Context, correlationID, field, value string) (*models.ServiceNowResult, error) {
+				if correlationID == currentID || stored[field] == value {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1"}, nil
+				}
+				return nil, nil
+			},
+		}
+		handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
+
+		sendAlerts(t, handler, alert)
+
+		if len(mockClient.resolveCalls) != 1 {
+			t.Errorf("expected the resolve to match by correlation ID, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+		}
+	})
+}
+
+func TestHandler_FingerprintField(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		FingerprintField:    "u_alert_fingerprint",
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighLatency"},
+		Fingerprint: "4f9a7c2e1b3d5a60",
+	})
+
+	if len(mockClient.createCalls) != 1 {
+		t.Fatalf("expected one incident, got %d", len(mockClient.createCalls))
+	}
+	if got := mockClient.createCalls[0].ExtraFields["u_alert_fingerprint"]; got != "4f9a7c2e1b3d5a60" {
+		t.Errorf("u_alert_fingerprint = %q, want the alert fingerprint", got)
+	}
+}
+
+func TestHandler_CorrelationSaltAnnotation(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:           "cluster",
+		EnvironmentLabelKey:       "environment",
+		WorkerPoolSize:            1,
+		CorrelationSaltAnnotation: "correlation_salt",
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	alert := func(status, salt string) models.Alert {
+		return models.Alert{
+			Status:      status,
+			Labels:      map[string]string{"alertname": "QueueBacklog", "cluster": "prod"},
+			Annotations: map[string]string{"correlation_salt": salt},
+		}
+	}
+
+	sendAlerts(t, handler, alert("firing", "ingest"), alert("firing", "billing"))
+	if len(mockClient.createCalls) != 2 {
+		t.Fatalf("expected 2 CreateIncident calls, got %d", len(mockClient.createCalls))
+	}
+
+	// Only the billing incident (created second, so sys2) is resolved.
+	sendAlerts(t, handler, alert("resolved", "billing"))
+	if want := []string{"sys2"}; !reflect.DeepEqual(mockClient.resolveCalls, want) {
+		t.Errorf("ResolveIncident calls = %v, want %v", mockClient.resolveCalls, want)
+	}
+}
+
+func TestHandler_ResolvedAlert_NoExistingIncident_LogsLabels(t *testing.T) {
+	mockClient := &mockServiceNowClient{}
+	cfg := &config.Config{
+		ClusterLabelKey:         "cluster",
+		EnvironmentLabelKey:     "environment",
+		WorkerPoolSize:          1,
+		CorrelationIgnoreLabels: []string{"pod"},
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), logger)
+
+	sendAlerts(t, handler, models.Alert{
+		Status: "resolved",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster", "pod": "api-7d9f-xk2p"},
+	})
+
+	var entry struct {
+		Msg               string            `json:"msg"`
+		Labels            map[string]string `json:"labels"`
+		CorrelationLabels map[string]string `json:"correlation_labels"`
+	}
+	found := false
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry.Msg == "no existing incident found for resolved alert" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("expected no-incident warning, got logs:\n%s", buf.String())
+	}
+	if entry.Labels["pod"] != "api-7d9f-xk2p" || entry.Labels["cluster"] != "test-cluster" {
+		t.Errorf("expected alert labels in warning, got %v", entry.Labels)
+	}
+	if _, ok := entry.CorrelationLabels["pod"]; ok || entry.CorrelationLabels["cluster"] != "test-cluster" {
+		t.Errorf("expected hashed labels without the ignored pod in warning, got %v", entry.CorrelationLabels)
+	}
+}
+
+func TestHandler_FiringAlert_ReopenWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		window     time.Duration
+		state      string
+		resolvedAt string
+		wantReopen bool
+	}{
+		{name: "resolved within window", window: 30 * time.Minute, state: models.StateResolved, resolvedAt: "2026-01-01 11:45:00", wantReopen: true},
+		{name: "resolved beyond window", window: 30 * time.Minute, state: models.StateResolved, resolvedAt: "2026-01-01 11:00:00"},
+		{name: "closed incident", window: 30 * time.Minute, state: models.StateClosed, resolvedAt: "2026-01-01 11:45:00"},
+		{name: "missing resolved_at", window: 30 * time.Minute, state: models.StateResolved},
+		{name: "disabled", state: models.StateResolved, resolvedAt: "2026-01-01 11:45:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: tt.state, ResolvedAt: tt.resolvedAt}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+				ReopenWindow:        tt.window,
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+			handler.now = func() time.Time { return now }
+
+			sendAlerts(t, handler, models.Alert{
+				Status: "firing",
+				Labels: map[string]string{"alertname": "Flapping", "cluster": "test-cluster"},
+			})
+
+			if gotReopen := len(mockClient.reopenCalls) == 1; gotReopen != tt.wantReopen {
+				t.Errorf("reopened = %v, want %v", gotReopen, tt.wantReopen)
+			}
+			if wantCreates := map[bool]int{true: 0, false: 1}[tt.wantReopen]; len(mockClient.createCalls) != wantCreates {
+				t.Errorf("expected %d CreateIncident calls, got %d", wantCreates, len(mockClient.createCalls))
+			}
+			if tt.wantReopen && !strings.Contains(mockClient.reopenNotes[0], "Flapping") {
+				t.Errorf("expected work note to describe the alert, got %q", mockClient.reopenNotes[0])
+			}
+		})
+	}
+}
+
+func TestHandler_DroppedAlerts(t *testing.T) {
+	tests := []struct {
+		name   string
+		alert  models.Alert
+		reason string
+	}{
+		{
+			name:   "unknown status",
+			alert:  models.Alert{Status: "fring", Labels: map[string]string{"alertname": "TestAlert"}},
+			reason: "unknown_status",
+		},
+		{
+			name:   "missing alertname",
+			alert:  models.Alert{Status: "firing", Labels: map[string]string{"cluster": "test-cluster"}},
+			reason: "missing_alertname",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, tt.alert)
+
+			if got := counterValue(t, m.AlertsDropped, tt.reason); got != 1 {
+				t.Errorf("alerts_dropped_total{reason=%q} = %v, want 1", tt.reason, got)
+			}
+			if len(mockClient.createCalls) != 0 || len(mockClient.resolveCalls) != 0 {
+				t.Errorf("expected dropped alert not to reach ServiceNow, got %d creates and %d resolves",
+					len(mockClient.createCalls), len(mockClient.resolveCalls))
+			}
+		})
+	}
+}
+
+func TestHandler_MaxPayloadAge(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+
+	tests := []struct {
+		name        string
+		maxAge      time.Duration
+		alert       models.Alert
+		wantDropped bool
+	}{
+		{
+			name:   "fresh firing alert",
+			maxAge: time.Hour,
+			alert:  models.Alert{Status: "firing", Labels: labels, StartsAt: now.Add(-30 * time.Minute)},
+		},
+		{
+			name:        "stale firing alert",
+			maxAge:      time.Hour,
+			alert:       models.Alert{Status: "firing", Labels: labels, StartsAt: now.Add(-2 * time.Hour)},
+			wantDropped: true,
+		},
+		{
+			name:   "recently resolved long-running alert",
+			maxAge: time.Hour,
+			alert:  models.Alert{Status: "resolved", Labels: labels, StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-time.Minute)},
+		},
+		{
+			name:        "stale resolved alert",
+			maxAge:      time.Hour,
+			alert:       models.Alert{Status: "resolved", Labels: labels, StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-2 * time.Hour)},
+			wantDropped: true,
+		},
+		{
+			name:   "alert without timestamps",
+			maxAge: time.Hour,
+			alert:  models.Alert{Status: "firing", Labels: labels},
+		},
+		{
+			name:  "disabled",
+			alert: models.Alert{Status: "firing", Labels: labels, StartsAt: now.Add(-48 * time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1"}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+				MaxPayloadAge:       tt.maxAge,
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+			handler.now = func() time.Time { return now }
+
+			sendAlerts(t, handler, tt.alert)
+
+			want := 0.0
+			if tt.wantDropped {
+				want = 1
+			}
+			if got := counterValue(t, m.AlertsDropped, "stale"); got != want {
+				t.Errorf("alerts_dropped_total{reason=\"stale\"} = %v, want %v", got, want)
+			}
+			if reached := len(mockClient.findPaths) > 0; reached == tt.wantDropped {
+				t.Errorf("alert reached ServiceNow = %v, want %v", reached, !tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestHandler_SuppressedAlert(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		wantResolve bool
+	}{
+		{name: "ignore", action: config.SuppressedActionIgnore},
+		{name: "resolve", action: config.SuppressedActionResolve, wantResolve: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "sys1", Number: "INC0000001", State: "1"}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:       "cluster",
+				EnvironmentLabelKey:   "environment",
+				WorkerPoolSize:        1,
+				SuppressedAlertAction: tt.action,
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: models.AlertStatusSuppressed,
+				Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+			})
+
+			if gotResolve := len(mockClient.resolveCalls) == 1; gotResolve != tt.wantResolve {
+				t.Errorf("resolved = %v, want %v", gotResolve, tt.wantResolve)
+			}
+			if len(mockClient.createCalls) != 0 {
+				t.Errorf("expected no CreateIncident calls, got %d", len(mockClient.createCalls))
+			}
+			if got := counterValue(t, m.AlertsDropped, "unknown_status"); got != 0 {
+				t.Errorf("suppressed alert counted as unknown status")
+			}
+		})
+	}
+}
+
+func TestHandler_TableLabel(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowTableLabel:   "snow_table",
+		ClusterLabelKey:        "cluster",
+		EnvironmentLabelKey:    "environment",
+		WorkerPoolSize:         1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	labels := map[string]string{"alertname": "CertRotationDue", "cluster": "prod", "snow_table": "change_request"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TargetDown", "cluster": "prod"}})
+
+	const changeRequest = "/api/now/table/change_request"
+	if want := []string{changeRequest, ""}; !reflect.DeepEqual(mockClient.createPaths, want) {
+		t.Errorf("create paths = %q, want %q", mockClient.createPaths, want)
+	}
+	if want := []string{changeRequest, changeRequest, ""}; !reflect.DeepEqual(mockClient.findPaths, want) {
+		t.Errorf("find paths = %q, want %q", mockClient.findPaths, want)
+	}
+	if want := []string{changeRequest}; !reflect.DeepEqual(mockClient.resolvePaths, want) {
+		t.Errorf("resolve paths = %q, want %q", mockClient.resolvePaths, want)
+	}
+}
+
 func TestHandler_ServeHTTP_InvalidJSON(t *testing.T) {
 	mockClient := &mockServiceNowClient{}
 	cfg := &config.Config{
@@ -217,8 +1023,8 @@ func TestHandler_ServeHTTP_InvalidJSON(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader([]byte("invalid json")))
 	rr := httptest.NewRecorder()
@@ -230,6 +1036,215 @@ func TestHandler_ServeHTTP_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestHandler_CreateForSeverities(t *testing.T) {
+	tests := []struct {
+		name        string
+		severity    string
+		wantCreates int
+		wantDropped float64
+	}{
+		{name: "excluded severity", severity: "info", wantCreates: 0, wantDropped: 1},
+		{name: "included severity", severity: "critical", wantCreates: 1},
+		{name: "case-insensitive match", severity: "Warning", wantCreates: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WorkerPoolSize:      1,
+				CreateForSeverities: []string{"critical", "warning"},
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, m, newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: models.AlertStatusFiring,
+				Labels: map[string]string{"alertname": "NodeInfo", "cluster": "prod", "severity": tt.severity},
+			})
+
+			if len(mockClient.createCalls) != tt.wantCreates {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreates, len(mockClient.createCalls))
+			}
+			if got := counterValue(t, m.AlertsDropped, "severity"); got != tt.wantDropped {
+				t.Errorf("alerts dropped for severity = %v, want %v", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateForSeverities_ResolvesExcluded(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+			return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		CreateForSeverities: []string{"critical"},
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	// An incident created before the filter was set still resolves.
+	sendAlerts(t, handler, models.Alert{
+		Status: models.AlertStatusResolved,
+		Labels: map[string]string{"alertname": "NodeInfo", "cluster": "prod", "severity": "info"},
+	})
+
+	if len(mockClient.resolveCalls) != 1 {
+		t.Errorf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveCalls))
+	}
+}
+
+func TestHandler_MaliciousFingerprint(t *testing.T) {
+	var correlationIDs []string
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, id string) (*models.ServiceNowResult, error) {
+			correlationIDs = append(correlationIDs, id)
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		CorrelationSource:   config.CorrelationSourceFingerprint,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status:      models.AlertStatusResolved,
+		Labels:      map[string]string{"alertname": "TestAlert", "cluster": "prod"},
+		Fingerprint: "x^ORcorrelation_idISNOTEMPTY",
+	})
+
+	if len(correlationIDs) != 1 {
+		t.Fatalf("expected 1 lookup, got %d", len(correlationIDs))
+	}
+	if strings.ContainsAny(correlationIDs[0], "^,=") {
+		t.Errorf("lookup used correlation ID %q, want the label hash", correlationIDs[0])
+	}
+}
+
+func TestHandler_SuppressIfLabel(t *testing.T) {
+	tests := []struct {
+		name           string
+		labels         map[string]string
+		wantCreates    int
+		wantSuppressed string
+	}{
+		{
+			name:           "dev environment",
+			labels:         map[string]string{"alertname": "PodCrashLooping", "cluster": "east", "environment": "dev"},
+			wantSuppressed: "environment=dev",
+		},
+		{
+			name:           "sandbox cluster",
+			labels:         map[string]string{"alertname": "PodCrashLooping", "cluster": "sandbox", "environment": "prod"},
+			wantSuppressed: "cluster=sandbox",
+		},
+		{
+			name:        "no match",
+			labels:      map[string]string{"alertname": "PodCrashLooping", "cluster": "east", "environment": "prod"},
+			wantCreates: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				SuppressIfLabel: []config.LabelMatcher{
+					{Label: "environment", Value: "dev"},
+					{Label: "cluster", Value: "sandbox"},
+				},
+			}
+			m := metrics.New()
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, m, newTestLogger()), m, newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{Status: models.AlertStatusFiring, Labels: tt.labels})
+
+			if len(mockClient.createCalls) != tt.wantCreates {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreates, len(mockClient.createCalls))
+			}
+			if tt.wantSuppressed != "" {
+				if got := counterValue(t, m.AlertsSuppressed, tt.wantSuppressed); got != 1 {
+					t.Errorf("alerts suppressed for %s = %v, want 1", tt.wantSuppressed, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ResolvedAlert_OpenAndResolvedIncidents(t *testing.T) {
+	// An older incident with the same correlation ID is still open although
+	// the newest one was resolved; the resolve must close the open one.
+	var patched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			patched = append(patched, r.URL.Path)
+			w.Write([]byte(`{"result":{}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(models.ServiceNowListResponse{Result: []models.ServiceNowResult{
+			{SysID: "sys-new", Number: "INC0002000", State: models.StateResolved},
+			{SysID: "sys-old", Number: "INC0001000", State: "2"},
+		}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ServiceNowBaseURL:      server.URL,
+		ServiceNowEndpointPath: "/api/now/table/incident",
+		ServiceNowUsername:     "testuser",
+		ServiceNowPassword:     "testpass",
+		ClusterLabelKey:        "cluster",
+		EnvironmentLabelKey:    "environment",
+	}
+	m := metrics.New()
+	client := servicenow.NewClient(cfg, m, newTestLogger())
+	handler := NewHandler(cfg, client, NewTransformer(cfg, m, newTestLogger()), m, newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status: models.AlertStatusResolved,
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "prod"},
+	})
+
+	if want := []string{"/api/now/table/incident/sys-old"}; !reflect.DeepEqual(patched, want) {
+		t.Errorf("resolved %v, want %v", patched, want)
+	}
+}
+
+func TestHandler_ResolvedState(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+			return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234", State: "8"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:         "cluster",
+		EnvironmentLabelKey:     "environment",
+		ServiceNowResolvedState: "8",
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "prod"}
+
+	// An incident already in the configured resolved state is not resolved
+	// again, and a firing alert gets a new incident.
+	sendAlerts(t, handler, models.Alert{Status: models.AlertStatusResolved, Labels: labels})
+	if len(mockClient.resolveCalls) != 0 {
+		t.Errorf("expected no ResolveIncident calls, got %d", len(mockClient.resolveCalls))
+	}
+	sendAlerts(t, handler, models.Alert{Status: models.AlertStatusFiring, Labels: labels})
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+}
+
 func TestHandler_ServeHTTP_MethodNotAllowed(t *testing.T) {
 	mockClient := &mockServiceNowClient{}
 	cfg := &config.Config{
@@ -238,8 +1253,8 @@ func TestHandler_ServeHTTP_MethodNotAllowed(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	req := httptest.NewRequest(http.MethodGet, "/alertmanager/webhook", nil)
 	rr := httptest.NewRecorder()
@@ -259,8 +1274,8 @@ func TestHandler_ServeHTTP_MultipleAlerts(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	payload := models.AlertmanagerPayload{
 		Version: "4",
@@ -296,6 +1311,43 @@ func TestHandler_ServeHTTP_MultipleAlerts(t *testing.T) {
 	}
 }
 
+func TestHandler_ServeHTTP_CreatedHeader(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		ServiceNowCategory:    "software",
+		ServiceNowSubcategory: "openshift",
+		WorkerPoolSize:        2,
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, newStatefulMock(), transformer, metrics.New(), newTestLogger())
+
+	send := func(alerts ...models.Alert) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.AlertmanagerPayload{Version: "4", Status: "firing", Alerts: alerts})
+		req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	firing := func(alertname string) models.Alert {
+		return models.Alert{Status: "firing", Labels: map[string]string{"alertname": alertname}}
+	}
+
+	rr := send(firing("Alert1"), firing("Alert2"))
+	if got, want := rr.Header().Get(createdHeader), "INC0000001,INC0000002"; got != want {
+		t.Errorf("%s = %q, want %q", createdHeader, got, want)
+	}
+	if got, want := rr.Body.String(), `{"status":"ok"}`; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	// Both incidents exist now, so nothing new is created.
+	rr = send(firing("Alert1"), firing("Alert2"))
+	if _, ok := rr.Header()[createdHeader]; ok {
+		t.Errorf("expected no %s header when nothing was created, got %q", createdHeader, rr.Header().Get(createdHeader))
+	}
+}
+
 // TestHandler_ServeHTTP_ResolvedPayloadFile tests using the test-payload-resolved.json file
 func TestHandler_ServeHTTP_ResolvedPayloadFile(t *testing.T) {
 	// Find the project root by looking for go.mod
@@ -339,8 +1391,8 @@ func TestHandler_ServeHTTP_ResolvedPayloadFile(t *testing.T) {
 		ServiceNowCategory:    "software",
 		ServiceNowSubcategory: "openshift",
 	}
-	transformer := NewTransformer(cfg)
-	handler := NewHandler(mockClient, transformer, newTestLogger())
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
 
 	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -385,3 +1437,680 @@ func findProjectRoot(t *testing.T) string {
 		dir = parent
 	}
 }
+
+func TestHandler_ServeHTTP_BearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authHeader    string
+		wantStatus    int
+		wantCreateLen int
+	}{
+		{
+			name:          "valid token",
+			authHeader:    "Bearer s3cret",
+			wantStatus:    http.StatusOK,
+			wantCreateLen: 1,
+		},
+		{
+			name:          "wrong token",
+			authHeader:    "Bearer wrong",
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+		{
+			name:          "missing header",
+			authHeader:    "",
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+		{
+			name:          "wrong scheme",
+			authHeader:    "Basic s3cret",
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WebhookAuthToken:    "s3cret",
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+			handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
+
+			payload := models.AlertmanagerPayload{
+				Version: "4",
+				Status:  "firing",
+				Alerts: []models.Alert{
+					{
+						Status: "firing",
+						Labels: map[string]string{"alertname": "TestAlert"},
+					},
+				},
+			}
+
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if len(mockClient.createCalls) != tt.wantCreateLen {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreateLen, len(mockClient.createCalls))
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_NoTokenConfigured(t *testing.T) {
+	mockClient := &mockServiceNowClient{}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
+
+	body := []byte(`{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"alertname":"TestAlert"}}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestHandler_ServeHTTP_HMACSignature(t *testing.T) {
+	body := []byte(`{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"alertname":"TestAlert"}}]}`)
+
+	mac := hmac.New(sha256.New, []byte("hmac-secret"))
+	mac.Write(body)
+	validSig := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name          string
+		signature     string
+		wantStatus    int
+		wantCreateLen int
+	}{
+		{
+			name:          "valid signature",
+			signature:     validSig,
+			wantStatus:    http.StatusOK,
+			wantCreateLen: 1,
+		},
+		{
+			name:          "valid signature with sha256 prefix",
+			signature:     "sha256=" + validSig,
+			wantStatus:    http.StatusOK,
+			wantCreateLen: 1,
+		},
+		{
+			name:          "signature over different body",
+			signature:     hex.EncodeToString(sha256.New().Sum(nil)),
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+		{
+			name:          "non-hex signature",
+			signature:     "not-hex",
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+		{
+			name:          "missing signature",
+			signature:     "",
+			wantStatus:    http.StatusUnauthorized,
+			wantCreateLen: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				WebhookHMACSecret:   "hmac-secret",
+				WebhookHMACHeader:   "X-Signature",
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+			handler := NewHandler(cfg, mockClient, transformer, metrics.New(), newTestLogger())
+
+			req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if len(mockClient.createCalls) != tt.wantCreateLen {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreateLen, len(mockClient.createCalls))
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_RecordsProcessingDuration(t *testing.T) {
+	failingID := GenerateCorrelationID("Alert2", map[string]string{"alertname": "Alert2"})
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+			if correlationID == failingID {
+				return nil, errors.New("servicenow unavailable")
+			}
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+	payload := models.AlertmanagerPayload{
+		Version: "4",
+		Alerts: []models.Alert{
+			{Status: "firing", Labels: map[string]string{"alertname": "Alert1"}},
+			{Status: "resolved", Labels: map[string]string{"alertname": "Alert2"}},
+		},
+	}
+
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if got := histogramSampleCount(t, m.AlertProcessingDuration, "success"); got != 1 {
+		t.Errorf("expected 1 success observation, got %d", got)
+	}
+	if got := histogramSampleCount(t, m.AlertProcessingDuration, "error"); got != 1 {
+		t.Errorf("expected 1 error observation, got %d", got)
+	}
+}
+
+func TestHandler_ServeHTTP_WorkerPool(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				cur := maxInFlight.Load()
+				if n <= cur || maxInFlight.CompareAndSwap(cur, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			if incident.ShortDescription == "[unknown-cluster] Fail" {
+				return nil, errors.New("create failed")
+			}
+			return &servicenow.CreateIncidentResult{SysID: "sys", Number: "INC"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      3,
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+	var alerts []models.Alert
+	for i := 0; i < 10; i++ {
+		name := "Ok"
+		if i%2 == 0 {
+			name = "Fail"
+		}
+		alerts = append(alerts, models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": name, "index": string(rune('a' + i))},
+		})
+	}
+
+	body, _ := json.Marshal(models.AlertmanagerPayload{Version: "4", Alerts: alerts})
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if len(mockClient.createCalls) != 10 {
+		t.Errorf("expected 10 CreateIncident calls, got %d", len(mockClient.createCalls))
+	}
+	if got := maxInFlight.Load(); got < 2 || got > 3 {
+		t.Errorf("expected between 2 and 3 concurrent creates, got %d", got)
+	}
+	if got := histogramSampleCount(t, m.AlertProcessingDuration, "error"); got != 5 {
+		t.Errorf("expected 5 failed alerts, got %d", got)
+	}
+}
+
+func TestHandler_ProcessAlerts_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			<-release
+			return nil, ctx.Err()
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	alerts := []models.Alert{
+		{Status: "firing", Labels: map[string]string{"alertname": "Alert1"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "Alert2"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "Alert3"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Cancel while the single worker is blocked on the first alert
+		for {
+			mockClient.mu.Lock()
+			started := len(mockClient.createCalls) > 0
+			mockClient.mu.Unlock()
+			if started {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		close(release)
+	}()
+
+	failed := failedAlerts(handler.processAlerts(ctx, alerts, ""))
+
+	if failed != 3 {
+		t.Errorf("expected all 3 alerts to be counted as failed, got %d", failed)
+	}
+}
+
+func TestHandler_ServeHTTP_ResolveNotes(t *testing.T) {
+	endsAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name: "default notes",
+			want: models.DefaultResolveNotes,
+		},
+		{
+			name:     "templated notes",
+			template: `{{.AlertName}} cleared at {{.ResolvedAt.Format "2006-01-02T15:04:05Z07:00"}} ({{.IncidentNumber}}, {{.CorrelationID}})`,
+			want:     "TestAlert cleared at 2026-03-04T05:06:07Z (INC0001234, ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var correlationID string
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, id string) (*models.ServiceNowResult, error) {
+					correlationID = id
+					return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:      "cluster",
+				EnvironmentLabelKey:  "environment",
+				ResolveNotesTemplate: tt.template,
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			payload := models.AlertmanagerPayload{
+				Version: "4",
+				Alerts: []models.Alert{
+					{
+						Status: "resolved",
+						Labels: map[string]string{"alertname": "TestAlert"},
+						EndsAt: endsAt,
+					},
+				},
+			}
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if len(mockClient.resolveNotes) != 1 {
+				t.Fatalf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveNotes))
+			}
+			want := tt.want
+			if tt.template != "" {
+				want += correlationID + ")"
+			}
+			if mockClient.resolveNotes[0] != want {
+				t.Errorf("resolve notes = %q, want %q", mockClient.resolveNotes[0], want)
+			}
+		})
+	}
+}
+
+func TestHandler_SetTransformer(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, id string) (*models.ServiceNowResult, error) {
+			return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	reloaded := *cfg
+	reloaded.ResolveNotesTemplate = "{{.AlertName}} cleared"
+	handler.SetTransformer(NewTransformer(&reloaded, metrics.New(), newTestLogger()))
+
+	sendAlerts(t, handler, models.Alert{
+		Status: "resolved",
+		Labels: map[string]string{"alertname": "TestAlert"},
+	})
+
+	if len(mockClient.resolveNotes) != 1 {
+		t.Fatalf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveNotes))
+	}
+	if want := "TestAlert cleared"; mockClient.resolveNotes[0] != want {
+		t.Errorf("resolve notes = %q, want %q", mockClient.resolveNotes[0], want)
+	}
+}
+
+func TestHandler_SetTransformer_DuringAlert(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:      "cluster",
+		EnvironmentLabelKey:  "environment",
+		ResolveNotesTemplate: "{{.AlertName}} resolved",
+	}
+	reloaded := *cfg
+	reloaded.ResolveNotesTemplate = "{{.AlertName}} cleared"
+
+	var handler *Handler
+	mockClient := &mockServiceNowClient{
+		findIncidentByCorrelationFn: func(ctx context.Context, id string) (*models.ServiceNowResult, error) {
+			handler.SetTransformer(NewTransformer(&reloaded, metrics.New(), newTestLogger()))
+			return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234"}, nil
+		},
+	}
+	handler = NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	sendAlerts(t, handler, models.Alert{
+		Status: "resolved",
+		Labels: map[string]string{"alertname": "TestAlert"},
+	})
+
+	if len(mockClient.resolveNotes) != 1 {
+		t.Fatalf("expected 1 ResolveIncident call, got %d", len(mockClient.resolveNotes))
+	}
+	if want := "TestAlert resolved"; mockClient.resolveNotes[0] != want {
+		t.Errorf("resolve notes = %q, want the alert to finish with the transformer it started with (%q)", mockClient.resolveNotes[0], want)
+	}
+}
+
+func TestHandler_ServeHTTP_ConcurrentDuplicateAlerts(t *testing.T) {
+	mockClient := newStatefulMock()
+	create := mockClient.createIncidentFn
+	mockClient.createIncidentFn = func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+		// Widen the window in which an unserialized second request would
+		// miss the incident and create its own
+		time.Sleep(20 * time.Millisecond)
+		return create(ctx, incident)
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      5,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+	}
+
+	body, _ := json.Marshal(models.AlertmanagerPayload{Version: "4", Alerts: []models.Alert{alert}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected 1 CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+	if n := handler.locks.len(); n != 0 {
+		t.Errorf("expected correlation locks to be cleaned up, %d remain", n)
+	}
+}
+
+func TestHandler_ServeHTTP_FiringAlert_ExistingIncident(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         string
+		wantCreateLen int
+	}{
+		{name: "open incident is reused", state: "2", wantCreateLen: 0},
+		{name: "resolved incident gets a new one", state: models.StateResolved, wantCreateLen: 1},
+		{name: "closed incident gets a new one", state: models.StateClosed, wantCreateLen: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockServiceNowClient{
+				findIncidentByCorrelationFn: func(ctx context.Context, correlationID string) (*models.ServiceNowResult, error) {
+					return &models.ServiceNowResult{SysID: "abc123", Number: "INC0001234", State: tt.state}, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			sendAlerts(t, handler, models.Alert{
+				Status: "firing",
+				Labels: map[string]string{"alertname": "TestAlert"},
+			})
+
+			if len(mockClient.createCalls) != tt.wantCreateLen {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreateLen, len(mockClient.createCalls))
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_BodyTooLarge(t *testing.T) {
+	mockClient := &mockServiceNowClient{}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		WebhookMaxBodyBytes: 64,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	body, _ := json.Marshal(models.AlertmanagerPayload{
+		Version: "4",
+		Alerts: []models.Alert{{
+			Status: "firing",
+			Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+		}},
+	})
+	if len(body) <= cfg.WebhookMaxBodyBytes {
+		t.Fatalf("test payload of %d bytes does not exceed the limit", len(body))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(mockClient.createCalls) != 0 {
+		t.Errorf("expected no CreateIncident calls, got %d", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_ServeHTTP_ProcessTimeout(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			// A ServiceNow that never answers
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		WorkerPoolSize:        1,
+		WebhookProcessTimeout: 20 * time.Millisecond,
+	}
+	m := metrics.New()
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), m, newTestLogger())
+
+	body, _ := json.Marshal(models.AlertmanagerPayload{
+		Version: "4",
+		Alerts: []models.Alert{{
+			Status: "firing",
+			Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+		}},
+	})
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		done <- rr.Code
+	}()
+
+	select {
+	case code := <-done:
+		if code != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return after the processing deadline")
+	}
+
+	if got := histogramSampleCount(t, m.AlertProcessingDuration, "error"); got != 1 {
+		t.Errorf("expected the timed-out alert to be recorded as an error, got %d", got)
+	}
+}
+
+func TestHandler_ServeHTTP_DetailedResponse_AlertTimeout(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			if strings.Contains(incident.ShortDescription, "SlowAlert") {
+				// A ServiceNow that never answers this create
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			if strings.Contains(incident.ShortDescription, "RejectedAlert") {
+				return nil, &servicenow.RetryableError{Err: errors.New("bad request"), StatusCode: http.StatusBadRequest}
+			}
+			return &servicenow.CreateIncidentResult{SysID: "sys1", Number: "INC0000001"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:         "cluster",
+		EnvironmentLabelKey:     "environment",
+		WorkerPoolSize:          3,
+		AlertTimeout:            20 * time.Millisecond,
+		WebhookDetailedResponse: true,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	alert := func(name string) models.Alert {
+		return models.Alert{Status: "firing", Labels: map[string]string{"alertname": name, "cluster": "test-cluster"}}
+	}
+	body, _ := json.Marshal(models.AlertmanagerPayload{
+		Version: "4",
+		Alerts:  []models.Alert{alert("SlowAlert"), alert("RejectedAlert"), alert("FastAlert")},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Status       string `json:"status"`
+		AlertTimeout string `json:"alert_timeout"`
+		Results      []struct {
+			Alertname        string `json:"alertname"`
+			Outcome          string `json:"outcome"`
+			Error            string `json:"error"`
+			DeadlineExceeded bool   `json:"deadline_exceeded"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response body %q: %v", rr.Body.String(), err)
+	}
+	if response.AlertTimeout != "20ms" {
+		t.Errorf("expected alert_timeout 20ms, got %q", response.AlertTimeout)
+	}
+	if len(response.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(response.Results))
+	}
+
+	want := []struct {
+		alertname        string
+		outcome          string
+		deadlineExceeded bool
+	}{
+		{"SlowAlert", "error", true},
+		{"RejectedAlert", "error", false},
+		{"FastAlert", "success", false},
+	}
+	for i, w := range want {
+		got := response.Results[i]
+		if got.Alertname != w.alertname || got.Outcome != w.outcome || got.DeadlineExceeded != w.deadlineExceeded {
+			t.Errorf("result %d = %+v, want alertname %s outcome %s deadline_exceeded %v", i, got, w.alertname, w.outcome, w.deadlineExceeded)
+		}
+	}
+	if response.Results[1].Error == "" {
+		t.Error("expected the rejected alert to report its error")
+	}
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// incidentCache remembers the sys_id of incidents this replica created
+// recently, so resolving them can skip the correlation ID lookup. Like
+// dedupCache it is per replica and best effort: a miss falls back to the
+// ServiceNow query.
+type incidentCache struct {
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[incidentCacheKey]cachedIncident
+}
+
+// incidentCacheKey scopes a correlation ID to the table its incident is in.
+type incidentCacheKey struct {
+	tablePath     string
+	correlationID string
+}
+
+// cachedIncident is a created incident and when it was recorded.
+type cachedIncident struct {
+	sysID  string
+	number string
+	at     time.Time
+}
+
+// newIncidentCache creates a cache holding up to maxSize entries for ttl; a
+// zero ttl disables it.
+func newIncidentCache(ttl time.Duration, maxSize int) *incidentCache {
+	return &incidentCache{
+		ttl:     ttl,
+		maxSize: max(maxSize, 1),
+		now:     time.Now,
+		entries: make(map[incidentCacheKey]cachedIncident),
+	}
+}
+
+// take removes and returns the cached incident for correlationID in
+// tablePath, if it was recorded within the TTL.
+func (c *incidentCache) take(tablePath, correlationID string) (cachedIncident, bool) {
+	if c.ttl <= 0 {
+		return cachedIncident{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := incidentCacheKey{tablePath, correlationID}
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedIncident{}, false
+	}
+	delete(c.entries, key)
+	return entry, c.now().Sub(entry.at) < c.ttl
+}
+
+// put records a created incident. When the cache is full, expired entries
+// are pruned and, if that frees nothing, the oldest entry is evicted.
+func (c *incidentCache) put(tablePath, correlationID, sysID, number string) {
+	if c.ttl <= 0 || sysID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key := incidentCacheKey{tablePath, correlationID}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxSize {
+		c.evictLocked(now)
+	}
+	c.entries[key] = cachedIncident{sysID: sysID, number: number, at: now}
+}
+
+// evictLocked prunes expired entries, or the oldest one if none expired.
+func (c *incidentCache) evictLocked(now time.Time) {
+	var oldest incidentCacheKey
+	var oldestAt time.Time
+	pruned := false
+	for key, entry := range c.entries {
+		if now.Sub(entry.at) >= c.ttl {
+			delete(c.entries, key)
+			pruned = true
+			continue
+		}
+		if oldestAt.IsZero() || entry.at.Before(oldestAt) {
+			oldest, oldestAt = key, entry.at
+		}
+	}
+	if !pruned {
+		delete(c.entries, oldest)
+	}
+}
+
+// len returns the number of entries, including expired ones not yet pruned.
+func (c *incidentCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestIncidentCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newIncidentCache(10*time.Minute, 100)
+	cache.now = func() time.Time { return now }
+
+	if _, ok := cache.take("", "a"); ok {
+		t.Fatal("unrecorded id returned from cache")
+	}
+
+	cache.put("", "a", "sys1", "INC0000001")
+	if _, ok := cache.take("/api/now/table/change_request", "a"); ok {
+		t.Error("entry returned for another table")
+	}
+	entry, ok := cache.take("", "a")
+	if !ok || entry.sysID != "sys1" || entry.number != "INC0000001" {
+		t.Errorf("take() = %+v, %v, want sys1/INC0000001", entry, ok)
+	}
+	if _, ok := cache.take("", "a"); ok {
+		t.Error("entry returned twice")
+	}
+
+	cache.put("", "b", "sys2", "INC0000002")
+	now = now.Add(10 * time.Minute)
+	if _, ok := cache.take("", "b"); ok {
+		t.Error("entry recorded 10m ago should have expired")
+	}
+
+	cache.put("", "c", "", "INC0000003")
+	if n := cache.len(); n != 0 {
+		t.Errorf("expected incidents without a sys_id to be skipped, %d cached", n)
+	}
+}
+
+func TestIncidentCache_Bounded(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newIncidentCache(time.Hour, 3)
+	cache.now = func() time.Time { return now }
+
+	for i := range 5 {
+		cache.put("", fmt.Sprint(i), fmt.Sprintf("sys%d", i), "")
+		now = now.Add(time.Second)
+	}
+	if n := cache.len(); n != 3 {
+		t.Fatalf("len() = %d, want 3", n)
+	}
+	for i, want := range []bool{false, false, true, true, true} {
+		if _, ok := cache.take("", fmt.Sprint(i)); ok != want {
+			t.Errorf("entry %d cached = %v, want %v (oldest evicted first)", i, ok, want)
+		}
+	}
+}
+
+func TestIncidentCache_Concurrent(t *testing.T) {
+	cache := newIncidentCache(time.Hour, 50)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 200 {
+				id := fmt.Sprintf("%d-%d", i, j)
+				cache.put("", id, "sys-"+id, "")
+				cache.take("", id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := cache.len(); n > 50 {
+		t.Errorf("len() = %d, want at most 50", n)
+	}
+}
+
+func TestHandler_IncidentCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		wantFinds int
+	}{
+		{name: "resolve skips lookup", ttl: time.Minute, wantFinds: 1},
+		{name: "disabled", wantFinds: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := newStatefulMock()
+			cfg := &config.Config{
+				ClusterLabelKey:      "cluster",
+				EnvironmentLabelKey:  "environment",
+				WorkerPoolSize:       1,
+				IncidentCacheTTL:     tt.ttl,
+				IncidentCacheMaxSize: 10,
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			sendAlerts(t, handler, podAlert(models.AlertStatusFiring, "HighCPU", "a"))
+			sendAlerts(t, handler, podAlert(models.AlertStatusResolved, "HighCPU", "a"))
+
+			if len(mockClient.findPaths) != tt.wantFinds {
+				t.Errorf("expected %d lookups, got %d", tt.wantFinds, len(mockClient.findPaths))
+			}
+			if len(mockClient.resolveCalls) != 1 || mockClient.resolveCalls[0] != "sys1" {
+				t.Errorf("expected sys1 to be resolved, got %v", mockClient.resolveCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import "sync"
+
+// correlationLocks serializes work per correlation ID so overlapping webhook
+// requests for the same alert can't both create an incident. Entries are
+// reference counted and removed once no goroutine holds or waits on them.
+type correlationLocks struct {
+	mu    sync.Mutex
+	locks map[string]*correlationLock
+}
+
+type correlationLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newCorrelationLocks() *correlationLocks {
+	return &correlationLocks{locks: make(map[string]*correlationLock)}
+}
+
+// lock blocks until the caller holds the lock for id and returns the function
+// that releases it.
+func (l *correlationLocks) lock(id string) (unlock func()) {
+	l.mu.Lock()
+	cl, ok := l.locks[id]
+	if !ok {
+		cl = &correlationLock{}
+		l.locks[id] = cl
+	}
+	cl.refs++
+	l.mu.Unlock()
+
+	cl.Lock()
+
+	return func() {
+		cl.Unlock()
+
+		l.mu.Lock()
+		cl.refs--
+		if cl.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// len returns the number of correlation IDs currently locked or waited on.
+func (l *correlationLocks) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
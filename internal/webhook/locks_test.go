@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCorrelationLocks(t *testing.T) {
+	locks := newCorrelationLocks()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		active  int
+		overlap bool
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock("same-id")
+			defer unlock()
+
+			mu.Lock()
+			active++
+			if active > 1 {
+				overlap = true
+			}
+			mu.Unlock()
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if overlap {
+		t.Error("expected holders of the same correlation ID to be serialized")
+	}
+	if n := locks.len(); n != 0 {
+		t.Errorf("expected all entries to be removed, %d remain", n)
+	}
+
+	// Different IDs don't block each other
+	unlockA := locks.lock("a")
+	unlockB := locks.lock("b")
+	if n := locks.len(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+	unlockA()
+	unlockB()
+	if n := locks.len(); n != 0 {
+		t.Errorf("expected all entries to be removed, %d remain", n)
+	}
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// prefetchedIncidents holds the incidents of one webhook's resolved alerts,
+// looked up in bulk with SERVICENOW_LOOKUP_BATCH_SIZE. A nil incident means
+// the lookup found none. Each entry is used once, so a duplicate resolve in
+// the same webhook sees the state the first one left.
+type prefetchedIncidents struct {
+	mu      sync.Mutex
+	entries map[incidentCacheKey]*models.ServiceNowResult
+}
+
+type prefetchedIncidentsKey struct{}
+
+// take removes and returns the prefetched incident for correlationID in
+// tablePath. ok is false if it wasn't looked up.
+func (p *prefetchedIncidents) take(tablePath, correlationID string) (incident *models.ServiceNowResult, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := incidentCacheKey{tablePath, correlationID}
+	incident, ok = p.entries[key]
+	delete(p.entries, key)
+	return incident, ok
+}
+
+// prefetchResolved looks up the incidents of the resolved jobs in pending
+// with one correlation_idIN query per table and batch, and returns a context
+// carrying them for handleResolvedAlert. Nothing is looked up unless
+// SERVICENOW_LOOKUP_BATCH_SIZE is set and at least two jobs resolve; if a
+// batch fails, its alerts are looked up individually as usual.
+func (h *Handler) prefetchResolved(ctx context.Context, pending []alertJob) context.Context {
+	if h.cfg.ServiceNowLookupBatchSize <= 0 {
+		return ctx
+	}
+
+	t := h.transformer(ctx)
+	prefetched := &prefetchedIncidents{entries: make(map[incidentCacheKey]*models.ServiceNowResult)}
+	byTable := make(map[string][]string)
+	var tables []string
+	wanted := make(map[incidentCacheKey]bool)
+	add := func(alert models.Alert, correlationID string) {
+		// IN lists are comma-separated, so such IDs can't be batched.
+		if strings.Contains(correlationID, ",") {
+			return
+		}
+		key := incidentCacheKey{t.EndpointPath(alert), correlationID}
+		if wanted[key] {
+			return
+		}
+		wanted[key] = true
+		if _, ok := byTable[key.tablePath]; !ok {
+			tables = append(tables, key.tablePath)
+		}
+		byTable[key.tablePath] = append(byTable[key.tablePath], correlationID)
+	}
+
+	for _, job := range pending {
+		if job.group != nil {
+			if allResolved(job.group.alerts) {
+				add(job.group.alerts[0], t.GroupCorrelationID(job.group.labels))
+			}
+			continue
+		}
+		alert := t.Normalize(job.alert)
+		if alert.Status != models.AlertStatusResolved || alert.Labels["alertname"] == "" || t.IsDigestAlert(alert) {
+			continue
+		}
+		add(alert, t.CorrelationID(alert))
+	}
+	if len(wanted) < 2 {
+		return ctx
+	}
+
+	for _, tablePath := range tables {
+		ids := byTable[tablePath]
+		found, err := h.snowClient.FindIncidentsByCorrelationIDs(ctx, tablePath, ids)
+		if err != nil {
+			h.log(ctx).Warn("batched incident lookup failed, looking up resolved alerts individually",
+				"correlation_ids", len(ids),
+				"error", err,
+			)
+			continue
+		}
+		for _, id := range ids {
+			prefetched.entries[incidentCacheKey{tablePath, id}] = found[id]
+		}
+	}
+
+	return context.WithValue(ctx, prefetchedIncidentsKey{}, prefetched)
+}
+
+// prefetchedIncident takes the incident prefetched for correlationID in
+// tablePath out of ctx. With FINGERPRINT_FIELD a correlation ID that matched
+// nothing is not conclusive, since the fingerprint may still match, so it
+// counts as not prefetched.
+func (h *Handler) prefetchedIncident(ctx context.Context, tablePath, correlationID string) (*models.ServiceNowResult, bool) {
+	prefetched, ok := ctx.Value(prefetchedIncidentsKey{}).(*prefetchedIncidents)
+	if !ok {
+		return nil, false
+	}
+	incident, ok := prefetched.take(tablePath, correlationID)
+	if incident == nil && h.cfg.FingerprintField != "" {
+		return nil, false
+	}
+	return incident, ok
+}
+
+// allResolved reports whether every alert has resolved.
+func allResolved(alerts []models.Alert) bool {
+	for _, alert := range alerts {
+		if alert.Status != models.AlertStatusResolved {
+			return false
+		}
+	}
+	return len(alerts) > 0
+}
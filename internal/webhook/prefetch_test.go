@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestHandler_LookupBatchSize(t *testing.T) {
+	alerts := func(status string) []models.Alert {
+		var out []models.Alert
+		for _, pod := range []string{"a", "b", "c"} {
+			out = append(out, models.Alert{
+				Status: status,
+				Labels: map[string]string{"alertname": "PodDown", "cluster": "prod", "pod": pod},
+			})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name            string
+		batchErr        error
+		wantBatchFinds  int
+		wantSingleFinds int
+	}{
+		{name: "resolved alerts looked up in one query", wantBatchFinds: 1, wantSingleFinds: 0},
+		{name: "failed batch falls back to single lookups", batchErr: errors.New("boom"), wantBatchFinds: 1, wantSingleFinds: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := newStatefulMock()
+			if tt.batchErr != nil {
+				mockClient.findIncidentsFn = func(ctx context.Context, ids []string) (map[string]*models.ServiceNowResult, error) {
+					return nil, tt.batchErr
+				}
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:           "cluster",
+				EnvironmentLabelKey:       "environment",
+				WorkerPoolSize:            2,
+				ServiceNowLookupBatchSize: 10,
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			sendAlerts(t, handler, alerts(models.AlertStatusFiring)...)
+			firingFinds := len(mockClient.findPaths)
+
+			sendAlerts(t, handler, alerts(models.AlertStatusResolved)...)
+
+			if len(mockClient.batchFinds) != tt.wantBatchFinds {
+				t.Fatalf("got %d batched lookups, want %d", len(mockClient.batchFinds), tt.wantBatchFinds)
+			}
+			if got := len(mockClient.batchFinds[0]); got != 3 {
+				t.Errorf("batched lookup had %d correlation IDs, want 3", got)
+			}
+			if got := len(mockClient.findPaths) - firingFinds; got != tt.wantSingleFinds {
+				t.Errorf("got %d single lookups, want %d", got, tt.wantSingleFinds)
+			}
+			if len(mockClient.resolveCalls) != 3 {
+				t.Errorf("got %d resolves, want 3", len(mockClient.resolveCalls))
+			}
+		})
+	}
+}
+
+func TestHandler_LookupBatchSize_DuplicateResolve(t *testing.T) {
+	mockClient := newStatefulMock()
+	cfg := &config.Config{
+		ClusterLabelKey:           "cluster",
+		EnvironmentLabelKey:       "environment",
+		WorkerPoolSize:            1,
+		ServiceNowLookupBatchSize: 10,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	alert := models.Alert{Labels: map[string]string{"alertname": "PodDown", "cluster": "prod"}}
+
+	alert.Status = models.AlertStatusFiring
+	sendAlerts(t, handler, alert)
+	firingFinds := len(mockClient.findPaths)
+
+	alert.Status = models.AlertStatusResolved
+	other := models.Alert{Status: models.AlertStatusResolved, Labels: map[string]string{"alertname": "Other", "cluster": "prod"}}
+	sendAlerts(t, handler, alert, alert, other)
+
+	if len(mockClient.batchFinds) != 1 || len(mockClient.batchFinds[0]) != 2 {
+		t.Fatalf("batched lookups = %v, want one with 2 correlation IDs", mockClient.batchFinds)
+	}
+	// The prefetched incident serves the first resolve only; the duplicate
+	// queries ServiceNow itself.
+	if got := len(mockClient.findPaths) - firingFinds; got != 1 {
+		t.Errorf("got %d single lookups, want 1", got)
+	}
+}
@@ -0,0 +1,455 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+	"github.com/cragr/alert2snow-agent/internal/servicenow"
+)
+
+// queueFileName is the file inside QUEUE_DIR holding queued work.
+const queueFileName = "queue.jsonl"
+
+// queueEntry is an alert, or an alert group, whose processing failed and is
+// waiting to be replayed.
+type queueEntry struct {
+	Seq         uint64            `json:"seq"`
+	Alerts      []models.Alert    `json:"alerts"`
+	Group       bool              `json:"group,omitempty"`
+	GroupLabels map[string]string `json:"group_labels,omitempty"`
+	ExternalURL string            `json:"external_url,omitempty"`
+	EnqueuedAt  time.Time         `json:"enqueued_at"`
+}
+
+// Queue is a bounded, file-backed FIFO of failed alert work. Entries are
+// appended to a JSON-lines file as they arrive; the file is rewritten when
+// entries are removed or the oldest are dropped to stay within the bound.
+type Queue struct {
+	path    string
+	maxSize int
+	metrics *metrics.Metrics
+
+	mu      sync.Mutex
+	entries []queueEntry
+	nextSeq uint64
+	file    *os.File
+	// resolves holds the resolves handled per correlation ID, so replay can
+	// drop firing work a resolve overtook while it was queued.
+	resolves map[string]resolveMark
+}
+
+// resolveMark is the endsAt of a handled resolve and when it was handled.
+type resolveMark struct {
+	endsAt time.Time
+	at     time.Time
+}
+
+// resolveMarkGrace is how long a resolve is remembered beyond the oldest
+// queued entry, covering work that failed before the resolve but was queued
+// after it.
+const resolveMarkGrace = 5 * time.Minute
+
+// OpenQueue opens the queue in dir, creating the directory if needed and
+// loading entries left by a previous run. Lines that can't be decoded, such
+// as one cut short by a crash, are skipped.
+func OpenQueue(dir string, maxSize int, m *metrics.Metrics) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &Queue{
+		path:    filepath.Join(dir, queueFileName),
+		maxSize: max(maxSize, 1),
+		metrics: m,
+	}
+
+	data, err := os.ReadFile(q.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		var entry queueEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		q.entries = append(q.entries, entry)
+		q.nextSeq = max(q.nextSeq, entry.Seq+1)
+	}
+	if len(q.entries) > q.maxSize {
+		q.metrics.QueueDropped.Add(float64(len(q.entries) - q.maxSize))
+		q.entries = q.entries[len(q.entries)-q.maxSize:]
+	}
+
+	if err := q.rewriteLocked(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// push appends an entry, dropping the oldest entries beyond the bound. It
+// returns how many entries were dropped.
+func (q *Queue) push(entry queueEntry) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry.Seq = q.nextSeq
+	q.nextSeq++
+	q.entries = append(q.entries, entry)
+
+	if dropped := len(q.entries) - q.maxSize; dropped > 0 {
+		q.entries = q.entries[dropped:]
+		q.metrics.QueueDropped.Add(float64(dropped))
+		return dropped, q.rewriteLocked()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal queue entry: %w", err)
+	}
+	q.metrics.QueueDepth.Set(float64(len(q.entries)))
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write queue entry: %w", err)
+	}
+	return 0, q.file.Sync()
+}
+
+// peek returns the oldest entry.
+func (q *Queue) peek() (queueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return queueEntry{}, false
+	}
+	return q.entries[0], true
+}
+
+// remove deletes the entry with the given sequence number, if it is still
+// queued; it may have been dropped while it was being replayed.
+func (q *Queue) remove(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, entry := range q.entries {
+		if entry.Seq == seq {
+			q.entries = append(q.entries[:i:i], q.entries[i+1:]...)
+			return q.rewriteLocked()
+		}
+	}
+	return nil
+}
+
+// noteResolve records that a resolve that ended at endsAt was handled for
+// correlationID at now.
+func (q *Queue) noteResolve(correlationID string, endsAt, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.resolves == nil {
+		q.resolves = make(map[string]resolveMark)
+	}
+	if mark, ok := q.resolves[correlationID]; !ok || endsAt.After(mark.endsAt) {
+		q.resolves[correlationID] = resolveMark{endsAt: endsAt, at: now}
+	}
+}
+
+// resolvedAfter reports whether a resolve handled for correlationID ended
+// after startsAt, that is, it resolved an alert that started then.
+func (q *Queue) resolvedAfter(correlationID string, startsAt time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	mark, ok := q.resolves[correlationID]
+	return ok && startsAt.Before(mark.endsAt)
+}
+
+// pruneResolves forgets resolves handled more than resolveMarkGrace before
+// the oldest queued entry, or before now if the queue is empty.
+func (q *Queue) pruneResolves(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) > 0 && q.entries[0].EnqueuedAt.Before(now) {
+		now = q.entries[0].EnqueuedAt
+	}
+	cutoff := now.Add(-resolveMarkGrace)
+	for id, mark := range q.resolves {
+		if mark.at.Before(cutoff) {
+			delete(q.resolves, id)
+		}
+	}
+}
+
+// Len returns the number of queued entries.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// Close closes the queue file. Queued entries stay on disk.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// rewriteLocked replaces the queue file with the current entries, through a
+// temporary file so a crash leaves either the old or the new contents, and
+// reopens it for appending.
+func (q *Queue) rewriteLocked() error {
+	q.metrics.QueueDepth.Set(float64(len(q.entries)))
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range q.entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal queue entry: %w", err)
+		}
+	}
+
+	tmp := q.path + ".tmp"
+	if err := writeFileSync(tmp, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	if q.file != nil {
+		q.file.Close()
+		q.file = nil
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to replace queue: %w", err)
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open queue: %w", err)
+	}
+	q.file = file
+	return nil
+}
+
+// writeFileSync writes data to name and flushes it to disk.
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SetQueue makes the handler queue alerts whose processing fails with a
+// retryable error, for ReplayQueue to process later. It must be called
+// before the handler serves requests.
+func (h *Handler) SetQueue(q *Queue) {
+	h.queue = q
+}
+
+// queueable reports whether work that failed with err is worth replaying:
+// ServiceNow was unreachable or returned 5xx/429, or the request ran out of
+// time or was cancelled. Other failures would fail the same way again, and
+// rate-limited alerts are retried by Alertmanager, so neither is queued.
+func queueable(err error) bool {
+	if err == nil || errors.Is(err, errRateLimited) {
+		return false
+	}
+	return servicenow.IsUnavailable(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// enqueue queues failed work if a queue is configured. groupLabels is nil
+// unless the alerts form a group.
+func (h *Handler) enqueue(ctx context.Context, alerts []models.Alert, groupLabels map[string]string, externalURL string) {
+	if h.queue == nil {
+		return
+	}
+
+	dropped, err := h.queue.push(queueEntry{
+		Alerts:      alerts,
+		Group:       groupLabels != nil,
+		GroupLabels: groupLabels,
+		ExternalURL: externalURL,
+		EnqueuedAt:  h.now().UTC(),
+	})
+	if err != nil {
+		h.log(ctx).Error("failed to persist queued alert", "error", err)
+	}
+	if dropped > 0 {
+		h.log(ctx).Warn("queue full, dropped oldest entries",
+			"dropped", dropped,
+			"max_size", h.queue.maxSize,
+		)
+	}
+	h.log(ctx).Info("queued alert for replay",
+		"alertname", alerts[0].Labels["alertname"],
+		"alerts", len(alerts),
+		"depth", h.queue.Len(),
+	)
+}
+
+// noteResolve tells the queue, if one is set, that a resolve for
+// correlationID was handled. Resolves without an endsAt are not recorded.
+func (h *Handler) noteResolve(alert models.Alert, correlationID string) {
+	if h.queue == nil || alert.EndsAt.IsZero() {
+		return
+	}
+	h.queue.noteResolve(correlationID, alert.EndsAt, h.now())
+}
+
+// resolvedSinceQueued reports whether a resolve was handled for entry's
+// firing alerts, one that ended after they started, so replaying the entry
+// would create an incident nothing resolves. Alerts without a startsAt are
+// always replayed.
+func (h *Handler) resolvedSinceQueued(ctx context.Context, entry queueEntry) bool {
+	t := h.transformer(ctx)
+	if entry.Group {
+		var startsAt time.Time
+		for _, alert := range entry.Alerts {
+			if alert.Status != models.AlertStatusFiring {
+				continue
+			}
+			if alert.StartsAt.IsZero() {
+				return false
+			}
+			startsAt = maxTime(startsAt, alert.StartsAt)
+		}
+		return !startsAt.IsZero() && h.queue.resolvedAfter(t.GroupCorrelationID(entry.GroupLabels), startsAt)
+	}
+
+	alert := t.Normalize(entry.Alerts[0])
+	if alert.Status != models.AlertStatusFiring || alert.StartsAt.IsZero() {
+		return false
+	}
+	return h.queue.resolvedAfter(t.CorrelationID(alert), alert.StartsAt)
+}
+
+// maxTime returns the later of a and b.
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// enqueueJob queues a failed worker pool job.
+func (h *Handler) enqueueJob(ctx context.Context, job alertJob, externalURL string) {
+	if job.group != nil {
+		h.enqueue(ctx, job.group.alerts, job.group.labels, externalURL)
+		return
+	}
+	h.enqueue(ctx, []models.Alert{job.alert}, nil, externalURL)
+}
+
+// queueReplayTimeout bounds the replay of a single queue entry.
+const queueReplayTimeout = 30 * time.Second
+
+// ReplayQueue replays queued alerts every QUEUE_REPLAY_INTERVAL until ctx is
+// cancelled. It returns immediately if no queue is set.
+func (h *Handler) ReplayQueue(ctx context.Context) {
+	if h.queue == nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.cfg.QueueReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.replayQueue(ctx)
+		}
+	}
+}
+
+// replayQueue processes queued entries oldest first and returns how many it
+// replayed. It stops at the first retryable failure, leaving that entry and
+// those after it for the next round; entries that fail for other reasons are
+// dropped, as are firing alerts resolved since they were queued.
+func (h *Handler) replayQueue(ctx context.Context) int {
+	defer h.queue.pruneResolves(h.now())
+
+	replayed := 0
+	for ctx.Err() == nil {
+		entry, ok := h.queue.peek()
+		if !ok {
+			break
+		}
+
+		if h.resolvedSinceQueued(ctx, entry) {
+			h.logger.Info("dropping queued firing alert resolved since it was queued",
+				"alertname", entry.Alerts[0].Labels["alertname"],
+				"alerts", len(entry.Alerts),
+			)
+			if err := h.queue.remove(entry.Seq); err != nil {
+				h.logger.Error("failed to persist queue", "error", err)
+			}
+			continue
+		}
+
+		err := h.replayEntry(ctx, entry)
+		if queueable(err) {
+			h.logger.Warn("queue replay failed, will retry",
+				"depth", h.queue.Len(),
+				"error", err,
+			)
+			break
+		}
+		if err != nil {
+			h.logger.Error("dropping queued alert that cannot be processed",
+				"alertname", entry.Alerts[0].Labels["alertname"],
+				"error", err,
+			)
+			if deadLetterable(err) {
+				h.sendToDeadLetter(ctx, entry.Alerts, entry.GroupLabels, entry.ExternalURL, err)
+			}
+		} else {
+			replayed++
+		}
+		if err := h.queue.remove(entry.Seq); err != nil {
+			h.logger.Error("failed to persist queue", "error", err)
+		}
+	}
+
+	if replayed > 0 {
+		h.logger.Info("replayed queued alerts",
+			"replayed", replayed,
+			"depth", h.queue.Len(),
+		)
+	}
+	return replayed
+}
+
+// replayEntry processes one queue entry.
+func (h *Handler) replayEntry(ctx context.Context, entry queueEntry) error {
+	if len(entry.Alerts) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queueReplayTimeout)
+	defer cancel()
+
+	if entry.Group {
+		return h.dispatchGroup(ctx, &alertGroup{labels: entry.GroupLabels, alerts: entry.Alerts}, entry.ExternalURL)
+	}
+	return h.dispatchAlert(ctx, entry.Alerts[0], entry.ExternalURL)
+}
@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+	"github.com/cragr/alert2snow-agent/internal/servicenow"
+)
+
+// metricValue reads the value of a single gauge or counter.
+func metricValue(t *testing.T, metric prometheus.Metric) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("failed to read metric: %v", err)
+	}
+	if m.Gauge != nil {
+		return m.GetGauge().GetValue()
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestQueue_BoundedAndPersistent(t *testing.T) {
+	dir := t.TempDir()
+	m := metrics.New()
+
+	q, err := OpenQueue(dir, 2, m)
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	for _, name := range []string{"First", "Second", "Third"} {
+		alert := models.Alert{Status: "firing", Labels: map[string]string{"alertname": name}}
+		if _, err := q.push(queueEntry{Alerts: []models.Alert{alert}}); err != nil {
+			t.Fatalf("push() error = %v", err)
+		}
+	}
+
+	if q.Len() != 2 {
+		t.Fatalf("expected queue bounded to 2 entries, got %d", q.Len())
+	}
+	if got := metricValue(t, m.QueueDropped); got != 1 {
+		t.Errorf("expected 1 dropped entry, got %v", got)
+	}
+	if got := metricValue(t, m.QueueDepth); got != 2 {
+		t.Errorf("expected depth gauge 2, got %v", got)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// A crash mid-write can leave a truncated last line; it is skipped.
+	f, err := os.OpenFile(filepath.Join(dir, queueFileName), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":9,"alerts":[{"status":"fir`)
+	f.Close()
+
+	reopened, err := OpenQueue(dir, 2, metrics.New())
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.Len() != 2 {
+		t.Fatalf("expected 2 entries after reopening, got %d", reopened.Len())
+	}
+	oldest, _ := reopened.peek()
+	if name := oldest.Alerts[0].Labels["alertname"]; name != "Second" {
+		t.Errorf("expected oldest remaining entry Second, got %s", name)
+	}
+
+	if err := reopened.remove(oldest.Seq); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if _, err := reopened.push(queueEntry{Alerts: []models.Alert{{Status: "firing"}}}); err != nil {
+		t.Fatalf("push() error = %v", err)
+	}
+	next, _ := reopened.peek()
+	if next.Seq <= oldest.Seq {
+		t.Errorf("expected sequence numbers to keep increasing across restarts, got %d after %d", next.Seq, oldest.Seq)
+	}
+}
+
+func TestHandler_QueueReplay(t *testing.T) {
+	var available atomic.Bool
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			if !available.Load() {
+				return nil, &servicenow.RetryableError{Err: errors.New("service unavailable"), StatusCode: http.StatusServiceUnavailable}
+			}
+			return &servicenow.CreateIncidentResult{SysID: "sys1", Number: "INC0000001"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	queue, err := OpenQueue(t.TempDir(), 10, metrics.New())
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	defer queue.Close()
+	handler.SetQueue(queue)
+
+	alert := models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}}
+	sendAlerts(t, handler, alert)
+
+	if queue.Len() != 1 {
+		t.Fatalf("expected failed alert to be queued, queue has %d entries", queue.Len())
+	}
+
+	// Still down: the entry stays queued.
+	if n := handler.replayQueue(context.Background()); n != 0 || queue.Len() != 1 {
+		t.Fatalf("expected replay to stop while ServiceNow is down, replayed %d, %d queued", n, queue.Len())
+	}
+
+	available.Store(true)
+	if n := handler.replayQueue(context.Background()); n != 1 {
+		t.Fatalf("expected 1 replayed alert, got %d", n)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("expected empty queue after replay, got %d entries", queue.Len())
+	}
+	if len(mockClient.createCalls) != 3 {
+		t.Errorf("expected 3 CreateIncident calls (failed, failed replay, replay), got %d", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_QueueReplay_ResolvedWhileQueued(t *testing.T) {
+	var available atomic.Bool
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			if !available.Load() {
+				return nil, &servicenow.RetryableError{Err: errors.New("service unavailable"), StatusCode: http.StatusServiceUnavailable}
+			}
+			return &servicenow.CreateIncidentResult{SysID: "sys1", Number: "INC0000001"}, nil
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	queue, err := OpenQueue(t.TempDir(), 10, metrics.New())
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	defer queue.Close()
+	handler.SetQueue(queue)
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	startsAt := time.Now().Add(-10 * time.Minute)
+
+	// The create fails and is queued, then the resolve is handled live and
+	// finds no incident.
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels, StartsAt: startsAt})
+	available.Store(true)
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels, StartsAt: startsAt, EndsAt: startsAt.Add(5 * time.Minute)})
+
+	if n := handler.replayQueue(context.Background()); n != 0 {
+		t.Errorf("expected the resolved firing alert to be dropped, replayed %d", n)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("expected empty queue, got %d entries", queue.Len())
+	}
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected only the failed CreateIncident call, got %d", len(mockClient.createCalls))
+	}
+
+	// A re-fire that started after the resolve is replayed.
+	available.Store(false)
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels, StartsAt: startsAt.Add(8 * time.Minute)})
+	available.Store(true)
+	if n := handler.replayQueue(context.Background()); n != 1 {
+		t.Errorf("expected the re-fired alert to be replayed, replayed %d", n)
+	}
+}
+
+func TestHandler_QueueSkipsClientErrors(t *testing.T) {
+	mockClient := &mockServiceNowClient{
+		createIncidentFn: func(ctx context.Context, incident models.ServiceNowIncident) (*servicenow.CreateIncidentResult, error) {
+			return nil, &servicenow.RetryableError{Err: errors.New("bad request"), StatusCode: http.StatusBadRequest}
+		},
+	}
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+	queue, err := OpenQueue(t.TempDir(), 10, metrics.New())
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	defer queue.Close()
+	handler.SetQueue(queue)
+
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TestAlert"}})
+
+	if queue.Len() != 0 {
+		t.Errorf("expected rejected alert not to be queued, queue has %d entries", queue.Len())
+	}
+}
+
+func TestQueueable(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantQueue      bool
+		wantDeadLetter bool
+	}{
+		{name: "transport error", err: &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("connection refused")}, wantQueue: true},
+		{name: "server error", err: &servicenow.RetryableError{Err: errors.New("unavailable"), StatusCode: http.StatusServiceUnavailable}, wantQueue: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantQueue: true},
+		{name: "client error", err: &servicenow.RetryableError{Err: errors.New("bad request"), StatusCode: http.StatusBadRequest}, wantDeadLetter: true},
+		{name: "decode error", err: errors.New("failed to decode response: unexpected EOF"), wantDeadLetter: true},
+		{name: "rate limited", err: errRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queueable(tt.err); got != tt.wantQueue {
+				t.Errorf("queueable() = %v, want %v", got, tt.wantQueue)
+			}
+			if got := deadLetterable(tt.err); got != tt.wantDeadLetter {
+				t.Errorf("deadLetterable() = %v, want %v", got, tt.wantDeadLetter)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errRateLimited is returned for a firing alert whose alertname has used up
+// its PER_ALERTNAME_RATE_LIMIT budget. The alert is retried when
+// Alertmanager sends it again.
+var errRateLimited = errors.New("per-alertname rate limit exceeded")
+
+// alertnameLimiter is a token bucket per alertname, so one noisy alert rule
+// can't use up ServiceNow throughput for every other rule. Buckets hold up to
+// one minute's worth of tokens. Alertnames are bounded by the number of
+// alert rules, so buckets are never evicted.
+type alertnameLimiter struct {
+	perMinute int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newAlertnameLimiter creates a limiter allowing perMinute incidents per
+// alertname; zero disables limiting.
+func newAlertnameLimiter(perMinute int) *alertnameLimiter {
+	return &alertnameLimiter{
+		perMinute: perMinute,
+		now:       time.Now,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from alertname's bucket, reporting false if it is empty.
+func (l *alertnameLimiter) allow(alertname string) bool {
+	if l.perMinute <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.perMinute)
+	b, ok := l.buckets[alertname]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[alertname] = b
+	}
+
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Minutes()*capacity)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestAlertnameLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newAlertnameLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !limiter.allow("Noisy") {
+			t.Fatalf("call %d within burst was throttled", i+1)
+		}
+	}
+	if limiter.allow("Noisy") {
+		t.Error("call beyond burst should be throttled")
+	}
+	if !limiter.allow("Quiet") {
+		t.Error("other alertname should have its own bucket")
+	}
+
+	// Two per minute refills one token every 30s.
+	now = now.Add(30 * time.Second)
+	if !limiter.allow("Noisy") {
+		t.Error("expected a token after 30s")
+	}
+	if limiter.allow("Noisy") {
+		t.Error("expected only one token after 30s")
+	}
+}
+
+func TestAlertnameLimiter_Disabled(t *testing.T) {
+	limiter := newAlertnameLimiter(0)
+	for i := 0; i < 100; i++ {
+		if !limiter.allow("Noisy") {
+			t.Fatal("zero rate should disable limiting")
+		}
+	}
+}
+
+func TestHandler_PerAlertnameRateLimit(t *testing.T) {
+	mockClient := &mockServiceNowClient{}
+	cfg := &config.Config{
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		WorkerPoolSize:        1,
+		PerAlertnameRateLimit: 1,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	handler.limiter.now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	alert := func(alertname, pod string) models.Alert {
+		return models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": alertname, "cluster": "test-cluster", "pod": pod},
+		}
+	}
+
+	sendAlerts(t, handler, alert("Noisy", "a"), alert("Noisy", "b"), alert("Quiet", "a"))
+
+	created := map[string]int{}
+	for _, incident := range mockClient.createCalls {
+		for _, name := range []string{"Noisy", "Quiet"} {
+			if strings.Contains(incident.ShortDescription, name) {
+				created[name]++
+			}
+		}
+	}
+	if created["Noisy"] != 1 {
+		t.Errorf("expected Noisy to be throttled to 1 incident, got %d", created["Noisy"])
+	}
+	if created["Quiet"] != 1 {
+		t.Errorf("expected Quiet to create 1 incident, got %d", created["Quiet"])
+	}
+}
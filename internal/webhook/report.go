@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reportCallbackTimeout bounds the POST of one processing report.
+const reportCallbackTimeout = 10 * time.Second
+
+// Actions listed in processing reports.
+const (
+	actionCreated         = "created"
+	actionAlreadyOpen     = "already_open"
+	actionReopened        = "reopened"
+	actionSuppressed      = "suppressed"
+	actionAddedToDigest   = "added_to_digest"
+	actionResolved        = "resolved"
+	actionResolveDeferred = "resolve_deferred"
+	actionNotFound        = "not_found"
+	actionSkipped         = "skipped"
+)
+
+// reportAction is what was done for one correlation ID.
+type reportAction struct {
+	CorrelationID  string `json:"correlation_id"`
+	Action         string `json:"action"`
+	IncidentNumber string `json:"incident_number,omitempty"`
+}
+
+// reportActions collects the actions taken while serving one webhook
+// request. Workers record into it concurrently.
+type reportActions struct {
+	mu      sync.Mutex
+	actions []reportAction
+}
+
+type reportActionsKey struct{}
+
+// withReportActions returns a context whose actions are recorded in the
+// returned collector.
+func withReportActions(ctx context.Context) (context.Context, *reportActions) {
+	actions := &reportActions{}
+	return context.WithValue(ctx, reportActionsKey{}, actions), actions
+}
+
+// recordAction notes an action taken on behalf of ctx. It does nothing
+// unless REPORT_CALLBACK_URL is set, and outside a webhook request.
+func recordAction(ctx context.Context, correlationID, action, number string) {
+	actions, ok := ctx.Value(reportActionsKey{}).(*reportActions)
+	if !ok {
+		return
+	}
+	actions.mu.Lock()
+	defer actions.mu.Unlock()
+	actions.actions = append(actions.actions, reportAction{
+		CorrelationID:  correlationID,
+		Action:         action,
+		IncidentNumber: number,
+	})
+}
+
+// list returns the recorded actions sorted by correlation ID, so reports
+// don't depend on worker scheduling.
+func (r *reportActions) list() []reportAction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actions := append([]reportAction{}, r.actions...)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].CorrelationID < actions[j].CorrelationID
+	})
+	return actions
+}
+
+// processingReport summarizes one webhook request for REPORT_CALLBACK_URL.
+type processingReport struct {
+	RequestID        string         `json:"request_id"`
+	Source           string         `json:"source"`
+	Receiver         string         `json:"receiver,omitempty"`
+	Status           string         `json:"status,omitempty"`
+	Received         int            `json:"received"`
+	Processed        int            `json:"processed"`
+	Failed           int            `json:"failed"`
+	IncidentsCreated []string       `json:"incidents_created"`
+	Actions          []reportAction `json:"actions"`
+	ProcessedAt      time.Time      `json:"processed_at"`
+}
+
+// newProcessingReport counts results and lists actions for a report.
+func newProcessingReport(results []alertResult, actions []reportAction) processingReport {
+	report := processingReport{
+		Failed:           failedAlerts(results),
+		IncidentsCreated: []string{},
+		Actions:          actions,
+	}
+	for _, result := range results {
+		report.Processed += result.Alerts
+	}
+	for _, action := range actions {
+		if action.Action == actionCreated && action.IncidentNumber != "" {
+			report.IncidentsCreated = append(report.IncidentsCreated, action.IncidentNumber)
+		}
+	}
+	sort.Strings(report.IncidentsCreated)
+	return report
+}
+
+// sendReport posts report to REPORT_CALLBACK_URL. Failures are logged and
+// counted but otherwise ignored.
+func (h *Handler) sendReport(report processingReport) {
+	err := h.postReport(report)
+	if err != nil {
+		h.metrics.ReportCallbacks.WithLabelValues("error").Inc()
+		h.logger.Warn("failed to send processing report",
+			"request_id", report.RequestID,
+			"error", err,
+		)
+		return
+	}
+	h.metrics.ReportCallbacks.WithLabelValues("success").Inc()
+}
+
+func (h *Handler) postReport(report processingReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportCallbackTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.ReportCallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestIDHeader, report.RequestID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
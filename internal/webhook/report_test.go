@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestHandler_ReportCallback(t *testing.T) {
+	reports := make(chan []byte, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		reports <- body
+	}))
+	defer callback.Close()
+
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		WorkerPoolSize:      1,
+		ReportCallbackURL:   callback.URL,
+	}
+	m := metrics.New()
+	transformer := NewTransformer(cfg, m, newTestLogger())
+	handler := NewHandler(cfg, newStatefulMock(), transformer, m, newTestLogger())
+	handler.now = func() time.Time { return time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC) }
+
+	firing := models.Alert{Status: "firing", Labels: map[string]string{"alertname": "Firing"}}
+	resolved := models.Alert{Status: "resolved", Labels: map[string]string{"alertname": "Unknown"}}
+	sendAlerts(t, handler, firing, resolved)
+
+	var report processingReport
+	select {
+	case body := <-reports:
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Fatalf("failed to decode report %s: %v", body, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback did not receive a report")
+	}
+
+	if report.RequestID == "" || report.Source != "alertmanager" {
+		t.Errorf("unexpected request_id %q or source %q", report.RequestID, report.Source)
+	}
+	if report.Received != 2 || report.Processed != 2 || report.Failed != 0 {
+		t.Errorf("counts = received %d, processed %d, failed %d, want 2, 2, 0", report.Received, report.Processed, report.Failed)
+	}
+	if want := []string{"INC0000001"}; !reflect.DeepEqual(report.IncidentsCreated, want) {
+		t.Errorf("incidents_created = %v, want %v", report.IncidentsCreated, want)
+	}
+	want := []reportAction{
+		{CorrelationID: transformer.CorrelationID(firing), Action: actionCreated, IncidentNumber: "INC0000001"},
+		{CorrelationID: transformer.CorrelationID(resolved), Action: actionNotFound},
+	}
+	if want[0].CorrelationID > want[1].CorrelationID {
+		want[0], want[1] = want[1], want[0]
+	}
+	if !reflect.DeepEqual(report.Actions, want) {
+		t.Errorf("actions = %+v, want %+v", report.Actions, want)
+	}
+	if !report.ProcessedAt.Equal(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("processed_at = %v", report.ProcessedAt)
+	}
+
+	// The counter is incremented after the callback responds.
+	deadline := time.Now().Add(5 * time.Second)
+	for counterValue(t, m.ReportCallbacks, "success") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a successful report callback to be counted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"github.com/cragr/alert2snow-agent/internal/logging"
+)
+
+// requestIDHeader carries the ID tying a webhook request to its log lines.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds a caller-supplied request ID.
+const maxRequestIDLen = 128
+
+// requestID returns the caller's X-Request-ID if it is usable, or a new
+// random ID.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); validRequestID(id) {
+		return id
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id is non-empty, bounded, and printable
+// ASCII, so it can be logged and echoed back as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// log returns the logger for work done on behalf of ctx: the request-scoped
+// logger carrying request_id for webhook requests, or the handler's logger
+// for background work such as queue replays.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
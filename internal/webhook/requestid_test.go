@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestHandler_RequestIDHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		inbound string
+		want    string
+	}{
+		{name: "echoes inbound ID", inbound: "abc-123", want: "abc-123"},
+		{name: "generates missing ID"},
+		{name: "replaces ID with spaces", inbound: "abc 123"},
+		{name: "replaces overlong ID", inbound: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment"}
+			handler := NewHandler(cfg, &mockServiceNowClient{}, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			body, _ := json.Marshal(models.AlertmanagerPayload{Status: "firing"})
+			req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+			if tt.inbound != "" {
+				req.Header.Set(requestIDHeader, tt.inbound)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(requestIDHeader)
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("X-Request-ID = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if len(got) != 16 || got == tt.inbound {
+				t.Errorf("X-Request-ID = %q, want a generated 16-character ID", got)
+			}
+		})
+	}
+}
+
+func TestHandler_RequestIDInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", WorkerPoolSize: 2}
+	handler := NewHandler(cfg, newStatefulMock(), NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), logger)
+
+	body, _ := json.Marshal(models.AlertmanagerPayload{
+		Status: "firing",
+		Alerts: []models.Alert{podAlert("firing", "HighCPU", "a"), podAlert("firing", "HighCPU", "b")},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/alertmanager/webhook", bytes.NewReader(body))
+	req.Header.Set(requestIDHeader, "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	messages := map[string]bool{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("failed to decode log line %q: %v", scanner.Text(), err)
+		}
+		if line["request_id"] != "req-42" {
+			t.Errorf("log line %q has request_id %v, want req-42", line["msg"], line["request_id"])
+		}
+		messages[line["msg"].(string)] = true
+	}
+	for _, msg := range []string{"received webhook", "processing firing alert", "created incident in ServiceNow"} {
+		if !messages[msg] {
+			t.Errorf("missing log line %q", msg)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/logging"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// pendingResolveTimeout bounds the ServiceNow calls of a deferred resolve,
+// which runs outside any webhook request.
+const pendingResolveTimeout = 30 * time.Second
+
+// stopper is the part of *time.Timer pendingResolves needs, so tests can
+// fire deferred resolves by hand.
+type stopper interface {
+	Stop() bool
+}
+
+// pendingResolves tracks resolves held back for RESOLVE_STABILIZATION, keyed
+// by correlation ID. Entries live in memory only and are lost on restart.
+type pendingResolves struct {
+	delay     time.Duration
+	afterFunc func(time.Duration, func()) stopper
+
+	mu      sync.Mutex
+	pending map[string]*pendingResolve
+}
+
+type pendingResolve struct {
+	timer stopper
+}
+
+func newPendingResolves(delay time.Duration) *pendingResolves {
+	return &pendingResolves{
+		delay: delay,
+		afterFunc: func(d time.Duration, f func()) stopper {
+			return time.AfterFunc(d, f)
+		},
+		pending: make(map[string]*pendingResolve),
+	}
+}
+
+// schedule runs fn once the delay has passed, replacing any resolve already
+// pending for id. Before acting, fn must hold the correlation lock and call
+// claim, which reports false if the resolve was cancelled or replaced in
+// the meantime.
+func (p *pendingResolves) schedule(id string, fn func(claim func() bool)) {
+	p.scheduleAfter(id, p.delay, fn)
+}
+
+// scheduleAfter is like schedule but waits for delay instead.
+func (p *pendingResolves) scheduleAfter(id string, delay time.Duration, fn func(claim func() bool)) {
+	entry := &pendingResolve{}
+	claim := func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.pending[id] != entry {
+			return false
+		}
+		delete(p.pending, id)
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old, ok := p.pending[id]; ok {
+		old.timer.Stop()
+	}
+	entry.timer = p.afterFunc(delay, func() { fn(claim) })
+	p.pending[id] = entry
+}
+
+// cancel drops the resolve pending for id and reports whether there was one.
+func (p *pendingResolves) cancel(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.pending[id]
+	if !ok {
+		return false
+	}
+	entry.timer.Stop()
+	delete(p.pending, id)
+	return true
+}
+
+// len returns the number of pending resolves.
+func (p *pendingResolves) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// resolve resolves the incident for a resolved alert, deferring it by
+// RESOLVE_STABILIZATION when configured so a quick re-fire keeps the
+// incident open.
+func (h *Handler) resolve(ctx context.Context, alert models.Alert, correlationID string) error {
+	h.noteResolve(alert, correlationID)
+
+	delay := h.stabilizationDelay(alert)
+	if delay <= 0 {
+		return h.handleResolvedAlert(ctx, alert, correlationID)
+	}
+
+	logger := h.log(ctx)
+	h.pending.scheduleAfter(correlationID, delay, func(claim func() bool) {
+		h.resolvePending(logger, alert, correlationID, claim)
+	})
+
+	recordAction(ctx, correlationID, actionResolveDeferred, "")
+
+	h.log(ctx).Info("deferring resolve until alert stabilizes",
+		"alertname", alert.Labels["alertname"],
+		"correlation_id", correlationID,
+		"stabilization", h.cfg.ResolveStabilization.String(),
+		"delay", delay.String(),
+	)
+
+	return nil
+}
+
+// stabilizationDelay returns how long to hold a resolve back. With
+// RESOLVE_STABILIZATION_FROM_ENDS_AT the time the alert has already been
+// resolved for counts toward the window; alerts without endsAt, or with
+// one in the future, wait for the whole window.
+func (h *Handler) stabilizationDelay(alert models.Alert) time.Duration {
+	window := h.cfg.ResolveStabilization
+	if window <= 0 || !h.cfg.StabilizeFromEndsAt || alert.EndsAt.IsZero() {
+		return window
+	}
+	resolvedFor := h.now().Sub(alert.EndsAt)
+	if resolvedFor < 0 {
+		return window
+	}
+	return window - resolvedFor
+}
+
+// resolvePending performs a deferred resolve unless the alert fired again
+// while it was pending. It logs with the logger of the request that
+// deferred it.
+func (h *Handler) resolvePending(logger *slog.Logger, alert models.Alert, correlationID string, claim func() bool) {
+	unlock := h.locks.lock(correlationID)
+	defer unlock()
+
+	if !claim() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(logging.NewContext(context.Background(), logger), pendingResolveTimeout)
+	defer cancel()
+
+	if err := h.handleResolvedAlert(ctx, alert, correlationID); err != nil {
+		logger.Error("failed to process deferred resolve",
+			"alertname", alert.Labels["alertname"],
+			"correlation_id", correlationID,
+			"error", err,
+		)
+		if queueable(err) {
+			h.enqueue(ctx, []models.Alert{alert}, nil, "")
+		}
+	}
+}
+
+// cancelPendingResolve drops a deferred resolve for an alert that fired again.
+func (h *Handler) cancelPendingResolve(correlationID string) {
+	if h.pending.cancel(correlationID) {
+		h.logger.Info("alert fired again before stabilizing, keeping incident open",
+			"correlation_id", correlationID,
+		)
+	}
+}
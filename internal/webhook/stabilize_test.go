@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// manualTimers replaces time.AfterFunc so tests decide when deferred
+// resolves fire.
+type manualTimers struct {
+	mu     sync.Mutex
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	delay   time.Duration
+	fn      func()
+	stopped bool
+}
+
+func (t *manualTimer) Stop() bool {
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+func (m *manualTimers) afterFunc(d time.Duration, fn func()) stopper {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &manualTimer{delay: d, fn: fn}
+	m.timers = append(m.timers, t)
+	return t
+}
+
+// fireAll runs every timer that has not been stopped.
+func (m *manualTimers) fireAll() {
+	m.mu.Lock()
+	timers := m.timers
+	m.timers = nil
+	m.mu.Unlock()
+
+	for _, t := range timers {
+		if !t.stopped {
+			t.stopped = true
+			t.fn()
+		}
+	}
+}
+
+func newStabilizingHandler(mockClient *mockServiceNowClient) (*Handler, *manualTimers) {
+	cfg := &config.Config{
+		ClusterLabelKey:      "cluster",
+		EnvironmentLabelKey:  "environment",
+		WorkerPoolSize:       1,
+		ResolveStabilization: 5 * time.Minute,
+	}
+	handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+	timers := &manualTimers{}
+	handler.pending.afterFunc = timers.afterFunc
+	return handler, timers
+}
+
+func TestHandler_ResolveStabilization_Resolves(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler, timers := newStabilizingHandler(mockClient)
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels})
+
+	if len(mockClient.resolveCalls) != 0 {
+		t.Fatalf("expected resolve to be deferred, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+	}
+	if n := handler.pending.len(); n != 1 {
+		t.Fatalf("expected 1 pending resolve, got %d", n)
+	}
+
+	timers.fireAll()
+
+	if len(mockClient.resolveCalls) != 1 {
+		t.Errorf("expected 1 ResolveIncident call after stabilization, got %d", len(mockClient.resolveCalls))
+	}
+	if n := handler.pending.len(); n != 0 {
+		t.Errorf("expected no pending resolves, got %d", n)
+	}
+}
+
+func TestHandler_ResolveStabilization_RefireCancels(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler, timers := newStabilizingHandler(mockClient)
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+
+	if n := handler.pending.len(); n != 0 {
+		t.Errorf("expected re-fire to cancel the pending resolve, %d remain", n)
+	}
+
+	timers.fireAll()
+
+	if len(mockClient.resolveCalls) != 0 {
+		t.Errorf("expected no ResolveIncident call after re-fire, got %d", len(mockClient.resolveCalls))
+	}
+	if len(mockClient.createCalls) != 1 {
+		t.Errorf("expected the open incident to be kept, got %d CreateIncident calls", len(mockClient.createCalls))
+	}
+}
+
+func TestHandler_ResolveStabilization_FromEndsAt(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler, timers := newStabilizingHandler(mockClient)
+	handler.cfg.StabilizeFromEndsAt = true
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels, EndsAt: now.Add(-2 * time.Minute)})
+
+	if len(mockClient.resolveCalls) != 0 {
+		t.Fatalf("expected resolve to be deferred, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+	}
+	if len(timers.timers) != 1 || timers.timers[0].delay != 3*time.Minute {
+		t.Fatalf("expected the resolve held for the rest of the 5m window, got %+v", timers.timers)
+	}
+
+	timers.fireAll()
+
+	if len(mockClient.resolveCalls) != 1 {
+		t.Errorf("expected 1 ResolveIncident call after stabilization, got %d", len(mockClient.resolveCalls))
+	}
+
+	// An alert resolved for longer than the window has already stabilized.
+	labels = map[string]string{"alertname": "OtherAlert", "cluster": "test-cluster"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels, EndsAt: now.Add(-10 * time.Minute)})
+
+	if len(mockClient.resolveCalls) != 2 {
+		t.Errorf("expected a stable alert to resolve immediately, got %d ResolveIncident calls", len(mockClient.resolveCalls))
+	}
+	if n := handler.pending.len(); n != 0 {
+		t.Errorf("expected no pending resolves, got %d", n)
+	}
+}
+
+func TestHandler_ResolveStabilization_FromEndsAtRefireCancels(t *testing.T) {
+	mockClient := newStatefulMock()
+	handler, timers := newStabilizingHandler(mockClient)
+	handler.cfg.StabilizeFromEndsAt = true
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+
+	labels := map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"}
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+	sendAlerts(t, handler, models.Alert{Status: "resolved", Labels: labels, EndsAt: now.Add(-time.Minute)})
+	sendAlerts(t, handler, models.Alert{Status: "firing", Labels: labels})
+
+	if n := handler.pending.len(); n != 0 {
+		t.Errorf("expected re-fire to cancel the pending resolve, %d remain", n)
+	}
+
+	timers.fireAll()
+
+	if len(mockClient.resolveCalls) != 0 {
+		t.Errorf("expected no ResolveIncident call after re-fire, got %d", len(mockClient.resolveCalls))
+	}
+}
+
+func TestPendingResolves_ClaimAfterCancel(t *testing.T) {
+	pending := newPendingResolves(time.Minute)
+	timers := &manualTimers{}
+	pending.afterFunc = timers.afterFunc
+
+	var claimed []bool
+	pending.schedule("id", func(claim func() bool) {
+		claimed = append(claimed, claim())
+	})
+
+	// The timer has already fired but the callback has not claimed the
+	// resolve yet when the alert fires again.
+	fired := timers.timers[0]
+	pending.cancel("id")
+	fired.fn()
+
+	if len(claimed) != 1 || claimed[0] {
+		t.Errorf("expected a cancelled resolve not to be claimed, got %v", claimed)
+	}
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+// parentLookupLimit bounds how many candidate parent incidents are fetched
+// per lookup; candidates are filtered client-side by exact alert name.
+const parentLookupLimit = 10
+
+// invertSuppressionRules turns parent -> children rules into a child ->
+// parents index, with parents sorted so lookups are deterministic.
+func invertSuppressionRules(rules map[string][]string) map[string][]string {
+	out := make(map[string][]string)
+	for parent, children := range rules {
+		for _, child := range children {
+			out[child] = append(out[child], parent)
+		}
+	}
+	for child := range out {
+		sort.Strings(out[child])
+	}
+	return out
+}
+
+// SuppressingParents returns the parent alert names whose open incident
+// suppresses incidents for alertname.
+func (t *Transformer) SuppressingParents(alertname string) []string {
+	return t.suppressedBy[alertname]
+}
+
+// SuppressingLabel returns the first SUPPRESS_IF_LABEL pair the alert's
+// labels match.
+func (t *Transformer) SuppressingLabel(alert models.Alert) (config.LabelMatcher, bool) {
+	for _, m := range t.cfg.SuppressIfLabel {
+		if value, ok := alert.Labels[m.Label]; ok && value == m.Value {
+			return m, true
+		}
+	}
+	return config.LabelMatcher{}, false
+}
+
+// isIncidentFor reports whether a short_description was produced by Transform
+// for alertname in cluster: the default format, or a SHORT_DESCRIPTION_TEMPLATE
+// that keeps it as a prefix, such as with a namespace suffix.
+func (t *Transformer) isIncidentFor(shortDescription, cluster, alertname string) bool {
+	base := t.buildShortDescription(cluster, alertname, "")
+	return shortDescription == base || strings.HasPrefix(shortDescription, base+" ")
+}
+
+// findSuppressingParent returns the open incident of a configured parent
+// alert in the same cluster, or nil if there is none.
+func (h *Handler) findSuppressingParent(ctx context.Context, alert models.Alert) (*models.ServiceNowResult, error) {
+	t := h.transformer(ctx)
+	parents := t.SuppressingParents(alert.Labels["alertname"])
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	cluster := t.extractClusterName(alert)
+	for _, parent := range parents {
+		prefix := t.buildShortDescription(cluster, parent, "")
+		candidates, err := h.snowClient.FindOpenIncidentsByShortDescriptionPrefix(ctx, prefix, parentLookupLimit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range candidates {
+			// The prefix also matches longer alert names (KubeAPIDown vs
+			// KubeAPIDownSoon), so confirm the exact parent.
+			if t.isIncidentFor(candidates[i].ShortDescription, cluster, parent) {
+				return &candidates[i], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// suppressUnderParent work-notes the parent incident instead of creating an
+// incident for a child alert. It reports whether the alert was suppressed.
+func (h *Handler) suppressUnderParent(ctx context.Context, alert models.Alert, correlationID string) (bool, error) {
+	parent, err := h.findSuppressingParent(ctx, alert)
+	if err != nil || parent == nil {
+		return false, err
+	}
+
+	note := fmt.Sprintf("Suppressed child alert while this incident is open:\n%s", h.transformer(ctx).AlertWorkNote(alert))
+	if err := h.snowClient.AddWorkNote(ctx, "", parent.SysID, note); err != nil {
+		return false, err
+	}
+
+	recordAction(ctx, correlationID, actionSuppressed, parent.Number)
+
+	h.log(ctx).Info("suppressed alert under open parent incident",
+		"alertname", alert.Labels["alertname"],
+		"correlation_id", correlationID,
+		"parent_incident_number", parent.Number,
+		"parent_sys_id", parent.SysID,
+	)
+
+	return true, nil
+}
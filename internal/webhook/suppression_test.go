@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
+	"github.com/cragr/alert2snow-agent/internal/models"
+)
+
+func TestHandler_Suppression(t *testing.T) {
+	child := func(name, cluster string) models.Alert {
+		return models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": name, "cluster": cluster, "namespace": "openshift-monitoring"},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		openParents   []models.ServiceNowResult
+		alerts        []models.Alert
+		wantCreateLen int
+		wantNotes     int
+	}{
+		{
+			name: "children suppressed while parent is open",
+			openParents: []models.ServiceNowResult{
+				{SysID: "parent1", Number: "INC0000100", ShortDescription: "[prod] KubeAPIDown in namespace: openshift-kube-apiserver"},
+			},
+			alerts:        []models.Alert{child("TargetDown", "prod"), child("KubeletDown", "prod"), child("TargetDown", "prod")},
+			wantCreateLen: 0,
+			wantNotes:     3,
+		},
+		{
+			name: "parent from a short description template",
+			openParents: []models.ServiceNowResult{
+				{SysID: "parent1", Number: "INC0000100", ShortDescription: "[prod] KubeAPIDown (critical)"},
+			},
+			alerts:    []models.Alert{child("TargetDown", "prod")},
+			wantNotes: 1,
+		},
+		{
+			name: "group incident as parent",
+			openParents: []models.ServiceNowResult{
+				{SysID: "parent1", Number: "INC0000100", ShortDescription: "[prod] KubeAPIDown (3 alerts)"},
+			},
+			alerts:    []models.Alert{child("TargetDown", "prod")},
+			wantNotes: 1,
+		},
+		{
+			name:          "children create incidents without an open parent",
+			alerts:        []models.Alert{child("TargetDown", "prod"), child("KubeletDown", "prod")},
+			wantCreateLen: 2,
+		},
+		{
+			name: "parent name must match exactly",
+			openParents: []models.ServiceNowResult{
+				{SysID: "other", ShortDescription: "[prod] KubeAPIDownSoon"},
+			},
+			alerts:        []models.Alert{child("TargetDown", "prod")},
+			wantCreateLen: 1,
+		},
+		{
+			name: "unrelated alerts are not suppressed",
+			openParents: []models.ServiceNowResult{
+				{SysID: "parent1", ShortDescription: "[prod] KubeAPIDown"},
+			},
+			alerts:        []models.Alert{child("PodCrashLooping", "prod")},
+			wantCreateLen: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prefixes []string
+			mockClient := &mockServiceNowClient{
+				findOpenByPrefixFn: func(ctx context.Context, prefix string, limit int) ([]models.ServiceNowResult, error) {
+					prefixes = append(prefixes, prefix)
+					var out []models.ServiceNowResult
+					for _, r := range tt.openParents {
+						if strings.HasPrefix(r.ShortDescription, prefix) {
+							out = append(out, r)
+						}
+					}
+					return out, nil
+				},
+			}
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				SuppressionRules: map[string][]string{
+					"KubeAPIDown": {"TargetDown", "KubeletDown"},
+				},
+				WorkerPoolSize: 1,
+			}
+			handler := NewHandler(cfg, mockClient, NewTransformer(cfg, metrics.New(), newTestLogger()), metrics.New(), newTestLogger())
+
+			sendAlerts(t, handler, tt.alerts...)
+
+			if len(mockClient.createCalls) != tt.wantCreateLen {
+				t.Errorf("expected %d CreateIncident calls, got %d", tt.wantCreateLen, len(mockClient.createCalls))
+			}
+			if got := len(mockClient.workNotes["parent1"]); got != tt.wantNotes {
+				t.Errorf("expected %d work notes on parent, got %d", tt.wantNotes, got)
+			}
+			for _, note := range mockClient.workNotes["parent1"] {
+				if !strings.Contains(note, "Suppressed child alert") {
+					t.Errorf("unexpected work note %q", note)
+				}
+			}
+			for _, p := range prefixes {
+				if p != "[prod] KubeAPIDown" {
+					t.Errorf("parent lookup should be scoped to the child's cluster, got prefix %q", p)
+				}
+			}
+		})
+	}
+}
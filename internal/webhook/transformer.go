@@ -4,96 +4,654 @@ package webhook
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"path"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 )
 
 // Transformer converts Alertmanager alerts to ServiceNow incidents.
 type Transformer struct {
-	cfg *config.Config
+	cfg                  *config.Config
+	labelNormalization   map[string]map[string]string
+	severityCategories   map[string]config.CategoryOverride
+	severityPatterns     []config.SeverityPattern
+	resolveNotes         *template.Template
+	descriptionTmpl      *template.Template
+	shortDescriptionTmpl *template.Template
+	incidentURLTmpl      *template.Template
+	consoleLinkTmpl      *template.Template
+	clusterURLRegex      *regexp.Regexp
+	suppressedBy         map[string][]string
+	metrics              *metrics.Metrics
+	logger               *slog.Logger
 }
 
 // NewTransformer creates a new Transformer with the given configuration.
-func NewTransformer(cfg *config.Config) *Transformer {
-	return &Transformer{cfg: cfg}
+func NewTransformer(cfg *config.Config, m *metrics.Metrics, logger *slog.Logger) *Transformer {
+	t := &Transformer{
+		cfg:                cfg,
+		labelNormalization: lowerCaseKeys(cfg.LabelNormalization),
+		severityCategories: lowerCaseSeverities(cfg.SeverityCategories),
+		suppressedBy:       invertSuppressionRules(cfg.SuppressionRules),
+		metrics:            m,
+		logger:             logger,
+	}
+	// config.Load has already validated the patterns.
+	t.severityPatterns, _ = config.CompileSeverityPatterns(cfg.SeverityPatterns)
+	if cfg.ResolveNotesTemplate != "" {
+		// config.Load has already validated the template; a nil template
+		// falls back to the default notes.
+		t.resolveNotes, _ = config.ParseResolveNotesTemplate(cfg.ResolveNotesTemplate)
+	}
+	if cfg.DescriptionTemplate != "" {
+		t.descriptionTmpl, _ = config.ParseDescriptionTemplate(cfg.DescriptionTemplate)
+	}
+	if cfg.ShortDescriptionTemplate != "" {
+		t.shortDescriptionTmpl, _ = config.ParseShortDescriptionTemplate(cfg.ShortDescriptionTemplate)
+	}
+	if cfg.ConsoleLinkTemplate != "" {
+		t.consoleLinkTmpl, _ = config.ParseConsoleLinkTemplate(cfg.ConsoleLinkTemplate)
+	}
+	if cfg.ClusterURLRegex != "" {
+		t.clusterURLRegex, _ = config.CompileClusterURLRegex(cfg.ClusterURLRegex)
+	}
+	urlTemplate := cfg.IncidentURLTemplate
+	if urlTemplate == "" {
+		urlTemplate = config.DefaultIncidentURLTemplate
+	}
+	t.incidentURLTmpl, _ = config.ParseIncidentURLTemplate(urlTemplate)
+	return t
+}
+
+// IncidentURL renders SERVICENOW_INCIDENT_URL_TEMPLATE for a record created
+// in the table at tablePath, or SERVICENOW_ENDPOINT_PATH if tablePath is
+// empty. It returns "" if the template fails to render.
+func (t *Transformer) IncidentURL(tablePath, sysID, number string) string {
+	if t.incidentURLTmpl == nil {
+		return ""
+	}
+	if tablePath == "" {
+		tablePath = t.cfg.ServiceNowEndpointPath
+	}
+	data := models.IncidentURLData{
+		BaseURL: strings.TrimSuffix(t.cfg.ServiceNowBaseURL, "/"),
+		Table:   tableName(tablePath),
+		SysID:   sysID,
+		Number:  number,
+	}
+	var sb strings.Builder
+	if err := t.incidentURLTmpl.Execute(&sb, data); err != nil {
+		t.logger.Warn("failed to render incident URL", "sys_id", sysID, "error", err)
+		return ""
+	}
+	return sb.String()
+}
+
+// tableName returns the table a Table API path such as
+// /api/now/table/change_request points at. Other paths, such as an import
+// set staging table, create incidents.
+func tableName(tablePath string) string {
+	if i := strings.LastIndex(tablePath, "/table/"); i >= 0 {
+		if name := strings.Trim(tablePath[i+len("/table/"):], "/"); name != "" {
+			return name
+		}
+	}
+	return "incident"
+}
+
+// ResolveNotes renders the close notes for a resolved alert. The alert's
+// EndsAt is used as the resolve time, or now if Alertmanager did not set it.
+func (t *Transformer) ResolveNotes(alert models.Alert, correlationID, incidentNumber string) (string, error) {
+	if t.resolveNotes == nil {
+		return models.DefaultResolveNotes, nil
+	}
+
+	resolvedAt := alert.EndsAt
+	if resolvedAt.IsZero() {
+		resolvedAt = time.Now()
+	}
+
+	var sb strings.Builder
+	err := t.resolveNotes.Execute(&sb, models.ResolveNotesData{
+		AlertName:      alert.Labels["alertname"],
+		CorrelationID:  correlationID,
+		IncidentNumber: incidentNumber,
+		ResolvedAt:     resolvedAt.UTC(),
+	})
+	if err != nil {
+		return models.DefaultResolveNotes, fmt.Errorf("failed to render resolve notes: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// Normalize returns a copy of the alert with aliased label names renamed to
+// their canonical name and configured label values replaced by their
+// canonical form. It must run before correlation and transformation so a
+// renamed label (e.g. k8s_namespace) or an equivalent value (e.g. PROD,
+// production) produces the same incident. If both an alias and its canonical
+// label are present, the canonical label's value is kept.
+func (t *Transformer) Normalize(alert models.Alert) models.Alert {
+	if len(t.cfg.LabelAliases) == 0 && len(t.labelNormalization) == 0 {
+		return alert
+	}
+
+	labels := make(map[string]string, len(alert.Labels))
+	for k, v := range alert.Labels {
+		if canonical, ok := t.cfg.LabelAliases[k]; ok {
+			if _, exists := alert.Labels[canonical]; exists {
+				continue
+			}
+			k = canonical
+		}
+		if canonical, ok := t.labelNormalization[k][strings.ToLower(v)]; ok {
+			v = canonical
+		}
+		labels[k] = v
+	}
+	alert.Labels = labels
+	return alert
+}
+
+// lowerCaseKeys copies a normalization map with its raw values lower-cased
+// for case-insensitive lookup.
+func lowerCaseKeys(m map[string]map[string]string) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(m))
+	for label, values := range m {
+		out[label] = make(map[string]string, len(values))
+		for raw, canonical := range values {
+			out[label][strings.ToLower(raw)] = canonical
+		}
+	}
+	return out
+}
+
+// Severity returns the alert's severity label or, when it is missing, the
+// severity of the first SEVERITY_PATTERNS entry matching the alertname,
+// falling back to DEFAULT_SEVERITY. The labels themselves are left untouched
+// so the inferred value doesn't change correlation IDs.
+func (t *Transformer) Severity(alert models.Alert) string {
+	if severity := alert.Labels["severity"]; severity != "" {
+		return severity
+	}
+
+	alertname := alert.Labels["alertname"]
+	for _, p := range t.severityPatterns {
+		if p.Pattern.MatchString(alertname) {
+			return p.Severity
+		}
+	}
+	return t.cfg.DefaultSeverity
+}
+
+// lowerCaseSeverities copies the severity category map keyed by lower-cased
+// severity for case-insensitive lookup.
+func lowerCaseSeverities(m map[string]config.CategoryOverride) map[string]config.CategoryOverride {
+	out := make(map[string]config.CategoryOverride, len(m))
+	for severity, override := range m {
+		out[strings.ToLower(severity)] = override
+	}
+	return out
+}
+
+// categoryFor returns the incident category and subcategory for a severity.
+// A configured severity mapping takes precedence over the static
+// SERVICENOW_CATEGORY/SERVICENOW_SUBCATEGORY; fields it leaves empty fall
+// back to the static values.
+func (t *Transformer) categoryFor(severity string) (category, subcategory string) {
+	category, subcategory = t.cfg.ServiceNowCategory, t.cfg.ServiceNowSubcategory
+
+	override, ok := t.severityCategories[strings.ToLower(severity)]
+	if !ok {
+		return category, subcategory
+	}
+	if override.Category != "" {
+		category = override.Category
+	}
+	if override.Subcategory != "" {
+		subcategory = override.Subcategory
+	}
+	return category, subcategory
 }
 
 // Transform converts an Alertmanager alert to a ServiceNow incident payload.
 func (t *Transformer) Transform(alert models.Alert, externalURL string) models.ServiceNowIncident {
-	alertname := alert.Labels["alertname"]
-	cluster := t.extractClusterName(alert)
-	namespace := alert.Labels["namespace"]
-	pod := alert.Labels["pod"]
-	container := alert.Labels["container"]
-	severity := alert.Labels["severity"]
-	environment := alert.Labels[t.cfg.EnvironmentLabelKey]
-
-	shortDesc := t.buildShortDescription(cluster, alertname, namespace)
-	description := t.buildDescription(alert, cluster, environment, severity, namespace, pod, container)
-	correlationID := GenerateCorrelationID(alertname, alert.Labels)
-
-	return models.ServiceNowIncident{
-		ShortDescription: shortDesc,
-		Description:      description,
+	data := models.DescriptionData{
+		Alert:       alert,
+		AlertName:   alert.Labels["alertname"],
+		Cluster:     t.extractClusterName(alert),
+		Environment: alert.Labels[t.cfg.EnvironmentLabelKey],
+		Severity:    t.Severity(alert),
+		Namespace:   alert.Labels["namespace"],
+		Pod:         alert.Labels["pod"],
+		Container:   alert.Labels["container"],
+		ExternalURL: externalURL,
+	}
+	if data.Cluster != "" && data.Namespace != "" {
+		data.ConsoleURL = t.consoleURL(alert, data.Cluster, data.Namespace)
+	}
+
+	correlationID := t.CorrelationID(alert)
+	category, subcategory := t.categoryFor(data.Severity)
+
+	incident := models.ServiceNowIncident{
+		ShortDescription: t.shortDescription(data),
+		Description:      t.truncateDescription(t.buildDescription(data)),
 		Impact:           t.cfg.ServiceNowImpact,
 		Urgency:          t.cfg.ServiceNowUrgency,
-		Category:         t.cfg.ServiceNowCategory,
-		Subcategory:      t.cfg.ServiceNowSubcategory,
-		AssignmentGroup:  t.cfg.ServiceNowAssignmentGroup,
+		Category:         category,
+		Subcategory:      subcategory,
+		AssignmentGroup:  t.assignmentGroup(alert),
 		CallerID:         t.cfg.ServiceNowCallerID,
+		ContactType:      t.cfg.ServiceNowContactType,
 		CorrelationID:    correlationID,
 	}
+	incident.ExtraFields = t.labelFields(alert)
+	if priority, ok := t.priorityOverride(alert); ok {
+		incident.Priority = priority
+	}
+	t.applyLabelFieldMap(&incident, alert)
+	t.applyFieldRules(&incident, alert, data.Severity)
+	if t.cfg.ServiceNowSetPriority && incident.Priority == "" {
+		if priority, ok := priorityFor(incident.Impact, incident.Urgency); ok {
+			incident.Priority = priority
+		}
+	}
+	t.applyMarker(&incident)
+	if t.cfg.FingerprintField != "" && alert.Fingerprint != "" {
+		incident.SetField(t.cfg.FingerprintField, alert.Fingerprint)
+	}
+	return incident
 }
 
-// buildShortDescription creates the short_description field for ServiceNow.
+// assignmentGroup returns the group of the first ASSIGNMENT_GROUP_MAP rule the
+// alert's labels match, or SERVICENOW_ASSIGNMENT_GROUP if none does.
+func (t *Transformer) assignmentGroup(alert models.Alert) string {
+	for _, rule := range t.cfg.AssignmentGroupMap {
+		if alert.Labels[rule.Label] == rule.Value {
+			return rule.Group
+		}
+	}
+	return t.cfg.ServiceNowAssignmentGroup
+}
+
+// applyFieldRules sets the fields of the first FIELD_RULES rule the alert
+// matches, overriding whatever was set before.
+func (t *Transformer) applyFieldRules(incident *models.ServiceNowIncident, alert models.Alert, severity string) {
+	for _, rule := range t.cfg.FieldRules {
+		if !ruleMatches(rule, alert, severity) {
+			continue
+		}
+		for field, value := range rule.Set {
+			incident.SetField(field, value)
+		}
+		return
+	}
+}
+
+// ruleMatches reports whether an alert with the given severity meets every
+// condition of rule.
+func ruleMatches(rule config.FieldRule, alert models.Alert, severity string) bool {
+	if rule.Severity != "" && !strings.EqualFold(rule.Severity, severity) {
+		return false
+	}
+	for label, value := range rule.Labels {
+		if alert.Labels[label] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// applyMarker sets INCIDENT_MARKER_FIELD, if configured, so later resolves
+// can tell the incident was created by this agent.
+func (t *Transformer) applyMarker(incident *models.ServiceNowIncident) {
+	if t.cfg.IncidentMarkerField != "" {
+		incident.SetField(t.cfg.IncidentMarkerField, t.cfg.IncidentMarkerValue)
+	}
+}
+
+// Owns reports whether an existing incident may be resolved by this agent:
+// always without INCIDENT_MARKER_FIELD, otherwise only if the field holds
+// the marker value.
+func (t *Transformer) Owns(incident *models.ServiceNowResult) bool {
+	if t.cfg.IncidentMarkerField == "" {
+		return true
+	}
+	return incident.Fields[t.cfg.IncidentMarkerField] == t.cfg.IncidentMarkerValue
+}
+
+// applyLabelFieldMap copies label values into incident fields per
+// LABEL_FIELD_MAP, overriding whatever was set before. Labels the alert
+// doesn't carry are skipped. Labels are applied in sorted order so two
+// labels mapped to one field resolve the same way every time.
+func (t *Transformer) applyLabelFieldMap(incident *models.ServiceNowIncident, alert models.Alert) {
+	if len(t.cfg.LabelFieldMap) == 0 {
+		return
+	}
+
+	labels := make([]string, 0, len(t.cfg.LabelFieldMap))
+	for label := range t.cfg.LabelFieldMap {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		if value := alert.Labels[label]; value != "" {
+			incident.SetField(t.cfg.LabelFieldMap[label], value)
+		}
+	}
+}
+
+// labelFields returns the extra incident fields for an alert: the static
+// SERVICENOW_EXTRA_FIELDS overlaid with fields populated from alert labels
+// per FIELD_LABEL_MAP, omitting fields whose label is unset or empty.
+func (t *Transformer) labelFields(alert models.Alert) map[string]string {
+	if len(t.cfg.ServiceNowExtraFields) == 0 && len(t.cfg.FieldLabelMap) == 0 {
+		return nil
+	}
+
+	fields := t.staticFields()
+	for field, label := range t.cfg.FieldLabelMap {
+		if value := alert.Labels[label]; value != "" {
+			fields[field] = value
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// staticFields returns a copy of SERVICENOW_EXTRA_FIELDS.
+func (t *Transformer) staticFields() map[string]string {
+	fields := make(map[string]string, len(t.cfg.ServiceNowExtraFields)+len(t.cfg.FieldLabelMap))
+	for field, value := range t.cfg.ServiceNowExtraFields {
+		fields[field] = value
+	}
+	return fields
+}
+
+// annotationPriority lets an alert set the incident priority directly.
+const annotationPriority = "snow_priority"
+
+// validPriorities are the ServiceNow priority values accepted from the
+// snow_priority annotation (1=Critical through 5=Planning).
+var validPriorities = map[string]bool{"1": true, "2": true, "3": true, "4": true, "5": true}
+
+// priorityOverride returns the priority set by the alert's snow_priority
+// annotation. Invalid values are ignored with a warning so the incident
+// still gets the priority ServiceNow computes from impact and urgency.
+func (t *Transformer) priorityOverride(alert models.Alert) (string, bool) {
+	raw, ok := alert.Annotations[annotationPriority]
+	if !ok {
+		return "", false
+	}
+
+	priority := strings.TrimSpace(raw)
+	if !validPriorities[priority] {
+		t.logger.Warn("ignoring invalid snow_priority annotation",
+			"alertname", alert.Labels["alertname"],
+			"value", raw,
+		)
+		return "", false
+	}
+	return priority, true
+}
+
+// priorityFor maps impact and urgency (1=High through 3=Low) to the priority
+// ServiceNow's default matrix derives from them, from 1=Critical for 1/1 to
+// 5=Planning for 3/3. It reports false if either value is out of range.
+func priorityFor(impact, urgency string) (string, bool) {
+	i, err := strconv.Atoi(strings.TrimSpace(impact))
+	if err != nil || i < 1 || i > 3 {
+		return "", false
+	}
+	u, err := strconv.Atoi(strings.TrimSpace(urgency))
+	if err != nil || u < 1 || u > 3 {
+		return "", false
+	}
+	return strconv.Itoa(i + u - 1), true
+}
+
+// AlertWorkNote renders a summary of an alert for a work note on an
+// incident that covers it, such as a daily digest or a suppressing parent,
+// or on its own incident when that is reopened or resolved.
+func (t *Transformer) AlertWorkNote(alert models.Alert) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("Alert: %s\n", alert.Labels["alertname"]))
+	b.WriteString(fmt.Sprintf("Severity: %s\n", t.Severity(alert)))
+	if namespace := alert.Labels["namespace"]; namespace != "" {
+		b.WriteString(fmt.Sprintf("Namespace: %s\n", namespace))
+	}
+	b.WriteString(fmt.Sprintf("Started At: %s\n", alert.StartsAt.UTC().Format("2006-01-02 15:04:05 UTC")))
+	if summary := alert.Annotations["summary"]; summary != "" {
+		b.WriteString(fmt.Sprintf("Summary: %s\n", summary))
+	}
+	if description := alert.Annotations["description"]; description != "" {
+		b.WriteString(fmt.Sprintf("Description: %s\n", description))
+	}
+
+	return b.String()
+}
+
+// maxShortDescriptionLen is the length of ServiceNow's short_description
+// column, in characters.
+const maxShortDescriptionLen = 160
+
+// shortDescription renders SHORT_DESCRIPTION_TEMPLATE, if one is configured,
+// against data with its cluster, alertname, and namespace cased per
+// SHORT_DESCRIPTION_CASE, trimmed and truncated to the column length.
+// Otherwise, or if the template fails, it returns the built-in format.
+func (t *Transformer) shortDescription(data models.DescriptionData) string {
+	if t.shortDescriptionTmpl != nil {
+		if data.Cluster == "" {
+			data.Cluster = "unknown-cluster"
+		}
+		data.Cluster = t.applyCase("cluster", data.Cluster)
+		data.AlertName = t.applyCase("alertname", data.AlertName)
+		data.Namespace = t.applyCase("namespace", data.Namespace)
+
+		var sb strings.Builder
+		err := t.shortDescriptionTmpl.Execute(&sb, data)
+		if err == nil {
+			return truncateRunes(strings.TrimSpace(sb.String()), maxShortDescriptionLen)
+		}
+		t.logger.Warn("using default short description",
+			"alertname", data.AlertName,
+			"error", err,
+		)
+	}
+	return t.buildShortDescription(data.Cluster, data.AlertName, data.Namespace)
+}
+
+// truncatedMarker ends a description cut to DESCRIPTION_MAX_LENGTH.
+const truncatedMarker = "… [truncated]"
+
+// truncateDescription cuts s to DESCRIPTION_MAX_LENGTH characters, marker
+// included, ending it with truncatedMarker.
+func (t *Transformer) truncateDescription(s string) string {
+	n := t.cfg.DescriptionMaxLen
+	if n <= 0 {
+		n = config.DefaultDescriptionMaxLen
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	markerLen := utf8.RuneCountInString(truncatedMarker)
+	if n <= markerLen {
+		return truncateRunes(s, n)
+	}
+	return truncateRunes(s, n-markerLen) + truncatedMarker
+}
+
+// truncateRunes shortens s to at most n characters without splitting a
+// multibyte character.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
+
+// buildShortDescription creates the short_description field for ServiceNow,
+// with each component cased per SHORT_DESCRIPTION_CASE. Parent lookups for
+// suppression build their prefix here too, so they match the same casing.
 func (t *Transformer) buildShortDescription(cluster, alertname, namespace string) string {
 	if cluster == "" {
 		cluster = "unknown-cluster"
 	}
+	cluster = t.applyCase("cluster", cluster)
+	alertname = t.applyCase("alertname", alertname)
+	namespace = t.applyCase("namespace", namespace)
 	if namespace != "" {
 		return fmt.Sprintf("[%s] %s in namespace: %s", cluster, alertname, namespace)
 	}
 	return fmt.Sprintf("[%s] %s", cluster, alertname)
 }
 
+// applyCase applies the SHORT_DESCRIPTION_CASE casing configured for a
+// short_description component to value.
+func (t *Transformer) applyCase(part, value string) string {
+	switch t.cfg.ShortDescriptionCase[part] {
+	case config.CaseLower:
+		return strings.ToLower(value)
+	case config.CaseUpper:
+		return strings.ToUpper(value)
+	case config.CaseTitle:
+		return titleCase(value)
+	default:
+		return value
+	}
+}
+
+// titleCase upper-cases the first letter of each word in s and lower-cases
+// the rest. Words are runs of letters and digits.
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	startOfWord := true
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			startOfWord = true
+		case startOfWord:
+			r = unicode.ToUpper(r)
+			startOfWord = false
+		default:
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 // extractClusterName determines the cluster name from alert labels or GeneratorURL.
 // It first checks the configured ClusterLabelKey, then attempts to extract
 // the cluster name from the GeneratorURL hostname (apps.<cluster>.<domain> pattern).
 func (t *Transformer) extractClusterName(alert models.Alert) string {
+	cluster, err := t.clusterName(alert)
+	if err != nil {
+		reason := "no_cluster"
+		if errors.Is(err, errMalformedURL) {
+			reason = "malformed"
+		}
+		t.metrics.GeneratorURLFailures.WithLabelValues(reason).Inc()
+		t.logger.Debug("could not extract cluster name from GeneratorURL",
+			"alertname", alert.Labels["alertname"],
+			"generator_url", alert.GeneratorURL,
+			"error", err,
+		)
+	}
+	return cluster
+}
+
+// clusterName resolves the cluster like extractClusterName but without
+// recording failures, for callers that look it up more than once per alert.
+func (t *Transformer) clusterName(alert models.Alert) (string, error) {
 	// First, try the configured label
 	if cluster := alert.Labels[t.cfg.ClusterLabelKey]; cluster != "" {
-		return cluster
+		return cluster, nil
 	}
 
-	// Fallback: extract from GeneratorURL (OpenShift pattern: apps.<cluster>.<domain>)
+	// Fallback: extract from GeneratorURL, through CLUSTER_URL_REGEX if set
+	// or the OpenShift pattern apps.<cluster>.<domain>
 	if alert.GeneratorURL != "" {
-		if cluster := extractClusterFromURL(alert.GeneratorURL); cluster != "" {
-			return cluster
+		if t.clusterURLRegex != nil {
+			return extractClusterWithRegex(t.clusterURLRegex, alert.GeneratorURL)
 		}
+		return extractClusterFromURL(alert.GeneratorURL)
 	}
 
-	return ""
+	return "", nil
+}
+
+var (
+	errMalformedURL   = errors.New("malformed URL")
+	errNoClusterURL   = errors.New("no .apps.<cluster> segment in hostname")
+	errNoClusterMatch = errors.New("CLUSTER_URL_REGEX did not match")
+)
+
+// extractClusterWithRegex extracts the cluster name from a URL with a
+// CLUSTER_URL_REGEX, returning its "cluster" group. It returns
+// errNoClusterMatch if the regex doesn't match or the group is empty.
+func extractClusterWithRegex(re *regexp.Regexp, rawURL string) (string, error) {
+	match := re.FindStringSubmatch(rawURL)
+	if match == nil {
+		return "", errNoClusterMatch
+	}
+	cluster := match[re.SubexpIndex("cluster")]
+	if cluster == "" {
+		return "", errNoClusterMatch
+	}
+	return cluster, nil
 }
 
 // extractClusterFromURL extracts the cluster name from an OpenShift-style URL.
 // Expected pattern: https://<app>.apps.<cluster>.<domain>/...
-// Returns empty string if pattern doesn't match.
-func extractClusterFromURL(rawURL string) string {
+// URLs without a scheme (e.g. prometheus:9090/graph) are treated as http so
+// the host is not mistaken for a scheme. It returns errMalformedURL if the
+// URL cannot be parsed and errNoClusterURL if the pattern doesn't match.
+func extractClusterFromURL(rawURL string) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return ""
+		return "", fmt.Errorf("%w: %v", errMalformedURL, err)
 	}
 
 	host := parsed.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("%w: missing host", errMalformedURL)
+	}
+
 	// Look for ".apps." in the hostname
 	appsIdx := strings.Index(host, ".apps.")
 	if appsIdx == -1 {
-		return ""
+		return "", errNoClusterURL
 	}
 
 	// Extract everything after ".apps."
@@ -102,14 +660,32 @@ func extractClusterFromURL(rawURL string) string {
 	// The cluster name is the first segment before the next dot
 	dotIdx := strings.Index(afterApps, ".")
 	if dotIdx == -1 {
-		return afterApps // No more dots, entire remainder is cluster name
+		return afterApps, nil // No more dots, entire remainder is cluster name
 	}
 
-	return afterApps[:dotIdx]
+	return afterApps[:dotIdx], nil
 }
 
-// buildDescription creates the detailed description field for ServiceNow.
-func (t *Transformer) buildDescription(alert models.Alert, cluster, environment, severity, namespace, pod, container string) string {
+// buildDescription creates the detailed description field for ServiceNow,
+// from DESCRIPTION_TEMPLATE if one is configured and renders, otherwise in
+// the built-in layout.
+func (t *Transformer) buildDescription(data models.DescriptionData) string {
+	if t.descriptionTmpl != nil {
+		var sb strings.Builder
+		err := t.descriptionTmpl.Execute(&sb, data)
+		if err == nil {
+			return sb.String()
+		}
+		t.logger.Warn("using default description layout",
+			"alertname", data.AlertName,
+			"error", err,
+		)
+	}
+
+	alert, cluster, namespace := data.Alert, data.Cluster, data.Namespace
+	environment, severity := data.Environment, data.Severity
+	pod, container := data.Pod, data.Container
+
 	var b strings.Builder
 
 	// Header section
@@ -119,15 +695,8 @@ func (t *Transformer) buildDescription(alert models.Alert, cluster, environment,
 	b.WriteString(fmt.Sprintf("Severity: %s\n", severity))
 	b.WriteString(fmt.Sprintf("Started At: %s\n", alert.StartsAt.UTC().Format("2006-01-02 15:04:05 UTC")))
 
-	// Summary section
-	if summary := alert.Annotations["summary"]; summary != "" {
-		b.WriteString(fmt.Sprintf("\nSummary:\n%s\n", summary))
-	}
-
-	// Description section
-	if desc := alert.Annotations["description"]; desc != "" {
-		b.WriteString(fmt.Sprintf("\nDescription:\n%s\n", desc))
-	}
+	// Annotation sections
+	t.writeAnnotations(&b, alert)
 
 	// Resource information
 	if namespace != "" || pod != "" || container != "" {
@@ -144,9 +713,8 @@ func (t *Transformer) buildDescription(alert models.Alert, cluster, environment,
 	}
 
 	// OpenShift Console link
-	if cluster != "" && namespace != "" {
-		consoleURL := t.buildConsoleURL(cluster, namespace)
-		b.WriteString(fmt.Sprintf("\nOpenShift Console: %s\n", consoleURL))
+	if data.ConsoleURL != "" {
+		b.WriteString(fmt.Sprintf("\nOpenShift Console: %s\n", data.ConsoleURL))
 	}
 
 	// Prometheus link
@@ -154,6 +722,23 @@ func (t *Transformer) buildDescription(alert models.Alert, cluster, environment,
 		b.WriteString(fmt.Sprintf("\nPrometheus Link: %s\n", alert.GeneratorURL))
 	}
 
+	// Grafana details, present for alerts received on the Grafana webhook
+	value := alert.Annotations[annotationGrafanaValueString]
+	dashboard := alert.Annotations[annotationGrafanaDashboardURL]
+	panel := alert.Annotations[annotationGrafanaPanelURL]
+	if value != "" || dashboard != "" || panel != "" {
+		b.WriteString("\nGrafana:\n")
+		if value != "" {
+			b.WriteString(fmt.Sprintf("  Values: %s\n", value))
+		}
+		if dashboard != "" {
+			b.WriteString(fmt.Sprintf("  Dashboard: %s\n", dashboard))
+		}
+		if panel != "" {
+			b.WriteString(fmt.Sprintf("  Panel: %s\n", panel))
+		}
+	}
+
 	// All labels
 	b.WriteString("\nAll Labels:\n")
 	keys := make([]string, 0, len(alert.Labels))
@@ -168,6 +753,57 @@ func (t *Transformer) buildDescription(alert models.Alert, cluster, environment,
 	return b.String()
 }
 
+// writeAnnotations renders the summary and description annotations or, when
+// DESCRIPTION_ANNOTATIONS is set, exactly the listed annotations in order.
+// Each gets its own section headed by its capitalized key.
+func (t *Transformer) writeAnnotations(b *strings.Builder, alert models.Alert) {
+	keys := t.cfg.DescriptionAnnotations
+	if len(keys) == 0 {
+		keys = defaultDescriptionAnnotations
+	}
+
+	for _, key := range keys {
+		if value := alert.Annotations[key]; value != "" {
+			b.WriteString(fmt.Sprintf("\n%s:\n%s\n", capitalize(key), value))
+		}
+	}
+}
+
+// defaultDescriptionAnnotations are rendered when no allowlist is configured.
+var defaultDescriptionAnnotations = []string{"summary", "description"}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// consoleURL returns the CONSOLE_LINK_TEMPLATE link for alert, or the
+// namespace link if no template is set or it renders to nothing, as it
+// should when the labels it links to are missing.
+func (t *Transformer) consoleURL(alert models.Alert, cluster, namespace string) string {
+	if t.consoleLinkTmpl != nil {
+		var sb strings.Builder
+		err := t.consoleLinkTmpl.Execute(&sb, models.ConsoleLinkData{
+			Cluster:   cluster,
+			Namespace: namespace,
+			Labels:    alert.Labels,
+		})
+		if err != nil {
+			t.logger.Warn("using namespace console link",
+				"alertname", alert.Labels["alertname"],
+				"error", err,
+			)
+		} else if link := strings.TrimSpace(sb.String()); link != "" {
+			return link
+		}
+	}
+	return t.buildConsoleURL(cluster, namespace)
+}
+
 // buildConsoleURL generates an OpenShift console URL for the namespace.
 func (t *Transformer) buildConsoleURL(cluster, namespace string) string {
 	// Extract base domain from cluster name or use a standard pattern
@@ -175,9 +811,182 @@ func (t *Transformer) buildConsoleURL(cluster, namespace string) string {
 		url.PathEscape(cluster), url.PathEscape(namespace))
 }
 
+// tableNamePattern matches the characters allowed in a ServiceNow table name.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// fingerprintPattern matches the fingerprints Alertmanager sends: 16
+// lower-case hex characters.
+var fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// validFingerprint reports whether fp looks like an Alertmanager fingerprint.
+// Anything else, such as a value carrying encoded query operators, is not
+// trusted as a correlation ID.
+func validFingerprint(fp string) bool {
+	return fingerprintPattern.MatchString(fp)
+}
+
+// EndpointPath returns the Table API path for the table named by the alert's
+// SERVICENOW_TABLE_LABEL label, such as snow_table=change_request, built
+// alongside SERVICENOW_ENDPOINT_PATH. It returns "" when the alert carries no
+// such label, meaning the configured table. Invalid table names are ignored.
+func (t *Transformer) EndpointPath(alert models.Alert) string {
+	if t.cfg.ServiceNowTableLabel == "" {
+		return ""
+	}
+	table := alert.Labels[t.cfg.ServiceNowTableLabel]
+	if table == "" {
+		return ""
+	}
+	if !tableNamePattern.MatchString(table) {
+		t.logger.Warn("ignoring invalid ServiceNow table label",
+			"alertname", alert.Labels["alertname"],
+			"label", t.cfg.ServiceNowTableLabel,
+			"table", table,
+		)
+		return ""
+	}
+
+	base := path.Dir(t.cfg.ServiceNowEndpointPath)
+	if base == "." || base == "/" {
+		base = "/api/now/table"
+	}
+	return base + "/" + table
+}
+
+// CorrelationID returns the correlation ID for a normalized alert, hashing
+// only the labels in CORRELATION_LABELS, when set, and leaving out those in
+// CORRELATION_IGNORE_LABELS. With
+// CORRELATION_INCLUDE_CLUSTER, an alert without the cluster label is hashed
+// as if it carried the cluster extracted from its GeneratorURL, so the same
+// alert from two clusters gets distinct IDs while alerts that already have
+// the label keep theirs. With CORRELATION_SALT_ANNOTATION, the value of that
+// annotation is hashed too, so rules sharing labels can be told apart.
+// With CORRELATION_SOURCE=fingerprint the alert's fingerprint, after
+// CORRELATION_PREFIX, is the ID instead; alerts without a valid one are
+// hashed.
+func (t *Transformer) CorrelationID(alert models.Alert) string {
+	if t.cfg.CorrelationSource == config.CorrelationSourceFingerprint {
+		if validFingerprint(alert.Fingerprint) {
+			return t.cfg.CorrelationPrefix + alert.Fingerprint
+		}
+		t.logger.Debug("alert has no valid fingerprint, hashing its labels",
+			"alertname", alert.Labels["alertname"],
+			"fingerprint", alert.Fingerprint,
+		)
+	}
+
+	alertname := alert.Labels["alertname"]
+	labels := t.hashedLabels(alert)
+
+	if t.cfg.CorrelationIncludeCluster && alert.Labels[t.cfg.ClusterLabelKey] == "" {
+		if cluster, _ := t.clusterName(alert); cluster != "" {
+			labels = withLabel(labels, t.cfg.ClusterLabelKey, cluster)
+		}
+	}
+	if name := t.cfg.CorrelationSaltAnnotation; name != "" {
+		if salt := alert.Annotations[name]; salt != "" {
+			labels = withLabel(labels, correlationSaltKey, salt)
+		}
+	}
+	return t.correlationID(alertname, labels)
+}
+
+// hashedLabels returns the alert labels that go into a label-hash
+// correlation ID, as selected by CORRELATION_LABELS and
+// CORRELATION_IGNORE_LABELS.
+func (t *Transformer) hashedLabels(alert models.Alert) map[string]string {
+	return withoutLabels(onlyLabels(alert.Labels, t.cfg.CorrelationLabels), t.cfg.CorrelationIgnoreLabels)
+}
+
+// correlationLabels returns the labels an alert's correlation ID depends on:
+// all of them when the ID is Alertmanager's fingerprint, else hashedLabels.
+func (t *Transformer) correlationLabels(alert models.Alert) map[string]string {
+	if t.cfg.CorrelationSource == config.CorrelationSourceFingerprint && validFingerprint(alert.Fingerprint) {
+		return alert.Labels
+	}
+	return t.hashedLabels(alert)
+}
+
+// withLabel returns a copy of labels with name set to value.
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[name] = value
+	return out
+}
+
+// onlyLabels returns the labels named in keep. labels is returned as is when
+// keep is empty.
+func onlyLabels(labels map[string]string, keep []string) map[string]string {
+	if len(keep) == 0 {
+		return labels
+	}
+	kept := make(map[string]string, len(keep))
+	for _, name := range keep {
+		if v, ok := labels[name]; ok {
+			kept[name] = v
+		}
+	}
+	return kept
+}
+
+// withoutLabels returns labels minus the names in ignore. labels is returned
+// as is when there is nothing to ignore.
+func withoutLabels(labels map[string]string, ignore []string) map[string]string {
+	if len(ignore) == 0 {
+		return labels
+	}
+	kept := make(map[string]string, len(labels))
+	for k, v := range labels {
+		kept[k] = v
+	}
+	for _, name := range ignore {
+		delete(kept, name)
+	}
+	return kept
+}
+
+// Keys carrying CORRELATION_ENVIRONMENT and the CORRELATION_SALT_ANNOTATION
+// value into the hash. Labels starting with __ are reserved by Prometheus and
+// dropped before alerts are sent, so they can't collide with a real label.
+const (
+	correlationEnvironmentKey = "__correlation_environment__"
+	correlationSaltKey        = "__correlation_salt__"
+)
+
+// correlationID hashes alertname and labels like GenerateCorrelationID, along
+// with CORRELATION_ENVIRONMENT if set, keeping CORRELATION_HASH_LEN hex
+// characters and prepending CORRELATION_PREFIX.
+func (t *Transformer) correlationID(alertname string, labels map[string]string) string {
+	n := t.cfg.CorrelationHashLen
+	if n <= 0 {
+		n = config.DefaultCorrelationHashLen
+	}
+	if env := t.cfg.CorrelationEnvironment; env != "" {
+		labels = withLabel(labels, correlationEnvironmentKey, env)
+	}
+	return t.cfg.CorrelationPrefix + correlationHash(alertname, labels)[:min(n, sha256.Size*2)]
+}
+
 // GenerateCorrelationID creates a deterministic correlation ID from alert data.
 // This ensures the same alert always produces the same ID across multiple replicas.
+// It uses the default length and no prefix; see Transformer.CorrelationID.
 func GenerateCorrelationID(alertname string, labels map[string]string) string {
+	return correlationHash(alertname, labels)[:config.DefaultCorrelationHashLen]
+}
+
+// GenerateCorrelationIDIgnoring is GenerateCorrelationID with the labels in
+// ignore left out of the hash. The alertname is always hashed, so ignoring
+// every label still yields one ID per alertname.
+func GenerateCorrelationIDIgnoring(alertname string, labels map[string]string, ignore []string) string {
+	return GenerateCorrelationID(alertname, withoutLabels(labels, ignore))
+}
+
+// correlationHash returns the hex SHA256 of alertname and the labels sorted
+// by key, so the result does not depend on label order.
+func correlationHash(alertname string, labels map[string]string) string {
 	// Sort label keys for deterministic output
 	keys := make([]string, 0, len(labels))
 	for k := range labels {
@@ -193,7 +1002,6 @@ func GenerateCorrelationID(alertname string, labels map[string]string) string {
 		b.WriteString(labels[k])
 	}
 
-	// SHA256 hash, truncate to 16 hex chars (8 bytes)
 	hash := sha256.Sum256([]byte(b.String()))
-	return hex.EncodeToString(hash[:8])
+	return hex.EncodeToString(hash[:])
 }
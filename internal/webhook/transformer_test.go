@@ -1,11 +1,18 @@
 package webhook
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/cragr/alert2snow-agent/internal/config"
+	"github.com/cragr/alert2snow-agent/internal/metrics"
 	"github.com/cragr/alert2snow-agent/internal/models"
 )
 
@@ -75,110 +82,1352 @@ func TestGenerateCorrelationID_DifferentAlerts(t *testing.T) {
 	}
 }
 
+func TestGenerateCorrelationIDIgnoring(t *testing.T) {
+	before := map[string]string{"alertname": "KubePodCrashLooping", "namespace": "apps", "pod": "web-7d9f8-abcde"}
+	after := map[string]string{"alertname": "KubePodCrashLooping", "namespace": "apps", "pod": "web-7d9f8-xyz12"}
+	ignore := []string{"pod", "instance", "__name__"}
+
+	if GenerateCorrelationID("KubePodCrashLooping", before) == GenerateCorrelationID("KubePodCrashLooping", after) {
+		t.Fatal("expected the pod label to change the ID when not ignored")
+	}
+	if GenerateCorrelationIDIgnoring("KubePodCrashLooping", before, ignore) != GenerateCorrelationIDIgnoring("KubePodCrashLooping", after, ignore) {
+		t.Error("expected pod churn to keep the same ID when pod is ignored")
+	}
+	if GenerateCorrelationIDIgnoring("KubePodCrashLooping", before, nil) != GenerateCorrelationID("KubePodCrashLooping", before) {
+		t.Error("expected no ignored labels to match GenerateCorrelationID")
+	}
+	if _, ok := before["pod"]; !ok {
+		t.Error("GenerateCorrelationIDIgnoring modified the labels")
+	}
+
+	// With every label ignored the ID depends on the alertname alone.
+	all := []string{"alertname", "namespace", "pod"}
+	if got, want := GenerateCorrelationIDIgnoring("KubePodCrashLooping", before, all), GenerateCorrelationID("KubePodCrashLooping", nil); got != want {
+		t.Errorf("all labels ignored: got %q, want %q", got, want)
+	}
+	if GenerateCorrelationIDIgnoring("KubePodCrashLooping", before, all) == GenerateCorrelationIDIgnoring("KubePodNotReady", before, all) {
+		t.Error("expected different alertnames to keep different IDs with every label ignored")
+	}
+}
+
+func TestTransformer_CorrelationID_IgnoreLabels(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:         "cluster",
+		EnvironmentLabelKey:     "environment",
+		CorrelationIgnoreLabels: []string{"pod"},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := func(pod string) models.Alert {
+		return models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod", "pod": pod},
+		}
+	}
+	if transformer.CorrelationID(alert("web-1")) != transformer.CorrelationID(alert("web-2")) {
+		t.Error("expected alerts differing only in an ignored label to share an ID")
+	}
+	if incident := transformer.Transform(alert("web-1"), ""); incident.CorrelationID != transformer.CorrelationID(alert("web-2")) {
+		t.Error("expected Transform to use the ID without ignored labels")
+	}
+}
+
+func TestTransformer_CorrelationID_Labels(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		CorrelationLabels:   []string{"alertname", "namespace", "cluster"},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := func(namespace, pod string) models.Alert {
+		return models.Alert{
+			Status: "firing",
+			Labels: map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod", "namespace": namespace, "pod": pod},
+		}
+	}
+	web1 := transformer.CorrelationID(alert("apps", "web-1"))
+	if web1 != transformer.CorrelationID(alert("apps", "web-2")) {
+		t.Error("expected a changed pod label not to change the ID when pod is not a correlation label")
+	}
+	if web1 == transformer.CorrelationID(alert("batch", "web-1")) {
+		t.Error("expected a changed namespace label to change the ID")
+	}
+	want := GenerateCorrelationID("KubePodCrashLooping", map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod", "namespace": "apps"})
+	if web1 != want {
+		t.Errorf("CorrelationID() = %q, want the hash of the listed labels %q", web1, want)
+	}
+	if web1 != transformer.CorrelationID(alert("apps", "web-1")) {
+		t.Error("expected the ID to be deterministic")
+	}
+}
+
+func TestTransformer_CorrelationID_Fingerprint(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		CorrelationSource:   config.CorrelationSourceFingerprint,
+		CorrelationPrefix:   "ocp-",
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	firing := models.Alert{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighLatency", "value": "0.91"},
+		Fingerprint: "4f9a7c2e1b3d5a60",
+	}
+	resolved := models.Alert{
+		Status:      "resolved",
+		Labels:      map[string]string{"alertname": "HighLatency", "value": "0.12"},
+		Fingerprint: "4f9a7c2e1b3d5a60",
+	}
+	if got := transformer.CorrelationID(firing); got != "ocp-4f9a7c2e1b3d5a60" {
+		t.Errorf("CorrelationID() = %q, want the prefixed fingerprint", got)
+	}
+	if transformer.CorrelationID(firing) != transformer.CorrelationID(resolved) {
+		t.Error("expected alerts with the same fingerprint to share an ID despite changed labels")
+	}
+	if incident := transformer.Transform(firing, ""); incident.CorrelationID != "ocp-4f9a7c2e1b3d5a60" {
+		t.Errorf("Transform() correlation_id = %q, want the fingerprint", incident.CorrelationID)
+	}
+
+	firing.Fingerprint = ""
+	if got, want := transformer.CorrelationID(firing), "ocp-"+GenerateCorrelationID("HighLatency", firing.Labels); got != want {
+		t.Errorf("CorrelationID() without fingerprint = %q, want label hash %q", got, want)
+	}
+
+	// A fingerprint that isn't Alertmanager's 16 hex characters, such as one
+	// smuggling encoded query operators, is not used.
+	for _, fp := range []string{"x^ORcorrelation_idISNOTEMPTY", "a,b", "4F9A7C2E1B3D5A60", strings.Repeat("4f9a7c2e1b3d5a60", 8)} {
+		firing.Fingerprint = fp
+		if got, want := transformer.CorrelationID(firing), "ocp-"+GenerateCorrelationID("HighLatency", firing.Labels); got != want {
+			t.Errorf("CorrelationID() with fingerprint %q = %q, want label hash %q", fp, got, want)
+		}
+	}
+}
+
+func TestTransformer_CorrelationID_Environment(t *testing.T) {
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "KubePodCrashLooping", "namespace": "apps", "pod": "web-1"},
+	}
+	idFor := func(env string) string {
+		cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", CorrelationEnvironment: env}
+		return NewTransformer(cfg, metrics.New(), newTestLogger()).CorrelationID(alert)
+	}
+
+	if got, want := idFor(""), GenerateCorrelationID("KubePodCrashLooping", alert.Labels); got != want {
+		t.Errorf("CorrelationID() without environment = %q, want %q", got, want)
+	}
+	prod, dev := idFor("prod"), idFor("dev")
+	if prod == dev {
+		t.Errorf("expected identical labels to get distinct IDs per environment, both got %q", prod)
+	}
+	if prod == idFor("") {
+		t.Error("expected an environment to change the correlation ID")
+	}
+	if prod != idFor("prod") {
+		t.Error("expected the same environment to give a stable ID")
+	}
+}
+
+func TestTransformer_CorrelationID_SaltAnnotation(t *testing.T) {
+	alert := func(salt string) models.Alert {
+		a := models.Alert{
+			Status:      "firing",
+			Labels:      map[string]string{"alertname": "QueueBacklog", "namespace": "apps"},
+			Annotations: map[string]string{"summary": "Queue backlog growing"},
+		}
+		if salt != "" {
+			a.Annotations["correlation_salt"] = salt
+		}
+		return a
+	}
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", CorrelationSaltAnnotation: "correlation_salt"}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	if transformer.CorrelationID(alert("ingest")) == transformer.CorrelationID(alert("billing")) {
+		t.Error("expected alerts with identical labels but different salt to get distinct IDs")
+	}
+	if transformer.CorrelationID(alert("ingest")) != transformer.CorrelationID(alert("ingest")) {
+		t.Error("expected the same salt to give a stable ID")
+	}
+	if got, want := transformer.CorrelationID(alert("")), GenerateCorrelationID("QueueBacklog", alert("").Labels); got != want {
+		t.Errorf("CorrelationID() without the annotation = %q, want %q", got, want)
+	}
+
+	cfg.CorrelationSaltAnnotation = ""
+	if transformer.CorrelationID(alert("ingest")) != transformer.CorrelationID(alert("billing")) {
+		t.Error("expected the salt to be ignored when CORRELATION_SALT_ANNOTATION is unset")
+	}
+}
+
+func TestTransformer_Transform_ConsoleLinkTemplate(t *testing.T) {
+	const podLink = `{{with .Labels.pod}}https://console.{{$.Cluster}}.example.com/k8s/ns/{{pathEscape $.Namespace}}/pods/{{pathEscape .}}{{end}}`
+	namespaceLink := "https://console-openshift-console.apps.prod.example.com/k8s/cluster/projects/payments"
+
+	tests := []struct {
+		name     string
+		template string
+		labels   map[string]string
+		want     string
+	}{
+		{
+			name:     "pod link",
+			template: podLink,
+			labels:   map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod", "namespace": "payments", "pod": "api-7d9f"},
+			want:     "https://console.prod.example.com/k8s/ns/payments/pods/api-7d9f",
+		},
+		{
+			name:     "falls back to namespace without pod label",
+			template: podLink,
+			labels:   map[string]string{"alertname": "KubeQuotaExceeded", "cluster": "prod", "namespace": "payments"},
+			want:     namespaceLink,
+		},
+		{
+			name:   "namespace link without template",
+			labels: map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod", "namespace": "payments", "pod": "api-7d9f"},
+			want:   namespaceLink,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", ConsoleLinkTemplate: tt.template}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+			incident := transformer.Transform(models.Alert{Status: "firing", Labels: tt.labels}, "")
+			if want := "OpenShift Console: " + tt.want + "\n"; !strings.Contains(incident.Description, want) {
+				t.Errorf("description missing %q:\n%s", want, incident.Description)
+			}
+		})
+	}
+}
+
+func TestTransformer_IncidentURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		tablePath string
+		want      string
+	}{
+		{
+			name: "default template and table",
+			want: "https://example.service-now.com/nav_to.do?uri=incident.do?sys_id=abc123",
+		},
+		{
+			name:      "routed table",
+			tablePath: "/api/now/table/change_request",
+			want:      "https://example.service-now.com/nav_to.do?uri=change_request.do?sys_id=abc123",
+		},
+		{
+			name:     "custom portal",
+			template: "{{.BaseURL}}/sp?id=ticket&table={{.Table}}&sys_id={{.SysID}}&number={{.Number}}",
+			want:     "https://example.service-now.com/sp?id=ticket&table=incident&sys_id=abc123&number=INC0001234",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ServiceNowBaseURL:      "https://example.service-now.com/",
+				ServiceNowEndpointPath: "/api/now/table/incident",
+				IncidentURLTemplate:    tt.template,
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+			if got := transformer.IncidentURL(tt.tablePath, "abc123", "INC0001234"); got != tt.want {
+				t.Errorf("IncidentURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformer_CorrelationID_PrefixAndLength(t *testing.T) {
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "KubePodCrashLooping", "namespace": "apps", "pod": "web-1"},
+	}
+	reordered := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"pod": "web-1", "namespace": "apps", "alertname": "KubePodCrashLooping"},
+	}
+
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment"}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	if got, want := transformer.CorrelationID(alert), GenerateCorrelationID("KubePodCrashLooping", alert.Labels); got != want {
+		t.Errorf("CorrelationID() with defaults = %q, want %q", got, want)
+	}
+
+	cfg.CorrelationPrefix = "ocp-prod-"
+	cfg.CorrelationHashLen = 16
+	short := transformer.CorrelationID(alert)
+	if !strings.HasPrefix(short, "ocp-prod-") {
+		t.Errorf("CorrelationID() = %q, want prefix %q", short, "ocp-prod-")
+	}
+	if got, want := short, "ocp-prod-"+GenerateCorrelationID("KubePodCrashLooping", alert.Labels); got != want {
+		t.Errorf("CorrelationID() = %q, want %q", got, want)
+	}
+
+	cfg.CorrelationHashLen = 32
+	long := transformer.CorrelationID(alert)
+	if len(long) != len("ocp-prod-")+32 {
+		t.Errorf("CorrelationID() length = %d, want %d", len(long), len("ocp-prod-")+32)
+	}
+	if long == short || !strings.HasPrefix(long, short) {
+		t.Errorf("expected the 32-char hash %q to extend the 16-char hash %q", long, short)
+	}
+	if again := transformer.CorrelationID(reordered); again != long {
+		t.Errorf("CorrelationID() depends on label order: %q != %q", again, long)
+	}
+
+	group := transformer.GroupCorrelationID(map[string]string{"alertname": "KubePodCrashLooping"})
+	if !strings.HasPrefix(group, "ocp-prod-") || len(group) != len("ocp-prod-")+32 {
+		t.Errorf("GroupCorrelationID() = %q, want prefix and 32-char hash", group)
+	}
+}
+
+func TestTransformer_EndpointPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpointPath string
+		tableLabel   string
+		labels       map[string]string
+		want         string
+	}{
+		{
+			name:         "no table label",
+			endpointPath: "/api/now/table/incident",
+			tableLabel:   "snow_table",
+			labels:       map[string]string{"alertname": "TargetDown"},
+			want:         "",
+		},
+		{
+			name:         "table label",
+			endpointPath: "/api/now/table/incident",
+			tableLabel:   "snow_table",
+			labels:       map[string]string{"alertname": "TargetDown", "snow_table": "change_request"},
+			want:         "/api/now/table/change_request",
+		},
+		{
+			name:         "custom endpoint base",
+			endpointPath: "/api/x_acme_proxy/table/incident",
+			tableLabel:   "snow_table",
+			labels:       map[string]string{"alertname": "TargetDown", "snow_table": "u_ops_event"},
+			want:         "/api/x_acme_proxy/table/u_ops_event",
+		},
+		{
+			name:         "invalid table name",
+			endpointPath: "/api/now/table/incident",
+			tableLabel:   "snow_table",
+			labels:       map[string]string{"alertname": "TargetDown", "snow_table": "../sys_user"},
+			want:         "",
+		},
+		{
+			name:         "label disabled",
+			endpointPath: "/api/now/table/incident",
+			labels:       map[string]string{"alertname": "TargetDown", "snow_table": "change_request"},
+			want:         "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ServiceNowEndpointPath: tt.endpointPath, ServiceNowTableLabel: tt.tableLabel}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+			if got := transformer.EndpointPath(models.Alert{Labels: tt.labels}); got != tt.want {
+				t.Errorf("EndpointPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformer_CorrelationID_IncludeCluster(t *testing.T) {
+	alertFrom := func(generatorURL string) models.Alert {
+		return models.Alert{
+			Status:       "firing",
+			Labels:       map[string]string{"alertname": "KubePodCrashLooping", "namespace": "apps"},
+			GeneratorURL: generatorURL,
+		}
+	}
+	east := alertFrom("https://console-openshift-console.apps.east.example.com/monitoring")
+	west := alertFrom("https://console-openshift-console.apps.west.example.com/monitoring")
+
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment"}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	if transformer.CorrelationID(east) != transformer.CorrelationID(west) {
+		t.Fatal("expected IDs to collide without CORRELATION_INCLUDE_CLUSTER")
+	}
+
+	cfg.CorrelationIncludeCluster = true
+	eastID, westID := transformer.CorrelationID(east), transformer.CorrelationID(west)
+	if eastID == westID {
+		t.Errorf("expected distinct IDs for east and west, both got %q", eastID)
+	}
+	if incident := transformer.Transform(east, ""); incident.CorrelationID != eastID {
+		t.Errorf("Transform() CorrelationID = %q, want %q", incident.CorrelationID, eastID)
+	}
+
+	// An alert carrying the cluster label keeps its existing ID, which is
+	// also the ID of the same alert whose cluster came from the URL.
+	labeled := alertFrom("")
+	labeled.Labels["cluster"] = "east"
+	if got, want := transformer.CorrelationID(labeled), GenerateCorrelationID("KubePodCrashLooping", labeled.Labels); got != want {
+		t.Errorf("CorrelationID() with cluster label = %q, want %q", got, want)
+	}
+	if transformer.CorrelationID(labeled) != eastID {
+		t.Error("expected the labeled and URL-derived east alerts to share an ID")
+	}
+}
+
 func TestTransformer_Transform(t *testing.T) {
 	cfg := &config.Config{
-		ClusterLabelKey:       "cluster",
-		EnvironmentLabelKey:   "environment",
-		ServiceNowCategory:    "software",
-		ServiceNowSubcategory: "openshift",
-		ServiceNowUrgency:     "3",
-		ServiceNowImpact:      "3",
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		ServiceNowCategory:    "software",
+		ServiceNowSubcategory: "openshift",
+		ServiceNowUrgency:     "3",
+		ServiceNowImpact:      "3",
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname":   "KubePodCrashLooping",
+			"cluster":     "production-cluster",
+			"environment": "prod",
+			"severity":    "warning",
+			"namespace":   "openshift-monitoring",
+			"pod":         "prometheus-k8s-0",
+			"container":   "prometheus",
+		},
+		Annotations: map[string]string{
+			"summary":     "Pod is crash looping",
+			"description": "Pod prometheus-k8s-0 is restarting frequently.",
+		},
+		StartsAt:     time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		GeneratorURL: "http://prometheus/graph?g0.expr=up",
+		Fingerprint:  "abc123",
+	}
+
+	incident := transformer.Transform(alert, "http://alertmanager")
+
+	// Check short description
+	expectedShortDesc := "[production-cluster] KubePodCrashLooping in namespace: openshift-monitoring"
+	if incident.ShortDescription != expectedShortDesc {
+		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, expectedShortDesc)
+	}
+
+	// Check impact and urgency (should always be 3)
+	if incident.Impact != "3" {
+		t.Errorf("Impact = %q, want %q", incident.Impact, "3")
+	}
+	if incident.Urgency != "3" {
+		t.Errorf("Urgency = %q, want %q", incident.Urgency, "3")
+	}
+
+	// Check category
+	if incident.Category != "software" {
+		t.Errorf("Category = %q, want %q", incident.Category, "software")
+	}
+	if incident.Subcategory != "openshift" {
+		t.Errorf("Subcategory = %q, want %q", incident.Subcategory, "openshift")
+	}
+
+	// Check correlation ID is generated
+	if incident.CorrelationID == "" {
+		t.Error("CorrelationID should not be empty")
+	}
+	if len(incident.CorrelationID) != 16 {
+		t.Errorf("CorrelationID length = %d, want 16", len(incident.CorrelationID))
+	}
+
+	// Check description contains expected content
+	if !strings.Contains(incident.Description, "Alert: KubePodCrashLooping") {
+		t.Error("Description should contain alert name")
+	}
+	if !strings.Contains(incident.Description, "Cluster: production-cluster") {
+		t.Error("Description should contain cluster")
+	}
+	if !strings.Contains(incident.Description, "Pod is crash looping") {
+		t.Error("Description should contain summary")
+	}
+	if !strings.Contains(incident.Description, "Namespace: openshift-monitoring") {
+		t.Error("Description should contain namespace")
+	}
+}
+
+func TestTransformer_Transform_MissingCluster(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		ServiceNowCategory:    "software",
+		ServiceNowSubcategory: "openshift",
+		ServiceNowUrgency:     "3",
+		ServiceNowImpact:      "3",
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname": "TestAlert",
+			"severity":  "warning",
+		},
+		Annotations: map[string]string{},
+		StartsAt:    time.Now(),
+	}
+
+	incident := transformer.Transform(alert, "")
+
+	expectedShortDesc := "[unknown-cluster] TestAlert"
+	if incident.ShortDescription != expectedShortDesc {
+		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, expectedShortDesc)
+	}
+}
+
+func TestTransformer_Transform_SeverityCategories(t *testing.T) {
+	cfg := &config.Config{
+		ServiceNowCategory:    "software",
+		ServiceNowSubcategory: "openshift",
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		SeverityCategories: map[string]config.CategoryOverride{
+			"critical": {Category: "outage", Subcategory: "platform"},
+			"Warning":  {Category: "degradation", Subcategory: "capacity"},
+			"none":     {Category: "informational"},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	tests := []struct {
+		severity        string
+		wantCategory    string
+		wantSubcategory string
+	}{
+		{severity: "critical", wantCategory: "outage", wantSubcategory: "platform"},
+		{severity: "warning", wantCategory: "degradation", wantSubcategory: "capacity"},
+		{severity: "CRITICAL", wantCategory: "outage", wantSubcategory: "platform"},
+		{severity: "none", wantCategory: "informational", wantSubcategory: "openshift"},
+		{severity: "info", wantCategory: "software", wantSubcategory: "openshift"},
+		{severity: "", wantCategory: "software", wantSubcategory: "openshift"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			alert := models.Alert{
+				Status: "firing",
+				Labels: map[string]string{
+					"alertname": "TestAlert",
+					"cluster":   "test-cluster",
+					"severity":  tt.severity,
+				},
+			}
+
+			incident := transformer.Transform(alert, "")
+			if incident.Category != tt.wantCategory {
+				t.Errorf("Category = %q, want %q", incident.Category, tt.wantCategory)
+			}
+			if incident.Subcategory != tt.wantSubcategory {
+				t.Errorf("Subcategory = %q, want %q", incident.Subcategory, tt.wantSubcategory)
+			}
+		})
+	}
+}
+
+func TestTransformer_Severity(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		DefaultSeverity:     "warning",
+		SeverityPatterns: map[string]string{
+			"Down$":       "critical",
+			"^KubeAPI":    "critical",
+			"^CPUThrott":  "info",
+			"^KubeAPIErr": "warning",
+		},
+		SeverityCategories: map[string]config.CategoryOverride{
+			"critical": {Category: "outage"},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	tests := []struct {
+		name         string
+		labels       map[string]string
+		wantSeverity string
+	}{
+		{
+			name:         "label wins over patterns",
+			labels:       map[string]string{"alertname": "TargetDown", "severity": "info"},
+			wantSeverity: "info",
+		},
+		{
+			name:         "inferred from alertname pattern",
+			labels:       map[string]string{"alertname": "TargetDown"},
+			wantSeverity: "critical",
+		},
+		{
+			name:         "first pattern in sorted order wins",
+			labels:       map[string]string{"alertname": "KubeAPIErrorBudgetBurn"},
+			wantSeverity: "critical",
+		},
+		{
+			name:         "missing severity uses default",
+			labels:       map[string]string{"alertname": "SomethingOdd"},
+			wantSeverity: "warning",
+		},
+		{
+			name:         "empty severity label uses default",
+			labels:       map[string]string{"alertname": "SomethingOdd", "severity": ""},
+			wantSeverity: "warning",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := models.Alert{Status: "firing", Labels: tt.labels}
+			if got := transformer.Severity(alert); got != tt.wantSeverity {
+				t.Errorf("Severity() = %q, want %q", got, tt.wantSeverity)
+			}
+		})
+	}
+
+	// The inferred severity drives the description and category mapping
+	// without changing the correlation ID.
+	alert := models.Alert{Status: "firing", Labels: map[string]string{"alertname": "TargetDown", "cluster": "test-cluster"}}
+	incident := transformer.Transform(alert, "")
+	if !strings.Contains(incident.Description, "Severity: critical") {
+		t.Errorf("expected inferred severity in description, got:\n%s", incident.Description)
+	}
+	if incident.Category != "outage" {
+		t.Errorf("Category = %q, want %q", incident.Category, "outage")
+	}
+	if incident.CorrelationID != GenerateCorrelationID("TargetDown", alert.Labels) {
+		t.Error("inferred severity changed the correlation ID")
+	}
+}
+
+func TestTransformer_Transform_PriorityOverride(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantPriority string
+		wantWarning  bool
+	}{
+		{name: "valid override", annotations: map[string]string{"snow_priority": "1"}, wantPriority: "1"},
+		{name: "surrounding whitespace", annotations: map[string]string{"snow_priority": " 2 "}, wantPriority: "2"},
+		{name: "out of range is ignored", annotations: map[string]string{"snow_priority": "9"}, wantWarning: true},
+		{name: "non-numeric is ignored", annotations: map[string]string{"snow_priority": "high"}, wantWarning: true},
+		{name: "no annotation", annotations: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &config.Config{
+				ServiceNowImpact:    "3",
+				ServiceNowUrgency:   "3",
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+			}
+			transformer := NewTransformer(cfg, metrics.New(), slog.New(slog.NewJSONHandler(&buf, nil)))
+
+			alert := models.Alert{
+				Status:      "firing",
+				Labels:      map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+				Annotations: tt.annotations,
+			}
+
+			incident := transformer.Transform(alert, "")
+			if incident.Priority != tt.wantPriority {
+				t.Errorf("Priority = %q, want %q", incident.Priority, tt.wantPriority)
+			}
+			if incident.Impact != "3" || incident.Urgency != "3" {
+				t.Errorf("impact/urgency changed: %q/%q", incident.Impact, incident.Urgency)
+			}
+			if gotWarning := strings.Contains(buf.String(), "ignoring invalid snow_priority annotation"); gotWarning != tt.wantWarning {
+				t.Errorf("warning logged = %v, want %v", gotWarning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestPriorityFor(t *testing.T) {
+	want := map[[2]string]string{
+		{"1", "1"}: "1", {"1", "2"}: "2", {"1", "3"}: "3",
+		{"2", "1"}: "2", {"2", "2"}: "3", {"2", "3"}: "4",
+		{"3", "1"}: "3", {"3", "2"}: "4", {"3", "3"}: "5",
+	}
+	for pair, wantPriority := range want {
+		if got, ok := priorityFor(pair[0], pair[1]); !ok || got != wantPriority {
+			t.Errorf("priorityFor(%q, %q) = %q, %v, want %q", pair[0], pair[1], got, ok, wantPriority)
+		}
+	}
+	for _, pair := range [][2]string{{"0", "1"}, {"1", "4"}, {"high", "1"}, {"", "2"}} {
+		if got, ok := priorityFor(pair[0], pair[1]); ok {
+			t.Errorf("priorityFor(%q, %q) = %q, want no priority", pair[0], pair[1], got)
+		}
+	}
+}
+
+func TestTransformer_Transform_SetPriority(t *testing.T) {
+	tests := []struct {
+		name         string
+		setPriority  bool
+		impact       string
+		annotations  map[string]string
+		wantPriority string
+	}{
+		{name: "disabled", impact: "1", wantPriority: ""},
+		{name: "computed", setPriority: true, impact: "1", wantPriority: "2"},
+		{name: "annotation wins", setPriority: true, impact: "1", annotations: map[string]string{"snow_priority": "4"}, wantPriority: "4"},
+		{name: "invalid impact", setPriority: true, impact: "9", wantPriority: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ServiceNowImpact:      tt.impact,
+				ServiceNowUrgency:     "2",
+				ServiceNowSetPriority: tt.setPriority,
+				ClusterLabelKey:       "cluster",
+				EnvironmentLabelKey:   "environment",
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+			incident := transformer.Transform(models.Alert{
+				Status:      "firing",
+				Labels:      map[string]string{"alertname": "TestAlert"},
+				Annotations: tt.annotations,
+			}, "")
+			if incident.Priority != tt.wantPriority {
+				t.Errorf("Priority = %q, want %q", incident.Priority, tt.wantPriority)
+			}
+		})
+	}
+}
+
+func TestTransformer_Transform_FieldLabelMap(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		FieldLabelMap: map[string]string{
+			"u_cluster":   "cluster",
+			"u_namespace": "namespace",
+			"u_team":      "team",
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname": "TestAlert",
+			"cluster":   "test-cluster",
+			"namespace": "default",
+		},
+	}
+
+	incident := transformer.Transform(alert, "")
+
+	want := map[string]string{"u_cluster": "test-cluster", "u_namespace": "default"}
+	if len(incident.ExtraFields) != len(want) {
+		t.Fatalf("ExtraFields = %v, want %v", incident.ExtraFields, want)
+	}
+	for field, value := range want {
+		if incident.ExtraFields[field] != value {
+			t.Errorf("ExtraFields[%q] = %q, want %q", field, incident.ExtraFields[field], value)
+		}
+	}
+	if _, ok := incident.ExtraFields["u_team"]; ok {
+		t.Error("expected u_team to be omitted when the team label is unset")
+	}
+}
+
+func TestTransformer_Transform_StaticExtraFields(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		ServiceNowExtraFields: map[string]string{
+			"cmdb_ci":  "abc123",
+			"u_source": "prometheus",
+			"u_team":   "platform",
+		},
+		FieldLabelMap: map[string]string{"u_team": "team"},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster", "team": "storage"},
+	}
+
+	want := map[string]string{"cmdb_ci": "abc123", "u_source": "prometheus", "u_team": "storage"}
+	incident := transformer.Transform(alert, "")
+	for field, value := range want {
+		if incident.ExtraFields[field] != value {
+			t.Errorf("ExtraFields[%q] = %q, want %q", field, incident.ExtraFields[field], value)
+		}
+	}
+
+	// Without the label, the static value is used.
+	delete(alert.Labels, "team")
+	if got := transformer.Transform(alert, "").ExtraFields["u_team"]; got != "platform" {
+		t.Errorf("ExtraFields[u_team] = %q, want static %q", got, "platform")
+	}
+
+	digest := transformer.DigestIncident("test-cluster", time.Now())
+	if digest.ExtraFields["cmdb_ci"] != "abc123" {
+		t.Errorf("expected static fields on the digest incident, got %v", digest.ExtraFields)
+	}
+	if cfg.ServiceNowExtraFields["u_team"] != "platform" {
+		t.Error("Transform modified the configured static fields")
+	}
+}
+
+func TestTransformer_Transform_DescriptionAnnotations(t *testing.T) {
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+		Annotations: map[string]string{
+			"summary":     "Pod is crash looping",
+			"description": "Very long generated text",
+			"runbook_url": "https://runbooks.example.com/crashloop",
+			"noise":       "internal debugging output",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		allowlist   []string
+		wantOrder   []string
+		wantMissing []string
+	}{
+		{
+			name:        "default renders summary and description",
+			wantOrder:   []string{"Summary:\nPod is crash looping", "Description:\nVery long generated text"},
+			wantMissing: []string{"Runbook_url:", "internal debugging output"},
+		},
+		{
+			name:        "allowlist renders listed annotations in order",
+			allowlist:   []string{"runbook_url", "summary"},
+			wantOrder:   []string{"Runbook_url:\nhttps://runbooks.example.com/crashloop", "Summary:\nPod is crash looping"},
+			wantMissing: []string{"Very long generated text", "internal debugging output"},
+		},
+		{
+			name:        "missing allowlisted annotations are skipped",
+			allowlist:   []string{"dashboard", "description"},
+			wantOrder:   []string{"Description:\nVery long generated text"},
+			wantMissing: []string{"Dashboard:", "Summary:", "Runbook_url:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ClusterLabelKey:        "cluster",
+				EnvironmentLabelKey:    "environment",
+				DescriptionAnnotations: tt.allowlist,
+			}
+			description := NewTransformer(cfg, metrics.New(), newTestLogger()).Transform(alert, "").Description
+
+			last := -1
+			for _, want := range tt.wantOrder {
+				i := strings.Index(description, want)
+				if i < 0 {
+					t.Errorf("expected description to contain %q, got:\n%s", want, description)
+					continue
+				}
+				if i < last {
+					t.Errorf("expected %q after the previous section", want)
+				}
+				last = i
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(description, missing) {
+					t.Errorf("expected description not to contain %q, got:\n%s", missing, description)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformer_Transform_AssignmentGroupMap(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:           "cluster",
+		EnvironmentLabelKey:       "environment",
+		ServiceNowAssignmentGroup: "default-group",
+		AssignmentGroupMap: []config.AssignmentGroupRule{
+			{Label: "team", Value: "platform", Group: "GROUP_SYS_A"},
+			{Label: "team", Value: "db", Group: "GROUP_SYS_B"},
+			{Label: "cluster", Value: "prod-east", Group: "GROUP_SYS_C"},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		wantGroup string
+	}{
+		{name: "first rule", labels: map[string]string{"team": "platform"}, wantGroup: "GROUP_SYS_A"},
+		{name: "later rule", labels: map[string]string{"team": "db"}, wantGroup: "GROUP_SYS_B"},
+		{
+			name:      "first matching rule wins across labels",
+			labels:    map[string]string{"team": "db", "cluster": "prod-east"},
+			wantGroup: "GROUP_SYS_B",
+		},
+		{
+			name:      "other label matches when the first does not",
+			labels:    map[string]string{"team": "web", "cluster": "prod-east"},
+			wantGroup: "GROUP_SYS_C",
+		},
+		{name: "no match falls back to default", labels: map[string]string{"team": "web"}, wantGroup: "default-group"},
+		{name: "label missing falls back to default", labels: map[string]string{}, wantGroup: "default-group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels := map[string]string{"alertname": "TestAlert"}
+			for k, v := range tt.labels {
+				labels[k] = v
+			}
+			incident := transformer.Transform(models.Alert{Status: "firing", Labels: labels}, "")
+			if incident.AssignmentGroup != tt.wantGroup {
+				t.Errorf("AssignmentGroup = %q, want %q", incident.AssignmentGroup, tt.wantGroup)
+			}
+		})
+	}
+}
+
+func TestTransformer_Transform_LabelFieldMap(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:           "cluster",
+		EnvironmentLabelKey:       "environment",
+		ServiceNowAssignmentGroup: "default-group",
+		ServiceNowExtraFields:     map[string]string{"u_namespace": "static"},
+		LabelFieldMap: map[string]string{
+			"team":      "assignment_group",
+			"namespace": "u_namespace",
+			"owner":     "u_owner",
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname": "TestAlert",
+			"cluster":   "test-cluster",
+			"namespace": "payments",
+			"team":      "payments-oncall",
+		},
+	}
+
+	raw, err := json.Marshal(transformer.Transform(alert, ""))
+	if err != nil {
+		t.Fatalf("failed to marshal incident: %v", err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+
+	if payload["assignment_group"] != "payments-oncall" {
+		t.Errorf("assignment_group = %q, want label value %q", payload["assignment_group"], "payments-oncall")
+	}
+	if payload["u_namespace"] != "payments" {
+		t.Errorf("u_namespace = %q, want label value %q", payload["u_namespace"], "payments")
+	}
+	if _, ok := payload["u_owner"]; ok {
+		t.Error("expected u_owner to be skipped when the owner label is missing")
+	}
+	if payload["correlation_id"] == "" {
+		t.Error("expected correlation_id to be kept")
+	}
+}
+
+func TestTransformer_Transform_FieldRules(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:           "cluster",
+		EnvironmentLabelKey:       "environment",
+		ServiceNowAssignmentGroup: "default-group",
+		ServiceNowUrgency:         "3",
+		FieldRules: []config.FieldRule{
+			{
+				Severity: "critical",
+				Labels:   map[string]string{"escalate": "true"},
+				Set:      map[string]string{"assignment_group": "senior-sre", "urgency": "1"},
+			},
+			{
+				Labels: map[string]string{"team": "payments"},
+				Set:    map[string]string{"assignment_group": "payments-oncall"},
+			},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		wantGroup string
+		wantUrg   string
+	}{
+		{
+			name:      "critical and escalate",
+			labels:    map[string]string{"severity": "Critical", "escalate": "true", "team": "payments"},
+			wantGroup: "senior-sre",
+			wantUrg:   "1",
+		},
+		{
+			name:      "escalate without critical falls through",
+			labels:    map[string]string{"severity": "warning", "escalate": "true", "team": "payments"},
+			wantGroup: "payments-oncall",
+			wantUrg:   "3",
+		},
+		{
+			name:      "no match",
+			labels:    map[string]string{"severity": "critical", "escalate": "false"},
+			wantGroup: "default-group",
+			wantUrg:   "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.labels["alertname"] = "TestAlert"
+			incident := transformer.Transform(models.Alert{Status: "firing", Labels: tt.labels}, "")
+			if incident.AssignmentGroup != tt.wantGroup {
+				t.Errorf("AssignmentGroup = %q, want %q", incident.AssignmentGroup, tt.wantGroup)
+			}
+			if incident.Urgency != tt.wantUrg {
+				t.Errorf("Urgency = %q, want %q", incident.Urgency, tt.wantUrg)
+			}
+		})
+	}
+}
+
+func TestTransformer_Transform_ContactType(t *testing.T) {
+	cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment"}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster"},
+	}
+
+	payload := func() map[string]string {
+		t.Helper()
+		raw, err := json.Marshal(transformer.Transform(alert, ""))
+		if err != nil {
+			t.Fatalf("failed to marshal incident: %v", err)
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			t.Fatalf("failed to unmarshal payload: %v", err)
+		}
+		return payload
+	}
+
+	if _, ok := payload()["contact_type"]; ok {
+		t.Error("expected contact_type to be omitted when SERVICENOW_CONTACT_TYPE is not set")
+	}
+
+	cfg.ServiceNowContactType = "Monitoring"
+	if got := payload()["contact_type"]; got != "Monitoring" {
+		t.Errorf("contact_type = %q, want %q", got, "Monitoring")
+	}
+}
+
+func TestTransformer_IncidentMarker(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		IncidentMarkerField: "u_source",
+		IncidentMarkerValue: "alert2snow-agent",
+		LabelFieldMap:       map[string]string{"source": "u_source"},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "TestAlert", "cluster": "test-cluster", "source": "other"},
+	}
+
+	raw, err := json.Marshal(transformer.Transform(alert, ""))
+	if err != nil {
+		t.Fatalf("failed to marshal incident: %v", err)
+	}
+	var created models.ServiceNowResult
+	if err := json.Unmarshal(raw, &created); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	if got := created.Fields["u_source"]; got != "alert2snow-agent" {
+		t.Errorf("u_source = %q, want the marker to override LABEL_FIELD_MAP", got)
+	}
+	if !transformer.Owns(&created) {
+		t.Error("expected the agent to own an incident it created")
+	}
+
+	var foreign models.ServiceNowResult
+	if err := json.Unmarshal([]byte(`{"sys_id":"sys1","u_source":{"link":"x","value":"y"}}`), &foreign); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	if transformer.Owns(&foreign) {
+		t.Error("expected the agent not to own an incident without the marker")
+	}
+
+	cfg.IncidentMarkerField = ""
+	if !transformer.Owns(&foreign) {
+		t.Error("expected every incident to be owned when INCIDENT_MARKER_FIELD is not set")
 	}
-	transformer := NewTransformer(cfg)
+}
 
+func TestTransformer_Transform_DescriptionTemplate(t *testing.T) {
 	alert := models.Alert{
 		Status: "firing",
 		Labels: map[string]string{
 			"alertname":   "KubePodCrashLooping",
-			"cluster":     "production-cluster",
-			"environment": "prod",
-			"severity":    "warning",
-			"namespace":   "openshift-monitoring",
-			"pod":         "prometheus-k8s-0",
-			"container":   "prometheus",
+			"cluster":     "prod-east",
+			"environment": "production",
+			"namespace":   "payments",
+			"pod":         "api-7f9c",
+			"severity":    "critical",
 		},
-		Annotations: map[string]string{
-			"summary":     "Pod is crash looping",
-			"description": "Pod prometheus-k8s-0 is restarting frequently.",
+		Annotations: map[string]string{"runbook_url": "https://runbooks.example.com/crashloop"},
+	}
+
+	tests := []struct {
+		name       string
+		template   string
+		want       string
+		wantPrefix string
+	}{
+		{
+			name:     "derived fields",
+			template: "{{.AlertName}} [{{.Severity}}] in {{.Cluster}}/{{.Environment}}\nPod: {{.Namespace}}/{{.Pod}}\nAlertmanager: {{.ExternalURL}}",
+			want:     "KubePodCrashLooping [critical] in prod-east/production\nPod: payments/api-7f9c\nAlertmanager: http://alertmanager.example.com",
+		},
+		{
+			name:     "alert labels and annotations",
+			template: `{{range $k, $v := .Alert.Labels}}{{$k}}={{$v}};{{end}} runbook: {{index .Alert.Annotations "runbook_url"}}`,
+			want:     "alertname=KubePodCrashLooping;cluster=prod-east;environment=production;namespace=payments;pod=api-7f9c;severity=critical; runbook: https://runbooks.example.com/crashloop",
+		},
+		{
+			name:       "execution error falls back to built-in layout",
+			template:   `{{index .Alert.Labels 1}}`,
+			wantPrefix: "Alert: KubePodCrashLooping\n",
 		},
-		StartsAt:     time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
-		GeneratorURL: "http://prometheus/graph?g0.expr=up",
-		Fingerprint:  "abc123",
 	}
 
-	incident := transformer.Transform(alert, "http://alertmanager")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ClusterLabelKey:     "cluster",
+				EnvironmentLabelKey: "environment",
+				DescriptionTemplate: tt.template,
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
 
-	// Check short description
-	expectedShortDesc := "[production-cluster] KubePodCrashLooping in namespace: openshift-monitoring"
-	if incident.ShortDescription != expectedShortDesc {
-		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, expectedShortDesc)
+			got := transformer.Transform(alert, "http://alertmanager.example.com").Description
+			if tt.wantPrefix != "" {
+				if !strings.HasPrefix(got, tt.wantPrefix) {
+					t.Errorf("Description = %q, want built-in layout starting %q", got, tt.wantPrefix)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Description = %q, want %q", got, tt.want)
+			}
+		})
 	}
+}
 
-	// Check impact and urgency (should always be 3)
-	if incident.Impact != "3" {
-		t.Errorf("Impact = %q, want %q", incident.Impact, "3")
-	}
-	if incident.Urgency != "3" {
-		t.Errorf("Urgency = %q, want %q", incident.Urgency, "3")
+func TestTransformer_Transform_ShortDescriptionTemplate(t *testing.T) {
+	alert := models.Alert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname":   "KubePodCrashLooping",
+			"cluster":     "Prod-East",
+			"environment": "production",
+			"namespace":   "payments",
+			"severity":    "critical",
+		},
 	}
 
-	// Check category
-	if incident.Category != "software" {
-		t.Errorf("Category = %q, want %q", incident.Category, "software")
-	}
-	if incident.Subcategory != "openshift" {
-		t.Errorf("Subcategory = %q, want %q", incident.Subcategory, "openshift")
+	tests := []struct {
+		name     string
+		template string
+		casing   map[string]string
+		want     string
+	}{
+		{
+			name: "default format",
+			want: "[Prod-East] KubePodCrashLooping in namespace: payments",
+		},
+		{
+			name:     "severity and environment",
+			template: "{{.Severity | printf \"%.4s\"}} {{.Environment}}/{{.Cluster}}: {{.AlertName}}",
+			want:     "crit production/Prod-East: KubePodCrashLooping",
+		},
+		{
+			name:     "components cased",
+			template: "[{{.Cluster}}] {{.AlertName}}",
+			casing:   map[string]string{"cluster": config.CaseLower},
+			want:     "[prod-east] KubePodCrashLooping",
+		},
+		{
+			name:     "surrounding whitespace trimmed",
+			template: "\n  {{.AlertName}}\n",
+			want:     "KubePodCrashLooping",
+		},
+		{
+			name:     "truncated to 160 characters",
+			template: `{{.AlertName}} {{index .Alert.Annotations "summary"}}`,
+			want:     "KubePodCrashLooping " + strings.Repeat("é", 140),
+		},
+		{
+			name:     "execution error falls back to default format",
+			template: `{{index .Alert.Labels 1}}`,
+			want:     "[Prod-East] KubePodCrashLooping in namespace: payments",
+		},
 	}
 
-	// Check correlation ID is generated
-	if incident.CorrelationID == "" {
-		t.Error("CorrelationID should not be empty")
-	}
-	if len(incident.CorrelationID) != 16 {
-		t.Errorf("CorrelationID length = %d, want 16", len(incident.CorrelationID))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ClusterLabelKey:          "cluster",
+				EnvironmentLabelKey:      "environment",
+				ShortDescriptionTemplate: tt.template,
+				ShortDescriptionCase:     tt.casing,
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+			alert := alert
+			alert.Annotations = map[string]string{"summary": strings.Repeat("é", 200)}
+			if got := transformer.Transform(alert, "").ShortDescription; got != tt.want {
+				t.Errorf("ShortDescription = %q, want %q", got, tt.want)
+			}
+		})
 	}
+}
 
-	// Check description contains expected content
-	if !strings.Contains(incident.Description, "Alert: KubePodCrashLooping") {
-		t.Error("Description should contain alert name")
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{in: "short", n: 10, want: "short"},
+		{in: "exactly", n: 7, want: "exactly"},
+		{in: "truncate me", n: 8, want: "truncate"},
+		{in: "日本語のテキスト", n: 3, want: "日本語"},
+		{in: "naïve café", n: 4, want: "naïv"},
+		{in: "", n: 3, want: ""},
 	}
-	if !strings.Contains(incident.Description, "Cluster: production-cluster") {
-		t.Error("Description should contain cluster")
+	for _, tt := range tests {
+		got := truncateRunes(tt.in, tt.n)
+		if got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateRunes(%q, %d) returned invalid UTF-8 %q", tt.in, tt.n, got)
+		}
 	}
-	if !strings.Contains(incident.Description, "Pod is crash looping") {
-		t.Error("Description should contain summary")
+}
+
+func TestTransformer_Transform_DescriptionMaxLen(t *testing.T) {
+	huge := map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod"}
+	for i := 0; i < 500; i++ {
+		huge[fmt.Sprintf("label_%03d", i)] = strings.Repeat("値", 20)
 	}
-	if !strings.Contains(incident.Description, "Namespace: openshift-monitoring") {
-		t.Error("Description should contain namespace")
+	small := map[string]string{"alertname": "KubePodCrashLooping", "cluster": "prod"}
+
+	tests := []struct {
+		name          string
+		maxLen        int
+		labels        map[string]string
+		wantLimit     int
+		wantTruncated bool
+	}{
+		{name: "default limit", labels: huge, wantLimit: config.DefaultDescriptionMaxLen, wantTruncated: true},
+		{name: "configured limit", maxLen: 500, labels: huge, wantLimit: 500, wantTruncated: true},
+		{name: "within limit", maxLen: 4000, labels: small, wantLimit: 4000},
 	}
-}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ClusterLabelKey: "cluster", EnvironmentLabelKey: "environment", DescriptionMaxLen: tt.maxLen}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
 
-func TestTransformer_Transform_MissingCluster(t *testing.T) {
-	cfg := &config.Config{
-		ClusterLabelKey:       "cluster",
-		EnvironmentLabelKey:   "environment",
-		ServiceNowCategory:    "software",
-		ServiceNowSubcategory: "openshift",
-		ServiceNowUrgency:     "3",
-		ServiceNowImpact:      "3",
+			desc := transformer.Transform(models.Alert{Status: "firing", Labels: tt.labels}, "").Description
+			if n := utf8.RuneCountInString(desc); n > tt.wantLimit {
+				t.Errorf("description has %d characters, want at most %d", n, tt.wantLimit)
+			}
+			if !utf8.ValidString(desc) {
+				t.Error("truncated description is not valid UTF-8")
+			}
+			if got := strings.HasSuffix(desc, truncatedMarker); got != tt.wantTruncated {
+				t.Errorf("description ends with truncation marker = %v, want %v", got, tt.wantTruncated)
+			}
+		})
 	}
-	transformer := NewTransformer(cfg)
+}
 
+func TestTransformer_Transform_ShortDescriptionCase(t *testing.T) {
 	alert := models.Alert{
 		Status: "firing",
-		Labels: map[string]string{
-			"alertname": "TestAlert",
-			"severity":  "warning",
+		Labels: map[string]string{"alertname": "kube_pod_CRASH_looping", "cluster": "Prod-East", "namespace": "Payments"},
+	}
+
+	tests := []struct {
+		name   string
+		casing map[string]string
+		want   string
+	}{
+		{
+			name: "unset",
+			want: "[Prod-East] kube_pod_CRASH_looping in namespace: Payments",
+		},
+		{
+			name:   "lowercase namespace and cluster",
+			casing: map[string]string{"namespace": config.CaseLower, "cluster": config.CaseLower},
+			want:   "[prod-east] kube_pod_CRASH_looping in namespace: payments",
+		},
+		{
+			name:   "title-case alertname",
+			casing: map[string]string{"alertname": config.CaseTitle},
+			want:   "[Prod-East] Kube_Pod_Crash_Looping in namespace: Payments",
+		},
+		{
+			name:   "uppercase cluster",
+			casing: map[string]string{"cluster": config.CaseUpper},
+			want:   "[PROD-EAST] kube_pod_CRASH_looping in namespace: Payments",
 		},
-		Annotations: map[string]string{},
-		StartsAt:    time.Now(),
 	}
 
-	incident := transformer.Transform(alert, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ClusterLabelKey:      "cluster",
+				EnvironmentLabelKey:  "environment",
+				ShortDescriptionCase: tt.casing,
+			}
+			transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+			if got := transformer.Transform(alert, "").ShortDescription; got != tt.want {
+				t.Errorf("ShortDescription = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
 
-	expectedShortDesc := "[unknown-cluster] TestAlert"
-	if incident.ShortDescription != expectedShortDesc {
-		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, expectedShortDesc)
+func TestTitleCase(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"targetdown":          "Targetdown",
+		"HIGH_CPU_usage":      "High_Cpu_Usage",
+		"etcd-member 2 down":  "Etcd-Member 2 Down",
+		"KubePodCrashLooping": "Kubepodcrashlooping",
+	}
+	for in, want := range tests {
+		if got := titleCase(in); got != want {
+			t.Errorf("titleCase(%q) = %q, want %q", in, got, want)
+		}
 	}
 }
 
@@ -187,6 +1436,7 @@ func TestExtractClusterFromURL(t *testing.T) {
 		name     string
 		url      string
 		expected string
+		err      error
 	}{
 		{
 			name:     "standard OpenShift console URL",
@@ -202,16 +1452,42 @@ func TestExtractClusterFromURL(t *testing.T) {
 			name:     "URL without apps pattern",
 			url:      "https://prometheus.example.com/graph",
 			expected: "",
+			err:      errNoClusterURL,
 		},
 		{
 			name:     "invalid URL",
 			url:      "not-a-url",
 			expected: "",
+			err:      errNoClusterURL,
 		},
 		{
 			name:     "empty URL",
 			url:      "",
 			expected: "",
+			err:      errMalformedURL,
+		},
+		{
+			name:     "scheme-less host and port",
+			url:      "prometheus:9090/graph",
+			expected: "",
+			err:      errNoClusterURL,
+		},
+		{
+			name:     "scheme-less OpenShift URL",
+			url:      "prometheus-k8s.apps.my-cluster.example.com:9091/graph",
+			expected: "my-cluster",
+		},
+		{
+			name:     "malformed host",
+			url:      "https://[::1/graph",
+			expected: "",
+			err:      errMalformedURL,
+		},
+		{
+			name:     "missing host",
+			url:      "https:///graph",
+			expected: "",
+			err:      errMalformedURL,
 		},
 		{
 			name:     "cluster name with hyphens and numbers",
@@ -227,10 +1503,16 @@ func TestExtractClusterFromURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := extractClusterFromURL(tt.url)
+			got, err := extractClusterFromURL(tt.url)
 			if got != tt.expected {
 				t.Errorf("extractClusterFromURL(%q) = %q, want %q", tt.url, got, tt.expected)
 			}
+			if tt.err == nil && err != nil {
+				t.Errorf("extractClusterFromURL(%q) unexpected error: %v", tt.url, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("extractClusterFromURL(%q) error = %v, want %v", tt.url, err, tt.err)
+			}
 		})
 	}
 }
@@ -244,7 +1526,7 @@ func TestTransformer_ExtractClusterName_FromURL(t *testing.T) {
 		ServiceNowUrgency:     "3",
 		ServiceNowImpact:      "3",
 	}
-	transformer := NewTransformer(cfg)
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
 
 	// Alert without cluster label but with GeneratorURL containing cluster name
 	alert := models.Alert{
@@ -276,6 +1558,44 @@ func TestTransformer_ExtractClusterName_FromURL(t *testing.T) {
 	}
 }
 
+func TestTransformer_ExtractClusterName_URLRegex(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:       "cluster",
+		EnvironmentLabelKey:   "environment",
+		ClusterURLRegex:       `thanos-query-(?P<cluster>[^.]+)\.monitoring`,
+		ServiceNowCategory:    "software",
+		ServiceNowSubcategory: "openshift",
+		ServiceNowUrgency:     "3",
+		ServiceNowImpact:      "3",
+	}
+	m := metrics.New()
+	transformer := NewTransformer(cfg, m, newTestLogger())
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "matches", url: "https://thanos-query-east-1.monitoring.example.com/graph", want: "east-1"},
+		{name: "apps pattern is not used", url: "https://prometheus-k8s.apps.my-cluster.example.com/graph", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := models.Alert{
+				Labels:       map[string]string{"alertname": "TestAlert"},
+				GeneratorURL: tt.url,
+			}
+			if got := transformer.extractClusterName(alert); got != tt.want {
+				t.Errorf("extractClusterName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := counterValue(t, m.GeneratorURLFailures, "no_cluster"); got != 1 {
+		t.Errorf("no_cluster failures = %v, want 1", got)
+	}
+}
+
 func TestTransformer_ExtractClusterName_LabelTakesPrecedence(t *testing.T) {
 	cfg := &config.Config{
 		ClusterLabelKey:       "cluster",
@@ -285,7 +1605,7 @@ func TestTransformer_ExtractClusterName_LabelTakesPrecedence(t *testing.T) {
 		ServiceNowUrgency:     "3",
 		ServiceNowImpact:      "3",
 	}
-	transformer := NewTransformer(cfg)
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
 
 	// Alert with both cluster label AND GeneratorURL - label should take precedence
 	alert := models.Alert{
@@ -307,3 +1627,141 @@ func TestTransformer_ExtractClusterName_LabelTakesPrecedence(t *testing.T) {
 		t.Errorf("ShortDescription = %q, want %q", incident.ShortDescription, expectedShortDesc)
 	}
 }
+
+func TestTransformer_Normalize(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		LabelNormalization: map[string]map[string]string{
+			"environment": {
+				"PROD":       "prod",
+				"production": "prod",
+			},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "upper case", value: "PROD", want: "prod"},
+		{name: "long form", value: "production", want: "prod"},
+		{name: "mixed case long form", value: "Production", want: "prod"},
+		{name: "already canonical", value: "prod", want: "prod"},
+		{name: "unmapped value", value: "staging", want: "staging"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := models.Alert{
+				Labels: map[string]string{
+					"alertname":   "TestAlert",
+					"environment": tt.value,
+				},
+			}
+
+			got := transformer.Normalize(alert)
+			if got.Labels["environment"] != tt.want {
+				t.Errorf("environment = %q, want %q", got.Labels["environment"], tt.want)
+			}
+			if alert.Labels["environment"] != tt.value {
+				t.Error("Normalize() must not modify the original labels")
+			}
+		})
+	}
+}
+
+func TestTransformer_Normalize_UnifiesCorrelation(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		LabelNormalization: map[string]map[string]string{
+			"environment": {"PROD": "prod", "production": "prod"},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	a := transformer.Normalize(models.Alert{Labels: map[string]string{"alertname": "TestAlert", "environment": "PROD"}})
+	b := transformer.Normalize(models.Alert{Labels: map[string]string{"alertname": "TestAlert", "environment": "production"}})
+
+	incidentA := transformer.Transform(a, "")
+	incidentB := transformer.Transform(b, "")
+
+	if incidentA.CorrelationID != incidentB.CorrelationID {
+		t.Errorf("expected equal correlation IDs after normalization, got %q and %q", incidentA.CorrelationID, incidentB.CorrelationID)
+	}
+	if !strings.Contains(incidentA.Description, "Environment: prod") {
+		t.Errorf("Description should contain normalized environment, got: %s", incidentA.Description)
+	}
+}
+
+func TestTransformer_Normalize_LabelAliases(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+		LabelAliases:        map[string]string{"k8s_namespace": "namespace"},
+		LabelNormalization: map[string]map[string]string{
+			"namespace": {"Payments": "payments"},
+		},
+	}
+	transformer := NewTransformer(cfg, metrics.New(), newTestLogger())
+
+	before := transformer.Normalize(models.Alert{Labels: map[string]string{"alertname": "TestAlert", "namespace": "payments"}})
+	after := transformer.Normalize(models.Alert{Labels: map[string]string{"alertname": "TestAlert", "k8s_namespace": "Payments"}})
+
+	beforeID := GenerateCorrelationID("TestAlert", before.Labels)
+	afterID := GenerateCorrelationID("TestAlert", after.Labels)
+	if beforeID != afterID {
+		t.Errorf("expected correlation ID to survive the label rename, got %q and %q", beforeID, afterID)
+	}
+	if _, ok := after.Labels["k8s_namespace"]; ok {
+		t.Error("aliased label should be renamed, not copied")
+	}
+
+	// Without the alias the rename changes the correlation ID
+	plain := NewTransformer(&config.Config{}, metrics.New(), newTestLogger())
+	renamed := plain.Normalize(models.Alert{Labels: map[string]string{"alertname": "TestAlert", "k8s_namespace": "payments"}})
+	if GenerateCorrelationID("TestAlert", renamed.Labels) == beforeID {
+		t.Error("expected a different correlation ID without an alias")
+	}
+
+	// The canonical label wins when both are present
+	both := transformer.Normalize(models.Alert{Labels: map[string]string{"namespace": "payments", "k8s_namespace": "billing"}})
+	if both.Labels["namespace"] != "payments" || len(both.Labels) != 1 {
+		t.Errorf("expected canonical label to be kept, got %v", both.Labels)
+	}
+}
+
+func TestTransformer_ExtractClusterName_CountsURLFailures(t *testing.T) {
+	cfg := &config.Config{
+		ClusterLabelKey:     "cluster",
+		EnvironmentLabelKey: "environment",
+	}
+	m := metrics.New()
+	transformer := NewTransformer(cfg, m, newTestLogger())
+
+	for _, generatorURL := range []string{"https://[::1/graph", "prometheus:9090/graph", "prometheus:9090/graph"} {
+		alert := models.Alert{
+			Labels:       map[string]string{"alertname": "TestAlert"},
+			GeneratorURL: generatorURL,
+		}
+		if cluster := transformer.extractClusterName(alert); cluster != "" {
+			t.Errorf("expected no cluster for %q, got %q", generatorURL, cluster)
+		}
+	}
+
+	// A cluster label means the URL is never consulted
+	transformer.extractClusterName(models.Alert{
+		Labels:       map[string]string{"cluster": "prod"},
+		GeneratorURL: "https://[::1/graph",
+	})
+
+	if got := counterValue(t, m.GeneratorURLFailures, "malformed"); got != 1 {
+		t.Errorf("malformed failures = %v, want 1", got)
+	}
+	if got := counterValue(t, m.GeneratorURLFailures, "no_cluster"); got != 2 {
+		t.Errorf("no_cluster failures = %v, want 2", got)
+	}
+}